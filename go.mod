@@ -14,4 +14,5 @@ require (
 	github.com/swaggo/http-swagger v0.0.0-20200308142732-58ac5e232fba
 	github.com/swaggo/swag v1.6.3
 	golang.org/x/crypto v0.0.0-20200221231518-2aa609cf4a9d // indirect
+	gopkg.in/yaml.v2 v2.2.8
 )
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// spring-apispec 比较和转换应用导出的 Swagger 2.0 文档，用于在 CI 中检查接口变更，
+// 也可以根据 Swagger 2.0 或者 OpenAPI 3 文档启动一个模拟服务器。
+//
+//	spring-apispec diff [-allow-breaking] old.json new.json
+//	spring-apispec convert [-openapi] in.json out.yaml
+//	spring-apispec mock [-addr :8080] [-validate] [-latency 100ms] [-error-rate 0.1] api.yaml
+//
+// 应用的文档通过 SpringWeb.ExportSpecFromArgs 导出，diff 存在不兼容的变更时返回 1。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-spring/go-spring-web/spring-gin"
+	"github.com/go-spring/go-spring-web/spring-web"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func usage() int {
+	fmt.Fprintln(os.Stderr, "usage: spring-apispec diff [-allow-breaking] <old> <new>")
+	fmt.Fprintln(os.Stderr, "       spring-apispec convert [-openapi] <in> <out>")
+	fmt.Fprintln(os.Stderr, "       spring-apispec mock [flags] <spec>")
+	return 2
+}
+
+func run(args []string) int {
+
+	if len(args) == 0 {
+		return usage()
+	}
+
+	switch args[0] {
+	case "diff":
+		return diff(args[1:])
+	case "convert":
+		return convert(args[1:])
+	case "mock":
+		return mock(args[1:])
+	}
+	return usage()
+}
+
+// diff 输出两个文档之间的差异，存在不兼容的变更时返回 1
+func diff(args []string) int {
+
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	allowBreaking := fs.Bool("allow-breaking", false, "don't fail on breaking changes")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return usage()
+	}
+
+	old, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	new, err := ioutil.ReadFile(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	d, err := SpringWeb.DiffSpec(old, new)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	fmt.Print(d.String())
+
+	if d.HasBreaking() && !*allowBreaking {
+		fmt.Fprintf(os.Stderr, "%d breaking change(s)\n", len(d.Breaking()))
+		return 1
+	}
+	return 0
+}
+
+// convert 将 Swagger 2.0 文档转换成 JSON、YAML 或者 OpenAPI 3.1 文档
+func convert(args []string) int {
+
+	fs := flag.NewFlagSet("convert", flag.ContinueOnError)
+	openapi := fs.Bool("openapi", false, "convert to OpenAPI 3.1")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 2 {
+		return usage()
+	}
+
+	b, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	kind := SpringWeb.SpecSwagger
+	if *openapi {
+		kind = SpringWeb.SpecOpenAPI
+	}
+
+	format := "json"
+	switch strings.ToLower(filepath.Ext(fs.Arg(1))) {
+	case ".yaml", ".yml":
+		format = "yaml"
+	}
+
+	if b, err = SpringWeb.ConvertSpec(b, kind, format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if err = ioutil.WriteFile(fs.Arg(1), b, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
+}
+
+// mock 根据文档启动模拟服务器，收到退出信号之后停止
+func mock(args []string) int {
+
+	var cfg SpringWeb.MockConfig
+
+	fs := flag.NewFlagSet("mock", flag.ContinueOnError)
+	addr := fs.String("addr", ":8080", "listen address")
+	fs.BoolVar(&cfg.Validate, "validate", false, "validate requests against the spec")
+	fs.DurationVar(&cfg.Latency, "latency", 0, "fixed latency added to every response")
+	fs.DurationVar(&cfg.Jitter, "jitter", 0, "random latency added on top of -latency")
+	fs.Float64Var(&cfg.ErrorRate, "error-rate", 0, "probability of an injected error response")
+	fs.IntVar(&cfg.ErrorCode, "error-code", 500, "status code of injected errors")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return usage()
+	}
+
+	host, port, err := net.SplitHostPort(*addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{IP: host, Port: p})
+	if err = SpringWeb.BuildMockFile(c, fs.Arg(0), cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	c.Start()
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	<-ch
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c.Stop(ctx)
+	return 0
+}
@@ -25,7 +25,6 @@ import (
 	"net/url"
 	"os"
 
-	"github.com/go-spring/go-spring-parent/spring-const"
 	"github.com/go-spring/go-spring-parent/spring-logger"
 	"github.com/go-spring/go-spring-parent/spring-utils"
 	"github.com/go-spring/go-spring-web/spring-web"
@@ -61,7 +60,7 @@ type Context struct {
 func NewContext(fn SpringWeb.Handler, wildCardName string, echoCtx echo.Context) *Context {
 
 	ctx := echoCtx.Request().Context()
-	logCtx := SpringLogger.NewDefaultLoggerContext(ctx)
+	logCtx := SpringWeb.NewLoggerContext(ctx)
 
 	webCtx := &Context{
 		LoggerContext: logCtx,
@@ -84,6 +83,12 @@ func (ctx *Context) Get(key string) interface{} {
 	return ctx.echoContext.Get(key)
 }
 
+// Lookup retrieves data from the context, reports whether the key exists.
+func (ctx *Context) Lookup(key string) (interface{}, bool) {
+	val := ctx.echoContext.Get(key)
+	return val, val != nil
+}
+
 // Set saves data in the context.
 func (ctx *Context) Set(key string, val interface{}) {
 	ctx.echoContext.Set(key, val)
@@ -94,6 +99,12 @@ func (ctx *Context) Request() *http.Request {
 	return ctx.echoContext.Request()
 }
 
+// SetRequest sets `*http.Request`.
+func (ctx *Context) SetRequest(r *http.Request) {
+	ctx.echoContext.SetRequest(r)
+	ctx.LoggerContext = SpringWeb.NewLoggerContext(r.Context())
+}
+
 // IsTLS returns true if HTTP connection is TLS otherwise false.
 func (ctx *Context) IsTLS() bool {
 	return ctx.echoContext.IsTLS()
@@ -248,6 +259,11 @@ func (ctx *Context) ResponseWriter() http.ResponseWriter {
 	return ctx.echoContext.Response().Writer
 }
 
+// SetResponseWriter replaces the `http.ResponseWriter`.
+func (ctx *Context) SetResponseWriter(w http.ResponseWriter) {
+	ctx.echoContext.Response().Writer = w
+}
+
 // Status sets the HTTP response code.
 func (ctx *Context) Status(code int) {
 	ctx.echoContext.Response().WriteHeader(code)
@@ -373,5 +389,12 @@ func (ctx *Context) Redirect(code int, url string) {
 
 // SSEvent writes a Server-Sent Event into the body stream.
 func (ctx *Context) SSEvent(name string, message interface{}) {
-	panic(SpringConst.UnimplementedMethod)
+	r := ctx.echoContext.Response()
+	if r.Header().Get(SpringWeb.HeaderContentType) == "" {
+		r.Header().Set(SpringWeb.HeaderContentType, SpringWeb.MIMETextEventStream)
+		r.Header().Set("Cache-Control", "no-cache")
+	}
+	err := SpringWeb.WriteSSEvent(r, name, message)
+	SpringUtils.Panic(err).When(err != nil)
+	r.Flush()
 }
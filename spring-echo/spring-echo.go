@@ -66,6 +66,10 @@ func (c *Container) Start() {
 		cFilters = append(cFilters, f)
 	}
 
+	if w := c.GetResultWrapper(); w != nil {
+		cFilters = append(cFilters, SpringWeb.ResultWrapperFilter(w))
+	}
+
 	cFilters = append(cFilters, c.GetFilters()...)
 
 	// 映射 Web 处理函数
@@ -73,7 +77,10 @@ func (c *Container) Start() {
 		c.PrintMapper(mapper)
 
 		path, wildCardName := SpringWeb.ToPathStyle(mapper.Path(), SpringWeb.EchoPathStyle)
-		filters := append(cFilters, mapper.Filters()...)
+		filters := append(append([]SpringWeb.Filter{}, cFilters...), mapper.Filters()...)
+		if f := c.SpecValidationFilter(mapper); f != nil {
+			filters = append(filters, f)
+		}
 		handler := HandlerWrapper(mapper.Handler(), wildCardName, filters)
 
 		for _, method := range SpringWeb.GetMethod(mapper.Method()) {
@@ -71,7 +71,7 @@ type Context struct {
 func NewContext(path string, fn SpringWeb.Handler, wildCardName string, ginCtx *gin.Context) *Context {
 
 	ctx := ginCtx.Request.Context()
-	logCtx := SpringLogger.NewDefaultLoggerContext(ctx)
+	logCtx := SpringWeb.NewLoggerContext(ctx)
 
 	webCtx := &Context{
 		LoggerContext: logCtx,
@@ -95,6 +95,11 @@ func (ctx *Context) Get(key string) interface{} {
 	return ctx.ginContext.MustGet(key)
 }
 
+// Lookup retrieves data from the context, reports whether the key exists.
+func (ctx *Context) Lookup(key string) (interface{}, bool) {
+	return ctx.ginContext.Get(key)
+}
+
 // Set saves data in the context.
 func (ctx *Context) Set(key string, val interface{}) {
 	ctx.ginContext.Set(key, val)
@@ -105,6 +110,12 @@ func (ctx *Context) Request() *http.Request {
 	return ctx.ginContext.Request
 }
 
+// SetRequest sets `*http.Request`.
+func (ctx *Context) SetRequest(r *http.Request) {
+	ctx.ginContext.Request = r
+	ctx.LoggerContext = SpringWeb.NewLoggerContext(r.Context())
+}
+
 // IsTLS returns true if HTTP connection is TLS otherwise false.
 func (ctx *Context) IsTLS() bool {
 	return ctx.ginContext.Request.TLS != nil
@@ -181,10 +192,11 @@ func (ctx *Context) PathParam(name string) string {
 		name = ctx.wildCardName
 	}
 	v := ctx.ginContext.Param(name)
-	if len(v) > 0 {
-		return v[1:]
+	// gin 的通配符参数以 / 开头
+	if name == ctx.wildCardName {
+		return strings.TrimPrefix(v, "/")
 	}
-	return ""
+	return v
 }
 
 // PathParamNames returns path parameter names.
@@ -290,6 +302,19 @@ func (ctx *Context) ResponseWriter() http.ResponseWriter {
 	return ctx.ginContext.Writer
 }
 
+// SetResponseWriter replaces the `http.ResponseWriter`.
+func (ctx *Context) SetResponseWriter(w http.ResponseWriter) {
+	if gw, ok := w.(gin.ResponseWriter); ok {
+		// 恢复原来的 Writer 时带上还没有输出的状态码
+		if rw, ok := ctx.ginContext.Writer.(*responseWriter); ok && !rw.Written() {
+			gw.WriteHeader(rw.Status())
+		}
+		ctx.ginContext.Writer = gw
+		return
+	}
+	ctx.ginContext.Writer = newResponseWriter(ctx.ginContext.Writer, w)
+}
+
 // Status sets the HTTP response code.
 func (ctx *Context) Status(code int) {
 	ctx.ginContext.Status(code)
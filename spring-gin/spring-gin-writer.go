@@ -0,0 +1,96 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringGin
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const noWritten = -1
+
+// responseWriter 使用自定义的 http.ResponseWriter 输出响应，同时实现 gin 需要的
+// 状态码和长度等接口，Hijack 等操作仍然使用原始的 Writer。
+type responseWriter struct {
+	gin.ResponseWriter
+
+	w      http.ResponseWriter
+	status int
+	size   int
+}
+
+// newResponseWriter responseWriter 的构造函数
+func newResponseWriter(origin gin.ResponseWriter, w http.ResponseWriter) *responseWriter {
+	return &responseWriter{
+		ResponseWriter: origin,
+		w:              w,
+		status:         origin.Status(),
+		size:           noWritten,
+	}
+}
+
+func (r *responseWriter) Header() http.Header {
+	return r.w.Header()
+}
+
+func (r *responseWriter) WriteHeader(code int) {
+	if code > 0 && !r.Written() {
+		r.status = code
+	}
+}
+
+func (r *responseWriter) WriteHeaderNow() {
+	if !r.Written() {
+		r.size = 0
+		r.w.WriteHeader(r.status)
+	}
+}
+
+func (r *responseWriter) Write(data []byte) (int, error) {
+	r.WriteHeaderNow()
+	n, err := r.w.Write(data)
+	r.size += n
+	return n, err
+}
+
+func (r *responseWriter) WriteString(s string) (int, error) {
+	r.WriteHeaderNow()
+	n, err := io.WriteString(r.w, s)
+	r.size += n
+	return n, err
+}
+
+func (r *responseWriter) Status() int {
+	return r.status
+}
+
+func (r *responseWriter) Size() int {
+	return r.size
+}
+
+func (r *responseWriter) Written() bool {
+	return r.size != noWritten
+}
+
+func (r *responseWriter) Flush() {
+	r.WriteHeaderNow()
+	if f, ok := r.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -72,6 +72,10 @@ func (c *Container) Start() {
 		cFilters = append(cFilters, f)
 	}
 
+	if w := c.GetResultWrapper(); w != nil {
+		cFilters = append(cFilters, SpringWeb.ResultWrapperFilter(w))
+	}
+
 	cFilters = append(cFilters, c.GetFilters()...)
 
 	// 映射 Web 处理函数
@@ -79,7 +83,10 @@ func (c *Container) Start() {
 		c.PrintMapper(mapper)
 
 		path, wildCardName := SpringWeb.ToPathStyle(mapper.Path(), SpringWeb.GinPathStyle)
-		filters := append(cFilters, mapper.Filters()...)
+		filters := append(append([]SpringWeb.Filter{}, cFilters...), mapper.Filters()...)
+		if f := c.SpecValidationFilter(mapper); f != nil {
+			filters = append(filters, f)
+		}
 		handlers := HandlerWrapper(mapper.Path(), mapper.Handler(), wildCardName, filters)
 
 		for _, method := range SpringWeb.GetMethod(mapper.Method()) {
@@ -127,8 +134,22 @@ func HandlerWrapper(path string, fn SpringWeb.Handler, wildCardName string, filt
 	// 封装过滤器
 	for _, filter := range filters {
 		f := filter // 避免延迟绑定
+
+		// gin 中间件自己决定是否中止
+		if _, ok := f.(ginFilter); ok {
+			handlers = append(handlers, func(ginCtx *gin.Context) {
+				f.Invoke(WebContext(ginCtx), nil)
+			})
+			continue
+		}
+
+		// 过滤器没有调用 chain.Next 时中止后面的处理函数
 		handlers = append(handlers, func(ginCtx *gin.Context) {
-			f.Invoke(WebContext(ginCtx), &ginFilterChain{ginCtx})
+			chain := &ginFilterChain{ginCtx: ginCtx}
+			f.Invoke(WebContext(ginCtx), chain)
+			if !chain.called {
+				ginCtx.Abort()
+			}
 		})
 	}
 
@@ -175,9 +196,11 @@ func Filter(fn gin.HandlerFunc) SpringWeb.Filter {
 // ginFilterChain gin 适配的过滤器链条
 type ginFilterChain struct {
 	ginCtx *gin.Context
+	called bool // 是否调用过 Next
 }
 
 // Next 内部调用 gin.Context 对象的 Next 函数驱动链条向后执行
 func (chain *ginFilterChain) Next(_ SpringWeb.WebContext) {
+	chain.called = true
 	chain.ginCtx.Next()
 }
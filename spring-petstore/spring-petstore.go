@@ -21,6 +21,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"net/http"
+	"os"
 	"reflect"
 	"time"
 
@@ -48,7 +49,7 @@ type Order struct {
 	PetId    int64     `json:"petId,omitempty"`
 	Quantity int32     `json:"quantity,omitempty"`
 	ShipDate time.Time `json:"shipDate,omitempty"`
-	Status   string    `json:"status,omitempty"` // Order Status
+	Status   string    `json:"status,omitempty" enum:"placed,approved,delivered" description:"Order Status"`
 	Complete bool      `json:"complete,omitempty"`
 }
 
@@ -62,10 +63,10 @@ type Pet struct {
 	XMLName   xml.Name  `xml:"Pet"`
 	Id        int64     `json:"id,omitempty"`
 	Category  *Category `json:"category,omitempty"`
-	Name      string    `json:"name"`
+	Name      string    `json:"name" example:"doggie"`
 	PhotoUrls []string  `json:"photoUrls" xml:"photoUrl,wrapped"`
 	Tags      []Tag     `json:"tags,omitempty" xml:"tag,wrapped"`
-	Status    string    `json:"status,omitempty"` // pet status in the store
+	Status    string    `json:"status,omitempty" enum:"available,pending,sold" description:"pet status in the store"`
 }
 
 type User struct {
@@ -77,7 +78,7 @@ type User struct {
 	Email      string   `json:"email,omitempty"`
 	Password   string   `json:"password,omitempty"`
 	Phone      string   `json:"phone,omitempty"`
-	UserStatus int32    `json:"userStatus,omitempty"` // User Status
+	UserStatus int32    `json:"userStatus,omitempty" description:"User Status"`
 }
 
 type UserController struct {
@@ -216,27 +217,7 @@ func main() {
 			Description: "Find out more about Swagger",
 			URL:         "http://swagger.io",
 		}).
-		BindDefinitions(new(ApiResponse), new(Tag), new(Category)).
-		BindDefinitionWithTags(new(Order), map[string]SpringWeb.DefinitionField{
-			"status": {
-				Enums:       []interface{}{"placed", "approved", "delivered"},
-				Description: "Order Status",
-			},
-		}).
-		BindDefinitionWithTags(new(User), map[string]SpringWeb.DefinitionField{
-			"userStatus": {
-				Description: "User Status",
-			},
-		}).
-		BindDefinitionWithTags(new(Pet), map[string]SpringWeb.DefinitionField{
-			"name": {
-				Example: "doggie",
-			},
-			"status": {
-				Description: "pet status in the store",
-				Enums:       []interface{}{"available", "pending", "sold"},
-			},
-		}).
+		BindDefinitions(new(ApiResponse), new(Tag), new(Category), new(Order), new(User), new(Pet)).
 		AddApiKeySecurityDefinition("api_key", "header").
 		AddOauth2ImplicitSecurityDefinition("petstore_auth",
 			"https://petstore.swagger.io/oauth/authorize",
@@ -499,6 +480,13 @@ func main() {
 			WithDefaultResponse(SpringWeb.NewResponse("successful operation"))
 	}
 
+	// 使用 -export-swagger=swagger.json 等参数导出文档，不启动服务器
+	if ok, err := SpringWeb.ExportSpecFromArgs(c, os.Args[1:]); err != nil {
+		panic(err)
+	} else if ok {
+		return
+	}
+
 	c.Start()
 
 	time.Sleep(200 * time.Millisecond)
@@ -507,7 +495,7 @@ func main() {
 	_ = json.Unmarshal([]byte(petstore), &m1)
 
 	var m2 map[string]interface{}
-	doc := SpringWeb.Swagger().ReadDoc()
+	doc := c.Swagger().ReadDoc()
 	_ = json.Unmarshal([]byte(doc), &m2)
 
 	setDef(m1)
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// redoc-bundle 下载指定版本的 redoc.standalone.js，生成内嵌到二进制文件中的 Go 代码，
+// 由 spring-web 包中的 go:generate 调用。无法访问 CDN 时可以使用 -file 指定本地的
+// redoc.standalone.js，例如 npm pack redoc@2.1.5 解压之后的 package/bundles/redoc.standalone.js。
+//
+//	redoc-bundle -version 2.1.5 [-file redoc.standalone.js] [-sha256 hex] -o spring-web-redoc-bundle.go
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+
+	version := flag.String("version", "", "redoc version")
+	file := flag.String("file", "", "local redoc.standalone.js, download from CDN when empty")
+	checksum := flag.String("sha256", "", "expected sha256 of redoc.standalone.js")
+	output := flag.String("o", "spring-web-redoc-bundle.go", "output file")
+	flag.Parse()
+
+	if err := run(*version, *file, *checksum, *output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(version string, file string, checksum string, output string) error {
+
+	if version == "" {
+		return fmt.Errorf("redoc version is required")
+	}
+
+	var (
+		script []byte
+		err    error
+	)
+
+	source := file
+	if file != "" {
+		script, err = ioutil.ReadFile(file)
+	} else {
+		source = "https://cdn.jsdelivr.net/npm/redoc@" + version + "/bundles/redoc.standalone.js"
+		script, err = download(source)
+	}
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(script)
+	if checksum != "" && hex.EncodeToString(sum[:]) != checksum {
+		return fmt.Errorf("sha256 of %s is %x, expected %s", source, sum, checksum)
+	}
+
+	var gz bytes.Buffer
+	w, _ := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	if _, err = w.Write(script); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by spring-web/internal/redoc-bundle. DO NOT EDIT.\n\n")
+	buf.WriteString("package SpringWeb\n\n")
+	buf.WriteString("// redocBundleVersion 内嵌的 redoc.standalone.js 的版本\n")
+	fmt.Fprintf(&buf, "const redocBundleVersion = %q\n\n", version)
+	fmt.Fprintf(&buf, "// redocBundleGzip gzip 压缩之后的 redoc.standalone.js，sha256 %x\n", sum)
+	fmt.Fprintf(&buf, "const redocBundleGzip = %q\n", gz.String())
+
+	return ioutil.WriteFile(output, buf.Bytes(), 0644)
+}
+
+// download 下载 url 处的文件
+func download(url string) ([]byte, error) {
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s error: %s", url, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// PrefixLogger 为 SpringLogger 的日志加上固定的前缀。SpringLogger.Console 查找日志的
+// 调用位置时跳过 spring-logger 目录下的文件，所以这里的封装放在该目录中，输出的调用
+// 位置仍然是打印日志的业务代码。
+package PrefixLogger
+
+import (
+	"context"
+
+	"github.com/go-spring/go-spring-parent/spring-logger"
+)
+
+// NewLoggerContext 返回日志以 prefix 开头的 LoggerContext，prefix 中不能出现 %
+func NewLoggerContext(ctx context.Context, prefix string) SpringLogger.LoggerContext {
+	return &loggerContext{
+		LoggerContext: SpringLogger.NewDefaultLoggerContext(ctx),
+		prefix:        prefix,
+	}
+}
+
+// loggerContext 带有前缀的 LoggerContext
+type loggerContext struct {
+	SpringLogger.LoggerContext
+	prefix string
+}
+
+func (c *loggerContext) args(args []interface{}) []interface{} {
+	return append([]interface{}{c.prefix}, args...)
+}
+
+// Logger 返回的 StdLogger 同样带有前缀
+func (c *loggerContext) Logger(tags ...string) SpringLogger.StdLogger {
+	return &stdLogger{l: c.LoggerContext.Logger(tags...), prefix: c.prefix}
+}
+
+func (c *loggerContext) LogTrace(args ...interface{}) {
+	c.LoggerContext.LogTrace(c.args(args)...)
+}
+
+func (c *loggerContext) LogTracef(format string, args ...interface{}) {
+	c.LoggerContext.LogTracef(c.prefix+format, args...)
+}
+
+func (c *loggerContext) LogDebug(args ...interface{}) {
+	c.LoggerContext.LogDebug(c.args(args)...)
+}
+
+func (c *loggerContext) LogDebugf(format string, args ...interface{}) {
+	c.LoggerContext.LogDebugf(c.prefix+format, args...)
+}
+
+func (c *loggerContext) LogInfo(args ...interface{}) {
+	c.LoggerContext.LogInfo(c.args(args)...)
+}
+
+func (c *loggerContext) LogInfof(format string, args ...interface{}) {
+	c.LoggerContext.LogInfof(c.prefix+format, args...)
+}
+
+func (c *loggerContext) LogWarn(args ...interface{}) {
+	c.LoggerContext.LogWarn(c.args(args)...)
+}
+
+func (c *loggerContext) LogWarnf(format string, args ...interface{}) {
+	c.LoggerContext.LogWarnf(c.prefix+format, args...)
+}
+
+func (c *loggerContext) LogError(args ...interface{}) {
+	c.LoggerContext.LogError(c.args(args)...)
+}
+
+func (c *loggerContext) LogErrorf(format string, args ...interface{}) {
+	c.LoggerContext.LogErrorf(c.prefix+format, args...)
+}
+
+func (c *loggerContext) LogPanic(args ...interface{}) {
+	c.LoggerContext.LogPanic(c.args(args)...)
+}
+
+func (c *loggerContext) LogPanicf(format string, args ...interface{}) {
+	c.LoggerContext.LogPanicf(c.prefix+format, args...)
+}
+
+func (c *loggerContext) LogFatal(args ...interface{}) {
+	c.LoggerContext.LogFatal(c.args(args)...)
+}
+
+func (c *loggerContext) LogFatalf(format string, args ...interface{}) {
+	c.LoggerContext.LogFatalf(c.prefix+format, args...)
+}
+
+// stdLogger 带有前缀的 StdLogger
+type stdLogger struct {
+	l      SpringLogger.StdLogger
+	prefix string
+}
+
+func (l *stdLogger) args(args []interface{}) []interface{} {
+	return append([]interface{}{l.prefix}, args...)
+}
+
+func (l *stdLogger) Trace(args ...interface{}) {
+	l.l.Trace(l.args(args)...)
+}
+
+func (l *stdLogger) Tracef(format string, args ...interface{}) {
+	l.l.Tracef(l.prefix+format, args...)
+}
+
+func (l *stdLogger) Debug(args ...interface{}) {
+	l.l.Debug(l.args(args)...)
+}
+
+func (l *stdLogger) Debugf(format string, args ...interface{}) {
+	l.l.Debugf(l.prefix+format, args...)
+}
+
+func (l *stdLogger) Info(args ...interface{}) {
+	l.l.Info(l.args(args)...)
+}
+
+func (l *stdLogger) Infof(format string, args ...interface{}) {
+	l.l.Infof(l.prefix+format, args...)
+}
+
+func (l *stdLogger) Warn(args ...interface{}) {
+	l.l.Warn(l.args(args)...)
+}
+
+func (l *stdLogger) Warnf(format string, args ...interface{}) {
+	l.l.Warnf(l.prefix+format, args...)
+}
+
+func (l *stdLogger) Error(args ...interface{}) {
+	l.l.Error(l.args(args)...)
+}
+
+func (l *stdLogger) Errorf(format string, args ...interface{}) {
+	l.l.Errorf(l.prefix+format, args...)
+}
+
+func (l *stdLogger) Panic(args ...interface{}) {
+	l.l.Panic(l.args(args)...)
+}
+
+func (l *stdLogger) Panicf(format string, args ...interface{}) {
+	l.l.Panicf(l.prefix+format, args...)
+}
+
+func (l *stdLogger) Fatal(args ...interface{}) {
+	l.l.Fatal(l.args(args)...)
+}
+
+func (l *stdLogger) Fatalf(format string, args ...interface{}) {
+	l.l.Fatalf(l.prefix+format, args...)
+}
@@ -0,0 +1,377 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// PrincipalKey 认证主体在 WebContext 中的键
+const PrincipalKey = "@Principal"
+
+// Principal 认证通过的主体
+type Principal struct {
+	Scheme string    // 认证方式，Basic、ApiKey 或者 Bearer
+	Name   string    // 主体名称，例如用户名、API Key 的所有者、JWT 的 sub 声明
+	Roles  []string  // 主体拥有的角色
+	Scopes []string  // OAuth2 访问令牌授予的 scope
+	Claims JwtClaims // JWT 的声明，其他认证方式为 nil
+}
+
+// GetPrincipal 返回请求的认证主体，没有认证时返回 nil
+func GetPrincipal(ctx WebContext) *Principal {
+	p, _ := lookupValue(ctx, PrincipalKey).(*Principal)
+	return p
+}
+
+// SetPrincipal 设置请求的认证主体
+func SetPrincipal(ctx WebContext, p *Principal) {
+	ctx.Set(PrincipalKey, p)
+}
+
+// SecurityFilter 实现认证的过滤器，生成文档时自动注册过滤器的安全定义，并为使用
+// 该过滤器的处理函数添加 SecuredWith，同一个处理函数的多个认证过滤器需要同时满足。
+type SecurityFilter interface {
+	Filter
+
+	// Security 返回安全定义的名称、安全定义以及需要的 scope
+	Security() (name string, scheme *spec.SecurityScheme, scopes []string)
+}
+
+// applySecurity 为 Operation 添加 filters 中认证过滤器的安全定义
+func (s *swagger) applySecurity(op *Operation, filters []Filter) {
+
+	requirement := make(map[string][]string)
+	for _, f := range filters {
+		sf, ok := f.(SecurityFilter)
+		if !ok {
+			continue
+		}
+		name, scheme, scopes := sf.Security()
+		if _, ok = s.SecurityDefinitions[name]; !ok {
+			s.SecurityDefinitions[name] = scheme
+		}
+		for _, scope := range scopes {
+			if !containsString(requirement[name], scope) {
+				requirement[name] = append(requirement[name], scope)
+			}
+		}
+		if _, ok = requirement[name]; !ok {
+			requirement[name] = []string{}
+		}
+	}
+
+	if len(requirement) == 0 {
+		return
+	}
+
+	for _, r := range op.operation.Security {
+		if sameRequirement(r, requirement) {
+			return
+		}
+	}
+	op.operation.Security = append(op.operation.Security, requirement)
+}
+
+// sameRequirement 两个安全要求是否使用相同的安全定义
+func sameRequirement(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// containsString 字符串数组是否包含 s
+func containsString(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// unauthorized 返回 401 响应，challenge 为 WWW-Authenticate 响应头
+func unauthorized(ctx WebContext, challenge string, message string) {
+	if challenge != "" {
+		ctx.Header(HeaderWWWAuthenticate, challenge)
+	}
+	ctx.String(http.StatusUnauthorized, "%s", message)
+}
+
+/////////////////// Basic //////////////////////
+
+// BasicAuthConfig Basic 认证的配置
+type BasicAuthConfig struct {
+
+	// Realm 认证失败时返回给浏览器的域，为空时使用 Restricted
+	Realm string
+
+	// Validator 验证用户名和密码，成功时返回认证主体，失败时返回 nil
+	Validator func(username, password string) *Principal
+}
+
+// BasicUsers 返回使用固定的用户名和密码验证的 Validator。比较的是密码的 SHA-256 摘要，
+// 用户不存在时和一个随机的摘要比较，比较的耗时不会暴露用户是否存在以及密码的长度。
+func BasicUsers(users map[string]string) func(username, password string) *Principal {
+
+	var dummy [sha256.Size]byte
+	_, _ = rand.Read(dummy[:])
+
+	return func(username, password string) *Principal {
+		p, ok := users[username]
+		expected := sha256.Sum256([]byte(p))
+		if !ok {
+			expected = dummy
+		}
+		actual := sha256.Sum256([]byte(password))
+		if subtle.ConstantTimeCompare(expected[:], actual[:]) != 1 || !ok {
+			return nil
+		}
+		return &Principal{Name: username}
+	}
+}
+
+// basicAuthFilter Basic 认证过滤器
+type basicAuthFilter struct {
+	realm     string
+	validator func(username, password string) *Principal
+}
+
+// BasicAuthFilter 返回 Basic 认证过滤器，安全定义的名称为 BasicAuth
+func BasicAuthFilter(cfg BasicAuthConfig) SecurityFilter {
+	if cfg.Validator == nil {
+		panic(errors.New("basic auth requires a validator"))
+	}
+	realm := cfg.Realm
+	if realm == "" {
+		realm = "Restricted"
+	}
+	return &basicAuthFilter{realm: realm, validator: cfg.Validator}
+}
+
+func (f *basicAuthFilter) Security() (string, *spec.SecurityScheme, []string) {
+	return "BasicAuth", spec.BasicAuth(), nil
+}
+
+func (f *basicAuthFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	challenge := `Basic realm="` + strings.Replace(f.realm, `"`, `\"`, -1) + `"`
+
+	auth := ctx.GetHeader(HeaderAuthorization)
+	if len(auth) < 6 || !strings.EqualFold(auth[:6], "Basic ") {
+		unauthorized(ctx, challenge, "missing basic credentials")
+		return
+	}
+
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[6:]))
+	if err != nil {
+		unauthorized(ctx, challenge, "invalid basic credentials")
+		return
+	}
+
+	i := strings.IndexByte(string(b), ':')
+	if i < 0 {
+		unauthorized(ctx, challenge, "invalid basic credentials")
+		return
+	}
+
+	p := f.validator(string(b[:i]), string(b[i+1:]))
+	if p == nil {
+		unauthorized(ctx, challenge, "invalid basic credentials")
+		return
+	}
+
+	p.Scheme = "Basic"
+	SetPrincipal(ctx, p)
+	chain.Next(ctx)
+}
+
+/////////////////// API Key //////////////////////
+
+// ApiKeyConfig API Key 认证的配置
+type ApiKeyConfig struct {
+
+	// Name 安全定义的名称，为空时使用 ApiKeyAuth
+	Name string
+
+	// In API Key 的位置，header、query 或者 cookie，为空时使用 header
+	In string
+
+	// Key 请求头、查询参数或者 Cookie 的名称，为空时使用 X-API-Key
+	Key string
+
+	// Validator 验证 API Key，成功时返回认证主体，失败时返回 nil
+	Validator func(key string) *Principal
+}
+
+// apiKeyFilter API Key 认证过滤器
+type apiKeyFilter struct {
+	name      string
+	in        string
+	key       string
+	validator func(key string) *Principal
+}
+
+// ApiKeyFilter 返回 API Key 认证过滤器
+func ApiKeyFilter(cfg ApiKeyConfig) SecurityFilter {
+
+	f := &apiKeyFilter{
+		name:      cfg.Name,
+		in:        cfg.In,
+		key:       cfg.Key,
+		validator: cfg.Validator,
+	}
+
+	if f.validator == nil {
+		panic(errors.New("api key auth requires a validator"))
+	}
+	if f.name == "" {
+		f.name = "ApiKeyAuth"
+	}
+	if f.key == "" {
+		f.key = "X-API-Key"
+	}
+
+	switch f.in {
+	case "":
+		f.in = "header"
+	case "header", "query", "cookie":
+	default:
+		panic(errors.New("api key must be in header, query or cookie"))
+	}
+	return f
+}
+
+func (f *apiKeyFilter) Security() (string, *spec.SecurityScheme, []string) {
+	return f.name, spec.APIKeyAuth(f.key, f.in), nil
+}
+
+func (f *apiKeyFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	var key string
+	switch f.in {
+	case "header":
+		key = ctx.GetHeader(f.key)
+	case "query":
+		key = ctx.QueryParam(f.key)
+	case "cookie":
+		if c, err := ctx.Cookie(f.key); err == nil {
+			key = c.Value
+		}
+	}
+
+	if key == "" {
+		unauthorized(ctx, "", "missing api key")
+		return
+	}
+
+	p := f.validator(key)
+	if p == nil {
+		unauthorized(ctx, "", "invalid api key")
+		return
+	}
+
+	p.Scheme = "ApiKey"
+	SetPrincipal(ctx, p)
+	chain.Next(ctx)
+}
+
+/////////////////// JWT //////////////////////
+
+// jwtFilter JWT Bearer 认证过滤器
+type jwtFilter struct {
+	name       string
+	rolesClaim string
+	verifier   *JwtVerifier
+}
+
+// JwtFilter 返回 JWT Bearer 认证过滤器，令牌从 Authorization: Bearer 请求头中读取
+func JwtFilter(cfg JwtConfig) SecurityFilter {
+
+	f := &jwtFilter{
+		name:       cfg.Name,
+		rolesClaim: cfg.RolesClaim,
+		verifier:   NewJwtVerifier(cfg),
+	}
+
+	if f.name == "" {
+		f.name = "BearerAuth"
+	}
+	if f.rolesClaim == "" {
+		f.rolesClaim = "roles"
+	}
+	return f
+}
+
+// bearerScheme 返回 Bearer 认证的安全定义，Swagger 2.0 不支持 Bearer 认证，
+// 使用 Authorization 请求头的 apiKey 表示，导出 OpenAPI 3 时转换回 http bearer。
+func bearerScheme(format string) *spec.SecurityScheme {
+	s := spec.APIKeyAuth(HeaderAuthorization, "header")
+	s.AddExtension("x-bearer-format", format)
+	return s
+}
+
+func (f *jwtFilter) Security() (string, *spec.SecurityScheme, []string) {
+	return f.name, bearerScheme("JWT"), nil
+}
+
+func (f *jwtFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	token := bearerToken(ctx)
+	if token == "" {
+		unauthorized(ctx, "Bearer", "missing bearer token")
+		return
+	}
+
+	claims, err := f.verifier.Verify(token)
+	if err != nil {
+		unauthorized(ctx, `Bearer error="invalid_token"`, err.Error())
+		return
+	}
+
+	SetPrincipal(ctx, &Principal{
+		Scheme: "Bearer",
+		Name:   claims.Subject(),
+		Roles:  claims.Strings(f.rolesClaim),
+		Scopes: claims.Strings("scope"),
+		Claims: claims,
+	})
+	chain.Next(ctx)
+}
+
+// bearerToken 返回 Authorization 请求头中的 Bearer 令牌
+func bearerToken(ctx WebContext) string {
+	auth := ctx.GetHeader(HeaderAuthorization)
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(auth[7:])
+}
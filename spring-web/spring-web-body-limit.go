@@ -0,0 +1,193 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrBodyTooLarge 请求体超过了限制的大小
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// BodyLimitConfig 请求体大小限制的配置
+type BodyLimitConfig struct {
+
+	// Limit 请求体的最大字节数，必须大于 0
+	Limit int64
+
+	// MultipartMemory multipart/form-data 请求在内存中保存的最大字节数，超出的文件
+	// 保存到临时文件中。大于 0 时过滤器会预先解析表单，为 0 时使用适配器默认的 32MB。
+	MultipartMemory int64
+}
+
+// bodyLimitFilter 请求体大小限制过滤器
+type bodyLimitFilter struct {
+	limit           int64
+	multipartMemory int64
+}
+
+// BodyLimitFilter 返回限制请求体大小的过滤器，可以作为全局、路由分组或者单个处理函数
+// 的过滤器使用，多个过滤器同时生效时以最小的限制为准。Content-Length 超出限制的请求
+// 直接返回 413，没有 Content-Length 的请求在读取时计数，超出限制之后读取返回
+// ErrBodyTooLarge，并且不论处理函数输出什么都返回 413。
+func BodyLimitFilter(cfg BodyLimitConfig) Filter {
+	if cfg.Limit <= 0 {
+		panic(errors.New("body limit must be greater than 0"))
+	}
+	return &bodyLimitFilter{
+		limit:           cfg.Limit,
+		multipartMemory: cfg.MultipartMemory,
+	}
+}
+
+func (f *bodyLimitFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+	w := ctx.ResponseWriter()
+
+	if r.ContentLength > f.limit {
+		writeBodyTooLarge(w)
+		return
+	}
+
+	if r.Body == nil || r.Body == http.NoBody {
+		chain.Next(ctx)
+		return
+	}
+
+	body := &limitedBody{ReadCloser: r.Body, limit: f.limit}
+	r.Body = body
+
+	lw := &bodyLimitWriter{ResponseWriter: w, body: body}
+	ctx.SetResponseWriter(lw)
+	defer ctx.SetResponseWriter(w)
+
+	// 预先解析表单，超出内存限制的文件保存到临时文件中
+	if f.multipartMemory > 0 && strings.HasPrefix(r.Header.Get(HeaderContentType), MIMEMultipartForm) {
+		if err := r.ParseMultipartForm(f.multipartMemory); err != nil {
+			if body.exceeded {
+				lw.reject()
+			} else {
+				ctx.String(http.StatusBadRequest, "invalid multipart form: %v", err)
+			}
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+	}
+
+	chain.Next(ctx)
+
+	// 处理函数没有输出时也要返回 413
+	if body.exceeded && !lw.rejected {
+		lw.reject()
+	}
+}
+
+// writeBodyTooLarge 输出 413 响应，并且要求关闭连接，避免继续读取剩余的请求体
+func writeBodyTooLarge(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del(HeaderContentLength)
+	h.Del(HeaderContentEncoding)
+	h.Set(HeaderContentType, MIMETextPlainCharsetUTF8)
+	h.Set("Connection", "close")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	_, _ = io.WriteString(w, ErrBodyTooLarge.Error())
+}
+
+// limitedBody 读取时计数的请求体
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	n        int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+
+	// 多读一个字节用于判断是否超出限制
+	if remain := b.limit - b.n + 1; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	if b.n += int64(n); b.n > b.limit {
+		b.exceeded = true
+		return n - int(b.n-b.limit), ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// bodyLimitWriter 请求体超出限制之后把响应替换成 413
+type bodyLimitWriter struct {
+	http.ResponseWriter
+
+	body        *limitedBody
+	wroteHeader bool
+	rejected    bool
+}
+
+// reject 输出 413 响应，已经输出了响应头时无法再修改
+func (w *bodyLimitWriter) reject() {
+	if w.rejected {
+		return
+	}
+	w.rejected = true
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		writeBodyTooLarge(w.ResponseWriter)
+	}
+}
+
+func (w *bodyLimitWriter) WriteHeader(code int) {
+	if w.body.exceeded {
+		w.reject()
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyLimitWriter) Write(b []byte) (int, error) {
+	if w.body.exceeded {
+		w.reject()
+		return len(b), nil
+	}
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyLimitWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *bodyLimitWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http.Hijacker not implemented")
+}
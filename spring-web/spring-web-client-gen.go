@@ -0,0 +1,382 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// springWebPkgPath 生成的代码引用的 SpringWeb 包路径
+const springWebPkgPath = "github.com/go-spring/go-spring-web/spring-web"
+
+// clientMethodOrder 一个 Mapper 注册了多个 HTTP 方法时，客户端使用的方法的优先级
+var clientMethodOrder = []uint32{
+	MethodGet, MethodPost, MethodPut, MethodPatch, MethodDelete, MethodHead, MethodOptions,
+}
+
+// ClientGenerator 根据路由表中 BIND 形式的处理函数生成类型化的 Go 客户端，
+// 每个 Router 生成一个客户端类型，直接注册在 WebMapping 上的接口归入 Client。
+type ClientGenerator struct {
+	pkgName string
+	imports map[string]string // 包路径 -> 别名，每次生成时重置
+	aliases map[string]bool
+}
+
+// NewClientGenerator ClientGenerator 的构造函数
+func NewClientGenerator(pkgName string) *ClientGenerator {
+	return &ClientGenerator{pkgName: pkgName}
+}
+
+// clientGroup 一个客户端类型
+type clientGroup struct {
+	typeName string
+	basePath string
+	methods  []*clientMethod
+	names    map[string]bool // 已使用的方法名
+}
+
+// clientMethod 客户端类型的一个方法
+type clientMethod struct {
+	name       string
+	httpMethod string
+	path       string
+	reqType    reflect.Type
+	respType   reflect.Type
+}
+
+// Generate 生成客户端代码并写入 w
+func (g *ClientGenerator) Generate(mapping WebMapping, w io.Writer) error {
+
+	g.imports = make(map[string]string)
+	g.aliases = make(map[string]bool)
+
+	groups, err := g.collect(mapping)
+	if err != nil {
+		return err
+	}
+
+	// 先生成方法体以收集需要导入的包
+	var body bytes.Buffer
+	for _, group := range groups {
+		if err = g.writeGroup(&body, group); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by SpringWeb.ClientGenerator. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", g.pkgName)
+	buf.WriteString("import (\n\t\"context\"\n\t\"net/http\"\n\n")
+
+	var pkgPaths []string
+	for pkgPath := range g.imports {
+		pkgPaths = append(pkgPaths, pkgPath)
+	}
+	sort.Strings(pkgPaths)
+	for _, pkgPath := range pkgPaths {
+		fmt.Fprintf(&buf, "\t%s %q\n", g.imports[pkgPath], pkgPath)
+	}
+	buf.WriteString(")\n")
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("format generated client error: %v", err)
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+// collect 按照 Router 分组收集 BIND 形式的处理函数
+func (g *ClientGenerator) collect(mapping WebMapping) ([]*clientGroup, error) {
+
+	var mappers []*Mapper
+	for _, m := range mapping.Mappers() {
+		if _, ok := m.handler.(*bindHandler); ok {
+			mappers = append(mappers, m)
+		}
+	}
+
+	sort.Slice(mappers, func(i, j int) bool {
+		if mappers[i].path == mappers[j].path {
+			return mappers[i].method < mappers[j].method
+		}
+		return mappers[i].path < mappers[j].path
+	})
+
+	var groups []*clientGroup
+	byRouter := make(map[*Router]*clientGroup)
+	typeNames := make(map[string]bool)
+
+	for _, m := range mappers {
+		group, ok := byRouter[m.router]
+		if !ok {
+			group = &clientGroup{names: make(map[string]bool)}
+			if m.router != nil {
+				group.basePath = m.router.basePath
+			}
+			group.typeName = uniqueName(exportedName(group.basePath)+"Client", typeNames)
+			byRouter[m.router] = group
+			groups = append(groups, group)
+		}
+
+		h := m.handler.(*bindHandler)
+
+		httpMethod := ""
+		for _, method := range clientMethodOrder {
+			if m.method&method == method {
+				httpMethod = methods[method]
+				break
+			}
+		}
+		if httpMethod == "" {
+			return nil, fmt.Errorf("unsupported method 0x%.4x of %s", m.method, m.path)
+		}
+
+		// 方法名优先使用处理函数的名称，闭包等无法使用时根据路径生成
+		_, _, fnName := h.FileLine()
+		if i := strings.LastIndex(fnName, "."); i >= 0 {
+			fnName = fnName[i+1:]
+		}
+		if !isExportedIdent(fnName) {
+			p := strings.TrimPrefix(m.path, group.basePath)
+			fnName = exportedName(strings.ToLower(httpMethod) + "/" + p)
+		}
+
+		group.methods = append(group.methods, &clientMethod{
+			name:       uniqueName(fnName, group.names),
+			httpMethod: httpMethod,
+			path:       m.path,
+			reqType:    h.bindType,
+			respType:   h.fnVal.Type().Out(0),
+		})
+	}
+
+	return groups, nil
+}
+
+func (g *ClientGenerator) writeGroup(w *bytes.Buffer, group *clientGroup) error {
+
+	desc := group.basePath
+	if desc == "" {
+		desc = "/"
+	}
+
+	fmt.Fprintf(w, "\n// %s 路由 %s 的客户端\n", group.typeName, desc)
+	fmt.Fprintf(w, "type %s struct {\n\trpc *%s.RpcClient\n}\n", group.typeName, g.alias(springWebPkgPath))
+
+	fmt.Fprintf(w, "\n// New%s %s 的构造函数\n", group.typeName, group.typeName)
+	fmt.Fprintf(w, "func New%s(baseURL string, httpClient *http.Client) *%s {\n", group.typeName, group.typeName)
+	fmt.Fprintf(w, "\treturn &%s{rpc: %s.NewRpcClient(baseURL, httpClient)}\n}\n", group.typeName, g.alias(springWebPkgPath))
+
+	fmt.Fprintf(w, "\n// New%sWithRpcClient 使用配置好的 RpcClient 创建 %s，例如设置了 ResultUnwrapper 的 RpcClient\n", group.typeName, group.typeName)
+	fmt.Fprintf(w, "func New%sWithRpcClient(rpc *%s.RpcClient) *%s {\n", group.typeName, g.alias(springWebPkgPath), group.typeName)
+	fmt.Fprintf(w, "\treturn &%s{rpc: rpc}\n}\n", group.typeName)
+
+	for _, m := range group.methods {
+
+		reqExpr, err := g.typeExpr(m.reqType)
+		if err != nil {
+			return err
+		}
+
+		respExpr, err := g.typeExpr(m.respType)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "\n// %s %s %s\n", m.name, m.httpMethod, m.path)
+		fmt.Fprintf(w, "func (c *%s) %s(ctx context.Context, req %s) (%s, error) {\n", group.typeName, m.name, reqExpr, respExpr)
+		fmt.Fprintf(w, "\tvar resp %s\n", respExpr)
+		fmt.Fprintf(w, "\terr := c.rpc.Call(ctx, %q, %q, req, &resp)\n", m.httpMethod, m.path)
+		fmt.Fprintf(w, "\treturn resp, err\n}\n")
+	}
+	return nil
+}
+
+// alias 返回包的导入别名
+func (g *ClientGenerator) alias(pkgPath string) string {
+	if a, ok := g.imports[pkgPath]; ok {
+		return a
+	}
+	base := path.Base(pkgPath)
+	if pkgPath == springWebPkgPath {
+		base = "SpringWeb"
+	}
+	a := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, base)
+	if a == "" || unicode.IsDigit(rune(a[0])) {
+		a = "_" + a
+	}
+	a = uniqueName(a, g.aliases)
+	g.imports[pkgPath] = a
+	return a
+}
+
+// typeExpr 返回类型在生成代码中的表达式
+func (g *ClientGenerator) typeExpr(t reflect.Type) (string, error) {
+
+	if t.Name() != "" {
+		if t.PkgPath() == "" { // 内置类型
+			return t.Name(), nil
+		}
+		if strings.HasSuffix(t.PkgPath(), "_test") || t.PkgPath() == "main" {
+			return "", fmt.Errorf("type %s can't be imported", t.String())
+		}
+		if !isExportedIdent(t.Name()) {
+			return "", fmt.Errorf("type %s isn't exported", t.String())
+		}
+		return g.alias(t.PkgPath()) + "." + t.Name(), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s, err := g.typeExpr(t.Elem())
+		return "*" + s, err
+	case reflect.Slice:
+		s, err := g.typeExpr(t.Elem())
+		return "[]" + s, err
+	case reflect.Array:
+		s, err := g.typeExpr(t.Elem())
+		return "[" + strconv.Itoa(t.Len()) + "]" + s, err
+	case reflect.Map:
+		k, err := g.typeExpr(t.Key())
+		if err != nil {
+			return "", err
+		}
+		v, err := g.typeExpr(t.Elem())
+		return "map[" + k + "]" + v, err
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "interface{}", nil
+		}
+	case reflect.Struct:
+		var fields []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			s, err := g.typeExpr(f.Type)
+			if err != nil {
+				return "", err
+			}
+			field := f.Name + " " + s
+			if f.Anonymous {
+				field = s
+			}
+			if f.Tag != "" {
+				field += " " + strconv.Quote(string(f.Tag))
+			}
+			fields = append(fields, field)
+		}
+		return "struct {" + strings.Join(fields, "; ") + "}", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", t.String())
+}
+
+// exportedName 将路径等字符串转换成导出的标识符
+func exportedName(s string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	name := sb.String()
+	if name != "" && unicode.IsDigit(rune(name[0])) {
+		name = "N" + name
+	}
+	return name
+}
+
+func isExportedIdent(s string) bool {
+	for i, r := range s {
+		if i == 0 && !unicode.IsUpper(r) {
+			return false
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// uniqueName 返回不和 used 中重复的名称，并将其加入 used
+func uniqueName(name string, used map[string]bool) string {
+	r := name
+	for i := 2; used[r]; i++ {
+		r = name + strconv.Itoa(i)
+	}
+	used[r] = true
+	return r
+}
+
+// GenerateClientFile 使用路由表生成客户端代码并写入 output 文件，output 为空时
+// 输出到标准输出，pkgName 为空时使用输出文件所在目录的名称。通常在 go generate
+// 调用的生成程序中使用：生成程序注册好路由之后调用该函数，例如：
+//
+//	//go:generate go run ./client-gen -o client/client.go
+func GenerateClientFile(mapping WebMapping, output string, pkgName string) error {
+
+	if pkgName == "" {
+		if output == "" {
+			pkgName = "client"
+		} else {
+			abs, err := filepath.Abs(output)
+			if err != nil {
+				return err
+			}
+			pkgName = filepath.Base(filepath.Dir(abs))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewClientGenerator(pkgName).Generate(mapping, &buf); err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(output, buf.Bytes(), 0644)
+}
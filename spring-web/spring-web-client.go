@@ -0,0 +1,340 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-spring/go-spring-parent/spring-error"
+)
+
+// RpcResultError 服务端返回的 RpcResult 不是成功时转换成的错误
+type RpcResultError struct {
+	Code int32  // 错误码
+	Msg  string // 错误信息
+	Err  string // 错误源
+}
+
+func (e *RpcResultError) Error() string {
+	if e.Err != "" {
+		return fmt.Sprintf("rpc error %d %s: %s", e.Code, e.Msg, e.Err)
+	}
+	return fmt.Sprintf("rpc error %d %s", e.Code, e.Msg)
+}
+
+// HttpStatusError 服务端返回非 2xx 状态码时的错误
+type HttpStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HttpStatusError) Error() string {
+	return fmt.Sprintf("http status %d: %s", e.StatusCode, e.Body)
+}
+
+// ResultUnwrapper 从响应中解析出 ResultWrapper 封装的结果，客户端需要使用和服务端
+// ResultWrapper 配套的 ResultUnwrapper
+type ResultUnwrapper interface {
+	// Unwrap 解析响应体，成功时将数据解析到 out 中，out 可能为 nil
+	Unwrap(statusCode int, body []byte, out interface{}) error
+}
+
+// DefaultResultUnwrapper 默认的 ResultUnwrapper，和 DefaultResultWrapper 配套使用，
+// 解析 SpringError.RpcResult 格式的响应
+var DefaultResultUnwrapper ResultUnwrapper = &rpcResultUnwrapper{}
+
+// rpcResultEnvelope 用于解析 SpringError.RpcResult
+type rpcResultEnvelope struct {
+	Code int32
+	Msg  string
+	Err  string
+	Data json.RawMessage
+}
+
+type rpcResultUnwrapper struct{}
+
+func (u *rpcResultUnwrapper) Unwrap(statusCode int, body []byte, out interface{}) error {
+
+	// 绑定失败等情况下服务端可能返回非 2xx 状态码和 RpcResult
+	var result rpcResultEnvelope
+	if err := json.Unmarshal(body, &result); err != nil {
+		if statusCode < 200 || statusCode >= 300 {
+			return &HttpStatusError{StatusCode: statusCode, Body: string(body)}
+		}
+		return err
+	}
+
+	if result.Code == SpringError.SUCCESS.Code && (statusCode < 200 || statusCode >= 300) {
+		return &HttpStatusError{StatusCode: statusCode, Body: string(body)}
+	}
+
+	if result.Code != SpringError.SUCCESS.Code {
+		return &RpcResultError{Code: result.Code, Msg: result.Msg, Err: result.Err}
+	}
+
+	if out != nil && len(result.Data) > 0 {
+		return json.Unmarshal(result.Data, out)
+	}
+	return nil
+}
+
+// RpcClient 调用 BIND 形式接口的客户端，生成的客户端代码基于它实现
+type RpcClient struct {
+	baseURL    string
+	httpClient *http.Client
+	unwrapper  ResultUnwrapper
+}
+
+// NewRpcClient RpcClient 的构造函数，httpClient 为 nil 时使用 http.DefaultClient
+func NewRpcClient(baseURL string, httpClient *http.Client) *RpcClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &RpcClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: httpClient,
+		unwrapper:  DefaultResultUnwrapper,
+	}
+}
+
+// WithResultUnwrapper 设置解析响应的 ResultUnwrapper，服务端使用自定义的
+// ResultWrapper 时需要设置配套的 ResultUnwrapper
+func (c *RpcClient) WithResultUnwrapper(u ResultUnwrapper) *RpcClient {
+	c.unwrapper = u
+	return c
+}
+
+// Call 调用接口，GET、DELETE 和 HEAD 请求的参数编码到查询字符串中，其他请求
+// 使用 JSON 编码。路径参数从 param、uri、json 标签或者同名字段中获取。响应体
+// 交给 ResultUnwrapper 解析，默认的 ResultUnwrapper 在 RpcResult 不是成功时返回
+// *RpcResultError，否则将 Data 解析到 out 中。
+func (c *RpcClient) Call(ctx context.Context, method string, path string, req interface{}, out interface{}) error {
+
+	fields := clientFields(req)
+
+	path, used, err := expandClientPath(path, fields)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	target := c.baseURL + path
+
+	switch method {
+	case http.MethodGet, http.MethodDelete, http.MethodHead:
+		query := url.Values{}
+		for _, f := range fields {
+			if !used[f.name] {
+				query[f.query] = append(query[f.query], f.values...)
+			}
+		}
+		if len(query) > 0 {
+			target += "?" + query.Encode()
+		}
+	default:
+		b, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	r, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return err
+	}
+
+	if ctx != nil {
+		r = r.WithContext(ctx)
+	}
+
+	if body != nil {
+		r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	}
+	r.Header.Set("Accept", MIMEApplicationJSON)
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return c.unwrapper.Unwrap(resp.StatusCode, b, out)
+}
+
+// clientField 请求参数的一个字段
+type clientField struct {
+	name   string   // 字段名称
+	param  string   // 路径参数名称
+	query  string   // 查询参数名称
+	json   string   // JSON 名称
+	values []string // 字符串形式的值
+}
+
+// clientFields 提取请求参数的字段
+func clientFields(req interface{}) []*clientField {
+	if req == nil {
+		return nil
+	}
+	v := reflect.ValueOf(req)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var fields []*clientField
+	appendClientFields(v, &fields)
+	return fields
+}
+
+func appendClientFields(v reflect.Value, fields *[]*clientField) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		fv := v.Field(i)
+
+		// 展开嵌入的结构体
+		if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+			appendClientFields(fv, fields)
+			continue
+		}
+
+		if ft.PkgPath != "" {
+			continue
+		}
+
+		f := &clientField{name: ft.Name, json: tagName(ft, "json")}
+		if f.param = tagName(ft, "param"); f.param == "" {
+			f.param = tagName(ft, "uri")
+		}
+		if f.query = tagName(ft, "query"); f.query == "" {
+			if f.query = tagName(ft, "form"); f.query == "" {
+				f.query = f.json
+			}
+		}
+		if f.query == "" {
+			f.query = ft.Name
+		}
+		if f.query == "-" {
+			continue
+		}
+
+		f.values = clientValues(fv)
+		*fields = append(*fields, f)
+	}
+}
+
+// tagName 返回标签中的名称部分
+func tagName(f reflect.StructField, key string) string {
+	tag, ok := f.Tag.Lookup(key)
+	if !ok {
+		return ""
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+// clientValues 将字段值转换成字符串形式
+func clientValues(v reflect.Value) []string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return []string{t.Format(time.RFC3339Nano)}
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var r []string
+		for i := 0; i < v.Len(); i++ {
+			r = append(r, clientValues(v.Index(i))...)
+		}
+		return r
+	case reflect.Struct, reflect.Map:
+		b, _ := json.Marshal(v.Interface())
+		return []string{string(b)}
+	}
+	return []string{fmt.Sprint(v.Interface())}
+}
+
+// expandClientPath 使用请求参数替换路径中的参数，返回被使用的字段
+func expandClientPath(path string, fields []*clientField) (string, map[string]bool, error) {
+
+	used := make(map[string]bool)
+
+	if !strings.ContainsAny(path, ":{*") {
+		return path, used, nil
+	}
+
+	path, _ = ToPathStyle(path, EchoPathStyle)
+
+	find := func(name string) *clientField {
+		for _, f := range fields {
+			if f.param == name {
+				return f
+			}
+		}
+		for _, f := range fields {
+			if f.json == name || strings.EqualFold(f.name, name) {
+				return f
+			}
+		}
+		return nil
+	}
+
+	ss := strings.Split(path, "/")
+	for i, s := range ss {
+		if s == "" || (s[0] != ':' && s[0] != '*') {
+			continue
+		}
+		name := s[1:]
+		if s == "*" {
+			name = "*"
+		}
+		f := find(name)
+		if f == nil || len(f.values) == 0 {
+			return "", nil, fmt.Errorf("missing path param %s", name)
+		}
+		used[f.name] = true
+		if s[0] == '*' {
+			ss[i] = f.values[0]
+		} else {
+			ss[i] = url.PathEscape(f.values[0])
+		}
+	}
+	return strings.Join(ss, "/"), used, nil
+}
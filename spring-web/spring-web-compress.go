@@ -0,0 +1,523 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompressEncoder 压缩编码器，Flush 把已经写入的内容编码输出，用于流式响应
+type CompressEncoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// CompressEncoderFunc 创建压缩编码器的函数，level 为 CompressConfig.Level
+type CompressEncoderFunc func(w io.Writer, level int) (CompressEncoder, error)
+
+// CompressDecoderFunc 创建解压缩请求体的函数
+type CompressDecoderFunc func(r io.Reader) (io.ReadCloser, error)
+
+// compressCodecs 注册的编码器和解码器
+var compressCodecs = struct {
+	mutex    sync.RWMutex
+	names    []string // 编码器的注册顺序
+	encoders map[string]CompressEncoderFunc
+	decoders map[string]CompressDecoderFunc
+}{
+	encoders: make(map[string]CompressEncoderFunc),
+	decoders: make(map[string]CompressDecoderFunc),
+}
+
+func init() {
+
+	RegisterCompressEncoder("gzip", func(w io.Writer, level int) (CompressEncoder, error) {
+		return gzip.NewWriterLevel(w, level)
+	})
+	RegisterCompressEncoder("deflate", func(w io.Writer, level int) (CompressEncoder, error) {
+		return flate.NewWriter(w, level)
+	})
+
+	RegisterCompressDecoder("gzip", func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+	RegisterCompressDecoder("deflate", func(r io.Reader) (io.ReadCloser, error) {
+		return flate.NewReader(r), nil
+	})
+}
+
+// RegisterCompressEncoder 注册 Content-Encoding 为 encoding 的压缩编码器，例如
+// brotli 的编码器可以这样注册：
+//
+//	RegisterCompressEncoder("br", func(w io.Writer, level int) (CompressEncoder, error) {
+//		return brotli.NewWriterLevel(w, brotli.DefaultCompression), nil
+//	})
+func RegisterCompressEncoder(encoding string, fn CompressEncoderFunc) {
+	compressCodecs.mutex.Lock()
+	defer compressCodecs.mutex.Unlock()
+	encoding = strings.ToLower(encoding)
+	if _, ok := compressCodecs.encoders[encoding]; !ok {
+		compressCodecs.names = append(compressCodecs.names, encoding)
+	}
+	compressCodecs.encoders[encoding] = fn
+}
+
+// RegisterCompressDecoder 注册 Content-Encoding 为 encoding 的请求体解码器
+func RegisterCompressDecoder(encoding string, fn CompressDecoderFunc) {
+	compressCodecs.mutex.Lock()
+	defer compressCodecs.mutex.Unlock()
+	compressCodecs.decoders[strings.ToLower(encoding)] = fn
+}
+
+// compressEncoder 返回 encoding 对应的编码器
+func compressEncoder(encoding string) (CompressEncoderFunc, bool) {
+	compressCodecs.mutex.RLock()
+	defer compressCodecs.mutex.RUnlock()
+	fn, ok := compressCodecs.encoders[encoding]
+	return fn, ok
+}
+
+// compressDecoder 返回 encoding 对应的解码器
+func compressDecoder(encoding string) (CompressDecoderFunc, bool) {
+	compressCodecs.mutex.RLock()
+	defer compressCodecs.mutex.RUnlock()
+	fn, ok := compressCodecs.decoders[encoding]
+	return fn, ok
+}
+
+// compressEncodings 返回编码器的注册顺序
+func compressEncodings() []string {
+	compressCodecs.mutex.RLock()
+	defer compressCodecs.mutex.RUnlock()
+	return append([]string(nil), compressCodecs.names...)
+}
+
+// DefaultCompressMinLength 默认压缩的最小响应长度
+const DefaultCompressMinLength = 1024
+
+// DefaultCompressExcludedTypes 默认不压缩的 MIME 类型，这些内容通常已经压缩过了
+var DefaultCompressExcludedTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp",
+	"video/*", "audio/*", "font/woff", "font/woff2",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-brotli", "application/x-7z-compressed",
+	"application/x-rar-compressed", "application/pdf",
+	MIMEOctetStream,
+}
+
+// CompressConfig 响应压缩的配置
+type CompressConfig struct {
+
+	// Level 压缩级别，为 0 时使用 gzip.DefaultCompression
+	Level int
+
+	// MinLength 压缩的最小响应长度，为 0 时使用 DefaultCompressMinLength，为负数时总是压缩
+	MinLength int
+
+	// Encodings 服务端对编码方式的偏好顺序，为空时使用编码器的注册顺序
+	Encodings []string
+
+	// ExcludedTypes 不压缩的 MIME 类型，支持 video/* 形式，为空时使用 DefaultCompressExcludedTypes
+	ExcludedTypes []string
+}
+
+// compressFilter 响应压缩过滤器
+type compressFilter struct {
+	level     int
+	minLength int
+	encodings []string
+	excluded  []string
+}
+
+// CompressFilter 返回压缩响应的过滤器，根据 Accept-Encoding 选择编码方式。过滤器会
+// 缓存响应的开头部分，长度达到 MinLength 之后才决定是否压缩，因此太小的响应、已经
+// 设置了 Content-Encoding 的响应以及 ExcludedTypes 中的响应都会原样输出。SSE 和
+// NDJSON 等流式响应只有在底层的 http.ResponseWriter 支持 Flush 时才会压缩，每次
+// Flush 时先输出编码器中的数据，保证客户端能够及时收到每个事件。
+func CompressFilter(cfg CompressConfig) Filter {
+
+	f := &compressFilter{
+		level:     cfg.Level,
+		minLength: cfg.MinLength,
+		excluded:  cfg.ExcludedTypes,
+	}
+
+	if f.level == 0 {
+		f.level = gzip.DefaultCompression
+	}
+	if f.minLength == 0 {
+		f.minLength = DefaultCompressMinLength
+	}
+	if f.excluded == nil {
+		f.excluded = DefaultCompressExcludedTypes
+	}
+
+	for _, encoding := range cfg.Encodings {
+		encoding = strings.ToLower(encoding)
+		if _, ok := compressEncoder(encoding); !ok {
+			panic(errors.New("unregistered compress encoding " + encoding))
+		}
+		f.encodings = append(f.encodings, encoding)
+	}
+	return f
+}
+
+func (f *compressFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	w := ctx.ResponseWriter()
+	w.Header().Add(HeaderVary, HeaderAcceptEncoding)
+
+	r := ctx.Request()
+	if r.Method == http.MethodHead {
+		chain.Next(ctx)
+		return
+	}
+
+	encoding := f.negotiate(r.Header.Get(HeaderAcceptEncoding))
+	if encoding == "" {
+		chain.Next(ctx)
+		return
+	}
+
+	cw := &compressWriter{ResponseWriter: w, filter: f, encoding: encoding}
+	ctx.SetResponseWriter(cw)
+	defer ctx.SetResponseWriter(w)
+	defer cw.close(ctx)
+
+	chain.Next(ctx)
+}
+
+// negotiate 根据 Accept-Encoding 选择编码方式，没有合适的编码方式时返回空字符串
+func (f *compressFilter) negotiate(accept string) string {
+
+	if accept == "" {
+		return ""
+	}
+
+	encodings := f.encodings
+	if len(encodings) == 0 {
+		encodings = compressEncodings()
+	}
+
+	accepted := make(map[string]float64)
+	for _, s := range strings.Split(accept, ",") {
+		ss := strings.Split(s, ";")
+		name := strings.ToLower(strings.TrimSpace(ss[0]))
+		q := 1.0
+		for _, p := range ss[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range encodings {
+		q, ok := accepted[encoding]
+		if !ok {
+			if q, ok = accepted["*"]; !ok {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// excludedType 是否不压缩 contentType 类型的响应
+func (f *compressFilter) excludedType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, t := range f.excluded {
+		if t == mediaType {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStreamType 是否为流式响应的类型
+func isStreamType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case MIMETextEventStream, MIMEApplicationNDJSON, MIMEJsonStream:
+		return true
+	}
+	return false
+}
+
+// compressWriter 压缩响应的 http.ResponseWriter，在确定是否压缩之前缓存响应的状态码和内容
+type compressWriter struct {
+	http.ResponseWriter
+
+	filter   *compressFilter
+	encoding string
+
+	status  int
+	buf     []byte
+	decided bool            // 是否已经决定了是否压缩
+	encoder CompressEncoder // 不为 nil 时表示压缩
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.decided && w.status == 0 {
+		w.status = code
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.filter.minLength || isStreamType(w.Header().Get(HeaderContentType)) {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// decide 决定是否压缩，然后输出缓存的状态码和内容，large 表示响应足够大
+func (w *compressWriter) decide(large bool) error {
+
+	w.decided = true
+	h := w.Header()
+
+	if h.Get(HeaderContentType) == "" && len(w.buf) > 0 {
+		h.Set(HeaderContentType, http.DetectContentType(w.buf))
+	}
+
+	compress := large && w.compressible()
+
+	if compress {
+		fn, _ := compressEncoder(w.encoding)
+		encoder, err := fn(w.ResponseWriter, w.filter.level)
+		if err != nil {
+			return err
+		}
+		w.encoder = encoder
+		h.Set(HeaderContentEncoding, w.encoding)
+		h.Del(HeaderContentLength)
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// compressible 响应是否可以压缩
+func (w *compressWriter) compressible() bool {
+
+	if w.status != 0 && (w.status < http.StatusOK || w.status == http.StatusNoContent ||
+		w.status == http.StatusNotModified) {
+		return false
+	}
+
+	h := w.Header()
+	if h.Get(HeaderContentEncoding) != "" {
+		return false
+	}
+
+	contentType := h.Get(HeaderContentType)
+	if w.filter.excludedType(contentType) {
+		return false
+	}
+
+	// 不能及时输出的流式响应不压缩
+	if isStreamType(contentType) {
+		_, ok := w.ResponseWriter.(http.Flusher)
+		return ok
+	}
+	return true
+}
+
+func (w *compressWriter) Flush() {
+
+	// 主动 Flush 的响应按照流式响应处理
+	if !w.decided {
+		_ = w.decide(len(w.buf) >= w.filter.minLength || isStreamType(w.Header().Get(HeaderContentType)))
+	}
+
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("http.Hijacker not implemented")
+}
+
+// close 输出剩余的内容，没有达到压缩长度的响应原样输出
+func (w *compressWriter) close(ctx WebContext) {
+
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			return // 没有任何输出，交给适配器处理
+		}
+		if err := w.decide(false); err != nil {
+			ctx.LogError("compress response error: ", err)
+		}
+	}
+
+	if w.encoder != nil {
+		if err := w.encoder.Close(); err != nil {
+			ctx.LogError("compress response error: ", err)
+		}
+	}
+}
+
+// DefaultDecompressMaxSize 解压缩之后请求体的默认最大字节数
+const DefaultDecompressMaxSize = 32 << 20
+
+// DecompressConfig 请求体解压缩的配置
+type DecompressConfig struct {
+
+	// MaxSize 解压缩之后请求体的最大字节数，超出时返回 413，为 0 时使用
+	// DefaultDecompressMaxSize。很小的压缩内容可以解压出非常大的数据，因此
+	// 放在 DecompressFilter 之前的 BodyLimitFilter 只能限制压缩之后的大小。
+	MaxSize int64
+}
+
+// decompressFilter 请求体解压缩过滤器
+type decompressFilter struct {
+	maxSize int64
+}
+
+// DecompressFilter 返回解压缩请求体的过滤器，支持使用 RegisterCompressDecoder 注册的
+// Content-Encoding，不支持的编码方式返回 415，请求体不是有效的压缩内容时返回 400，
+// 解压缩之后超出 MaxSize 时读取返回 ErrBodyTooLarge，并且不论处理函数输出什么都返回 413。
+func DecompressFilter(cfg DecompressConfig) Filter {
+	if cfg.MaxSize < 0 {
+		panic(errors.New("decompress max size must not be negative"))
+	}
+	if cfg.MaxSize == 0 {
+		cfg.MaxSize = DefaultDecompressMaxSize
+	}
+	return &decompressFilter{maxSize: cfg.MaxSize}
+}
+
+func (f *decompressFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+	w := ctx.ResponseWriter()
+
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get(HeaderContentEncoding)))
+	if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+		chain.Next(ctx)
+		return
+	}
+
+	fn, ok := compressDecoder(encoding)
+	if !ok {
+		ctx.NoContent(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	decoded, err := fn(r.Body)
+	if err != nil {
+		ctx.String(http.StatusBadRequest, "invalid %s request body", encoding)
+		return
+	}
+
+	body := &limitedBody{
+		ReadCloser: &decompressBody{ReadCloser: decoded, origin: r.Body},
+		limit:      f.maxSize,
+	}
+
+	r.Body = body
+	r.ContentLength = -1
+	r.Header.Del(HeaderContentEncoding)
+	r.Header.Del(HeaderContentLength)
+
+	lw := &bodyLimitWriter{ResponseWriter: w, body: body}
+	ctx.SetResponseWriter(lw)
+	defer ctx.SetResponseWriter(w)
+
+	chain.Next(ctx)
+
+	// 处理函数没有输出时也要返回 413
+	if body.exceeded && !lw.rejected {
+		lw.reject()
+	}
+}
+
+// decompressBody 解压缩之后的请求体，关闭时同时关闭原始的请求体
+type decompressBody struct {
+	io.ReadCloser
+	origin io.Closer
+}
+
+func (b *decompressBody) Close() error {
+	err := b.ReadCloser.Close()
+	if e := b.origin.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -17,13 +17,46 @@
 package SpringWeb
 
 const (
+	HeaderAccept             = "Accept"
+	HeaderAcceptEncoding     = "Accept-Encoding"
+	HeaderAcceptLanguage     = "Accept-Language"
+	HeaderAuthorization      = "Authorization"
 	HeaderContentDisposition = "Content-Disposition"
+	HeaderContentEncoding    = "Content-Encoding"
+	HeaderContentLength      = "Content-Length"
 	HeaderContentType        = "Content-Type"
+	HeaderOrigin             = "Origin"
+	HeaderVary               = "Vary"
+	HeaderWWWAuthenticate    = "WWW-Authenticate"
+	HeaderXForwardedFor      = "X-Forwarded-For"
 	HeaderXForwardedProto    = "X-Forwarded-Proto"
 	HeaderXForwardedProtocol = "X-Forwarded-Protocol"
 	HeaderXForwardedSsl      = "X-Forwarded-Ssl"
+	HeaderXRealIP            = "X-Real-IP"
+	HeaderXRequestId         = "X-Request-Id"
 	HeaderXUrlScheme         = "X-Url-Scheme"
 
+	HeaderStrictTransportSecurity         = "Strict-Transport-Security"
+	HeaderContentSecurityPolicy           = "Content-Security-Policy"
+	HeaderContentSecurityPolicyReportOnly = "Content-Security-Policy-Report-Only"
+	HeaderXContentTypeOptions             = "X-Content-Type-Options"
+	HeaderXFrameOptions                   = "X-Frame-Options"
+	HeaderReferrerPolicy                  = "Referrer-Policy"
+	HeaderRetryAfter                      = "Retry-After"
+	HeaderRateLimitLimit                  = "RateLimit-Limit"
+	HeaderRateLimitRemaining              = "RateLimit-Remaining"
+	HeaderRateLimitReset                  = "RateLimit-Reset"
+	HeaderPermissionsPolicy               = "Permissions-Policy"
+
+	HeaderAccessControlRequestMethod    = "Access-Control-Request-Method"
+	HeaderAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	HeaderAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	HeaderAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	HeaderAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	HeaderAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	HeaderAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	HeaderAccessControlMaxAge           = "Access-Control-Max-Age"
+
 	CharsetUTF8 = "charset=UTF-8"
 
 	MIMEApplicationJSON                  = "application/json"
@@ -34,6 +67,7 @@ const (
 	MIMEApplicationXMLCharsetUTF8        = MIMEApplicationXML + "; " + CharsetUTF8
 	MIMETextXML                          = "text/xml"
 	MIMETextXMLCharsetUTF8               = MIMETextXML + "; " + CharsetUTF8
+	MIMEApplicationYAML                  = "application/yaml"
 	MIMEApplicationForm                  = "application/x-www-form-urlencoded"
 	MIMEApplicationProtobuf              = "application/protobuf"
 	MIMEApplicationMsgpack               = "application/msgpack"
@@ -45,6 +79,8 @@ const (
 	MIMEOctetStream                      = "application/octet-stream"
 	MIMEJsonAPI                          = "application/vnd.api+json"
 	MIMEJsonStream                       = "application/x-json-stream"
+	MIMEApplicationNDJSON                = "application/x-ndjson"
+	MIMETextEventStream                  = "text/event-stream"
 	MIMEImagePng                         = "image/png"
 	MIMEImageJpeg                        = "image/jpeg"
 	MIMEImageGif                         = "image/gif"
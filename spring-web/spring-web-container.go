@@ -22,11 +22,11 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
 	"time"
 
 	"github.com/go-spring/go-spring-parent/spring-logger"
 	"github.com/go-spring/go-spring-parent/spring-utils"
-	"github.com/swaggo/http-swagger"
 )
 
 // HandlerFunc 标准 Web 处理函数
@@ -82,9 +82,42 @@ type WebContainer interface {
 	// SetRecoveryFilter 设置 Recovery Filter
 	SetRecoveryFilter(filter Filter)
 
+	// GetResultWrapper 获取 RPC 结果的封装器
+	GetResultWrapper() ResultWrapper
+
+	// SetResultWrapper 设置 RPC 结果的封装器，Router 可以使用 WithResultWrapper 覆盖
+	SetResultWrapper(wrapper ResultWrapper)
+
 	// AddRouter 添加新的路由信息
 	AddRouter(router *Router)
 
+	// Swagger 返回容器的 Swagger 文档
+	Swagger() *swagger
+
+	// SwaggerGroup 返回容器中名为 group 的 Swagger 文档分组
+	SwaggerGroup(group string) *swagger
+
+	// SwaggerGroups 返回容器中所有 Swagger 文档分组的名称
+	SwaggerGroups() []string
+
+	// GetDocConfig 获取文档接口的配置
+	GetDocConfig() DocConfig
+
+	// SetDocConfig 设置文档接口的路径、过滤器以及内嵌的静态资源
+	SetDocConfig(cfg DocConfig)
+
+	// BuildDocs 根据路由表生成 Swagger 文档，不需要启动容器
+	BuildDocs()
+
+	// GetSpecValidation 获取使用文档校验请求和响应的配置
+	GetSpecValidation() SpecValidation
+
+	// SetSpecValidation 设置使用文档校验请求和响应的配置，默认不校验
+	SetSpecValidation(cfg SpecValidation)
+
+	// SpecValidationFilter 返回使用 mapper 的文档校验请求和响应的过滤器，可能为 nil
+	SpecValidationFilter(mapper *Mapper) Filter
+
 	// EnableSwagger 是否启用 Swagger 功能
 	EnableSwagger() bool
 
@@ -108,6 +141,16 @@ type BaseWebContainer struct {
 
 	loggerFilter   Filter // 日志过滤器
 	recoveryFilter Filter // 恢复过滤器
+
+	resultWrapper ResultWrapper // RPC 结果的封装器
+
+	swagger   *swagger            // 容器的 Swagger 文档
+	docGroups map[string]*swagger // Swagger 文档分组
+	docConfig DocConfig           // 文档接口的配置
+	docsBuilt bool                // 是否已经生成文档
+
+	specValidation SpecValidation       // 使用文档校验请求和响应的配置
+	mapperDocs     map[*Mapper]*swagger // 处理函数所在的文档
 }
 
 // NewBaseWebContainer BaseWebContainer 的构造函数
@@ -166,6 +209,16 @@ func (c *BaseWebContainer) SetRecoveryFilter(filter Filter) {
 	c.recoveryFilter = filter
 }
 
+// GetResultWrapper 获取 RPC 结果的封装器
+func (c *BaseWebContainer) GetResultWrapper() ResultWrapper {
+	return c.resultWrapper
+}
+
+// SetResultWrapper 设置 RPC 结果的封装器
+func (c *BaseWebContainer) SetResultWrapper(wrapper ResultWrapper) {
+	c.resultWrapper = wrapper
+}
+
 // AddRouter 添加新的路由信息
 func (c *BaseWebContainer) AddRouter(router *Router) {
 	for _, mapper := range router.mapping.Mappers() {
@@ -173,6 +226,61 @@ func (c *BaseWebContainer) AddRouter(router *Router) {
 	}
 }
 
+// Swagger 返回容器的 Swagger 文档，第一次调用时复制全局文档作为初始内容
+func (c *BaseWebContainer) Swagger() *swagger {
+	if c.swagger == nil {
+		c.swagger = doc.clone(true)
+	}
+	return c.swagger
+}
+
+// SwaggerGroup 返回容器中名为 group 的 Swagger 文档分组，第一次调用时复制
+// 容器文档中除路径以外的内容，group 为空时返回容器的文档。
+func (c *BaseWebContainer) SwaggerGroup(group string) *swagger {
+	if group == "" {
+		return c.Swagger()
+	}
+	if c.docGroups == nil {
+		c.docGroups = make(map[string]*swagger)
+	}
+	s, ok := c.docGroups[group]
+	if !ok {
+		s = c.Swagger().clone(false)
+		c.docGroups[group] = s
+	}
+	return s
+}
+
+// SwaggerGroups 返回容器中所有 Swagger 文档分组的名称，按照名称排序
+func (c *BaseWebContainer) SwaggerGroups() []string {
+	var names []string
+	for group := range c.docGroups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetDocConfig 获取文档接口的配置
+func (c *BaseWebContainer) GetDocConfig() DocConfig {
+	return c.docConfig
+}
+
+// SetDocConfig 设置文档接口的路径、过滤器以及内嵌的静态资源
+func (c *BaseWebContainer) SetDocConfig(cfg DocConfig) {
+	c.docConfig = cfg
+}
+
+// GetSpecValidation 获取使用文档校验请求和响应的配置
+func (c *BaseWebContainer) GetSpecValidation() SpecValidation {
+	return c.specValidation
+}
+
+// SetSpecValidation 设置使用文档校验请求和响应的配置
+func (c *BaseWebContainer) SetSpecValidation(cfg SpecValidation) {
+	c.specValidation = cfg
+}
+
 // EnableSwagger 是否启用 Swagger 功能
 func (c *BaseWebContainer) EnableSwagger() bool {
 	return c.enableSwg
@@ -185,28 +293,68 @@ func (c *BaseWebContainer) SetEnableSwagger(enable bool) {
 
 // PreStart 执行 Start 之前的准备工作
 func (c *BaseWebContainer) PreStart() {
-
 	if c.enableSwg {
+		c.BuildDocs()
+		c.registerDocHandlers()
+	}
+}
+
+// BuildDocs 根据路由表生成容器的 Swagger 文档，不需要启动容器，多次调用只生成一次
+func (c *BaseWebContainer) BuildDocs() {
+
+	if c.docsBuilt {
+		return
+	}
+	c.docsBuilt = true
+	c.mapperDocs = make(map[*Mapper]*swagger)
+
+	var mappers []*Mapper
+	for _, mapper := range c.Mappers() {
+		mappers = append(mappers, mapper)
+	}
+	sort.Slice(mappers, func(i, j int) bool {
+		return mappers[i].Key() < mappers[j].Key()
+	})
+
+	// 按照路由分组的设置将处理函数分配到不同的文档
+	groups := map[string][]*Mapper{"": nil}
+	for _, mapper := range mappers {
+		group := ""
+		if r := mapper.router; r != nil {
+			if r.docExcluded {
+				continue
+			}
+			group = r.docGroup
+		}
+		groups[group] = append(groups[group], mapper)
+		c.SwaggerGroup(group)
+	}
+
+	for group, mappers := range groups {
+		d := c.SwaggerGroup(group)
+
+		// 根据 BIND 处理函数的签名补全 Operation
+		d.deriveOperations(mappers)
 
 		// 注册 path 的 Operation
-		for _, mapper := range c.Mappers() {
+		for _, mapper := range mappers {
 			if op := mapper.swagger; op != nil {
 				if err := op.parseBind(); err != nil {
 					panic(err)
 				}
-				doc.AddPath(mapper.Path(), mapper.Method(), op)
-			}
-		}
 
-		// 注册 swagger-ui 和 doc.json 接口
-		c.HandleGet("/swagger/*", HTTP(httpSwagger.Handler(
-			httpSwagger.URL("/swagger/doc.json"),
-		)))
+				// 根据认证过滤器添加安全定义
+				filters := append([]Filter{}, c.GetFilters()...)
+				d.applySecurity(op, append(filters, mapper.Filters()...))
 
-		// 注册 redoc 接口
-		c.GetMapping("/redoc", ReDoc)
+				d.AddPath(mapper.Path(), mapper.Method(), op)
+				c.mapperDocs[mapper] = d
+			}
+			if j, ok := mapper.handler.(*JsonRpc); ok {
+				j.doc = d
+			}
+		}
 	}
-
 }
 
 // PrintMapper 打印路由注册信息
@@ -45,6 +45,9 @@ type WebContext interface {
 	// Get retrieves data from the context.
 	Get(key string) interface{}
 
+	// Lookup retrieves data from the context, reports whether the key exists.
+	Lookup(key string) (interface{}, bool)
+
 	// Set saves data in the context.
 	Set(key string, val interface{})
 
@@ -54,6 +57,10 @@ type WebContext interface {
 	// Request returns `*http.Request`.
 	Request() *http.Request
 
+	// SetRequest sets `*http.Request`, the LoggerContext is rebuilt
+	// from the request's context.
+	SetRequest(r *http.Request)
+
 	// IsTLS returns true if HTTP connection is TLS otherwise false.
 	IsTLS() bool
 
@@ -133,6 +140,10 @@ type WebContext interface {
 	// ResponseWriter returns `http.ResponseWriter`.
 	ResponseWriter() http.ResponseWriter
 
+	// SetResponseWriter replaces the `http.ResponseWriter` used by the
+	// following filters and the handler, e.g. to compress or record the body.
+	SetResponseWriter(w http.ResponseWriter)
+
 	// Status sets the HTTP response code.
 	Status(code int)
 
@@ -204,3 +215,9 @@ type WebContext interface {
 	// SSEvent writes a Server-Sent Event into the body stream.
 	SSEvent(name string, message interface{})
 }
+
+// lookupValue 返回 WebContext 中保存的值，不存在时返回 nil 而不是 panic
+func lookupValue(ctx WebContext, key string) interface{} {
+	val, _ := ctx.Lookup(key)
+	return val
+}
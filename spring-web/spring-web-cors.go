@@ -0,0 +1,227 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCorsAllowMethods 默认允许跨域访问的方法
+var DefaultCorsAllowMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPut,
+	http.MethodPatch, http.MethodPost, http.MethodDelete,
+}
+
+// CorsConfig 跨域资源共享 (CORS) 的配置
+type CorsConfig struct {
+
+	// AllowOrigins 允许的源，"*" 表示所有的源，支持 https://*.example.com 形式的通配符
+	AllowOrigins []string
+
+	// AllowOriginPatterns 使用正则表达式描述允许的源，需要匹配整个源
+	AllowOriginPatterns []string
+
+	// AllowOriginFunc 自定义源的检查函数，在上面两项都不匹配时调用
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods 预检请求允许的方法，为空时使用 DefaultCorsAllowMethods
+	AllowMethods []string
+
+	// AllowHeaders 预检请求允许的请求头，为空时允许预检请求声明的所有请求头
+	AllowHeaders []string
+
+	// ExposeHeaders 允许浏览器读取的响应头
+	ExposeHeaders []string
+
+	// AllowCredentials 是否允许携带 Cookie 等凭证，此时返回请求的源。允许携带凭证时
+	// AllowOrigins 不能包含 "*"，否则任意站点都可以读取带凭证的响应，需要列出具体的源
+	// 或者使用通配符、正则表达式以及 AllowOriginFunc 进行限制。
+	AllowCredentials bool
+
+	// MaxAge 预检结果的缓存时间，为 0 时不返回 Access-Control-Max-Age
+	MaxAge time.Duration
+}
+
+// corsFilter 跨域资源共享过滤器
+type corsFilter struct {
+	allowAll      bool
+	origins       map[string]bool
+	patterns      []*regexp.Regexp
+	originFunc    func(origin string) bool
+	allowMethods  string
+	allowHeaders  string
+	exposeHeaders string
+	credentials   bool
+	maxAge        string
+}
+
+// CorsFilter 返回实现跨域资源共享的过滤器，预检请求在过滤器中直接返回，不会执行
+// 后面的过滤器和处理函数。预检请求不携带凭证，因此 CorsFilter 需要放在认证、授权
+// 等过滤器的前面。使用 Router.WithCors 时会为路由自动注册 OPTIONS 方法，并且把
+// 跨域过滤器放在路由分组其他过滤器的前面。
+func CorsFilter(cfg CorsConfig) Filter {
+
+	f := &corsFilter{
+		origins:     make(map[string]bool),
+		originFunc:  cfg.AllowOriginFunc,
+		credentials: cfg.AllowCredentials,
+	}
+
+	for _, origin := range cfg.AllowOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			if cfg.AllowCredentials {
+				panic(errors.New("cors: AllowOrigins \"*\" can't be used with AllowCredentials"))
+			}
+			f.allowAll = true
+		} else if strings.Contains(origin, "*") {
+			f.patterns = append(f.patterns, wildcardOrigin(origin))
+		} else {
+			f.origins[origin] = true
+		}
+	}
+
+	for _, pattern := range cfg.AllowOriginPatterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			panic(fmt.Errorf("invalid cors origin pattern %q: %v", pattern, err))
+		}
+		f.patterns = append(f.patterns, re)
+	}
+
+	methods := cfg.AllowMethods
+	if len(methods) == 0 {
+		methods = DefaultCorsAllowMethods
+	}
+	f.allowMethods = strings.ToUpper(strings.Join(methods, ", "))
+	f.allowHeaders = strings.Join(cfg.AllowHeaders, ", ")
+	f.exposeHeaders = strings.Join(cfg.ExposeHeaders, ", ")
+
+	if cfg.MaxAge > 0 {
+		f.maxAge = strconv.FormatInt(int64(cfg.MaxAge/time.Second), 10)
+	}
+	return f
+}
+
+// wildcardOrigin 把带有通配符的源转换为正则表达式，通配符只匹配域名中的字符
+func wildcardOrigin(origin string) *regexp.Regexp {
+	parts := strings.Split(origin, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, "[a-z0-9.-]+") + "$")
+}
+
+// allowOrigin 源是否允许跨域访问
+func (f *corsFilter) allowOrigin(origin string) bool {
+
+	if f.allowAll {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+	if f.origins[lower] {
+		return true
+	}
+
+	for _, re := range f.patterns {
+		if re.MatchString(lower) {
+			return true
+		}
+	}
+
+	return f.originFunc != nil && f.originFunc(origin)
+}
+
+// isPreflight 是否为 CORS 预检请求
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get(HeaderAccessControlRequestMethod) != ""
+}
+
+func (f *corsFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+	h := ctx.ResponseWriter().Header()
+
+	origin := r.Header.Get(HeaderOrigin)
+	preflight := isPreflight(r)
+
+	// 响应内容和请求的源有关，需要告知缓存
+	if !f.allowAll {
+		h.Add(HeaderVary, HeaderOrigin)
+	}
+
+	// 非跨域请求
+	if origin == "" {
+		chain.Next(ctx)
+		return
+	}
+
+	if !f.allowOrigin(origin) {
+		if preflight {
+			ctx.NoContent(http.StatusForbidden)
+			return
+		}
+		chain.Next(ctx)
+		return
+	}
+
+	if f.allowAll {
+		h.Set(HeaderAccessControlAllowOrigin, "*")
+	} else {
+		h.Set(HeaderAccessControlAllowOrigin, origin)
+	}
+
+	if f.credentials {
+		h.Set(HeaderAccessControlAllowCredentials, "true")
+	}
+
+	if !preflight {
+		if f.exposeHeaders != "" {
+			h.Set(HeaderAccessControlExposeHeaders, f.exposeHeaders)
+		}
+		chain.Next(ctx)
+		return
+	}
+
+	h.Add(HeaderVary, HeaderAccessControlRequestMethod)
+	h.Add(HeaderVary, HeaderAccessControlRequestHeaders)
+	h.Set(HeaderAccessControlAllowMethods, f.allowMethods)
+
+	if f.allowHeaders != "" {
+		h.Set(HeaderAccessControlAllowHeaders, f.allowHeaders)
+	} else if headers := r.Header.Get(HeaderAccessControlRequestHeaders); headers != "" {
+		h.Set(HeaderAccessControlAllowHeaders, headers)
+	}
+
+	if f.maxAge != "" {
+		h.Set(HeaderAccessControlMaxAge, f.maxAge)
+	}
+
+	ctx.NoContent(http.StatusNoContent)
+}
+
+// corsPreflight 自动注册的 OPTIONS 处理函数，只处理不是预检请求的 OPTIONS 请求
+func corsPreflight(ctx WebContext) {
+	ctx.NoContent(http.StatusNoContent)
+}
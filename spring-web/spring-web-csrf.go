@@ -0,0 +1,392 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CsrfKey CSRF 令牌在 WebContext 中的键
+const CsrfKey = "@CsrfToken"
+
+// ErrInvalidCsrfToken CSRF 令牌缺失或者不匹配
+var ErrInvalidCsrfToken = errors.New("invalid csrf token")
+
+// CsrfStore 同步令牌模式下保存会话令牌的存储
+type CsrfStore interface {
+
+	// Get 返回会话的令牌，不存在时返回空字符串
+	Get(session string) string
+
+	// Set 保存会话的令牌
+	Set(session string, token string)
+}
+
+// csrfEntry 内存中保存的令牌
+type csrfEntry struct {
+	token  string
+	expire time.Time
+}
+
+// memoryCsrfStore 基于内存的 CsrfStore
+type memoryCsrfStore struct {
+	ttl       time.Duration
+	mutex     sync.Mutex
+	tokens    map[string]csrfEntry
+	lastSweep time.Time
+}
+
+// NewMemoryCsrfStore 返回基于内存的 CsrfStore，适合单实例部署。令牌在保存 ttl 之后
+// 过期，过期的令牌会被定期清理，ttl 为 0 时使用 24 小时。
+func NewMemoryCsrfStore(ttl time.Duration) CsrfStore {
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &memoryCsrfStore{ttl: ttl, tokens: make(map[string]csrfEntry)}
+}
+
+func (s *memoryCsrfStore) Get(session string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if e, ok := s.tokens[session]; ok && time.Now().Before(e.expire) {
+		return e.token
+	}
+	return ""
+}
+
+func (s *memoryCsrfStore) Set(session string, token string) {
+
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// 定期清理过期的令牌
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, e := range s.tokens {
+			if !now.Before(e.expire) {
+				delete(s.tokens, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.tokens[session] = csrfEntry{token: token, expire: now.Add(s.ttl)}
+}
+
+// CsrfConfig CSRF 过滤器的配置。默认使用双重提交 Cookie 模式，Cookie 中的令牌使用
+// Secret 进行 HMAC 签名，设置了 Session 时签名还和会话绑定。同时设置 Store 和
+// Session 时使用同步令牌模式，令牌保存在服务端的会话中。
+type CsrfConfig struct {
+
+	// TokenLookup 令牌的位置，多个位置用逗号分隔，格式为 header:<名称>、form:<名称>
+	// 或者 query:<名称>，为空时使用 header:X-CSRF-Token,form:_csrf
+	TokenLookup string
+
+	// TokenLength 令牌的随机字节数，为 0 时使用 32
+	TokenLength int
+
+	// Secret 双重提交模式签名令牌的密钥，为空时使用启动时生成的随机密钥，
+	// 多实例部署时需要设置相同的密钥
+	Secret []byte
+
+	// CookieName 双重提交模式保存令牌的 Cookie，为空时使用 _csrf
+	CookieName string
+
+	// CookiePath、CookieDomain、CookieSecure、CookieHTTPOnly 令牌 Cookie 的属性，
+	// CookiePath 为空时使用 /
+	CookiePath     string
+	CookieDomain   string
+	CookieSecure   bool
+	CookieHTTPOnly bool
+
+	// CookieMaxAge 令牌 Cookie 的有效期，为 0 时使用 24 小时
+	CookieMaxAge time.Duration
+
+	// CookieSameSite 令牌 Cookie 的 SameSite 属性，为 0 时使用 Lax
+	CookieSameSite http.SameSite
+
+	// Store 同步令牌模式的令牌存储，需要同时设置 Session
+	Store CsrfStore
+
+	// Session 返回请求的会话标识，没有会话时返回空字符串。同步令牌模式下用于
+	// 读写 Store，双重提交模式下令牌的签名和会话绑定，会话变化之后令牌失效。
+	Session func(ctx WebContext) string
+
+	// ExemptPaths 不需要检查的路径，可以是任意 PathStyleEnum 风格的路径
+	ExemptPaths []string
+
+	// Skipper 返回 true 时不检查令牌
+	Skipper func(ctx WebContext) bool
+}
+
+// csrfLookup 令牌的位置
+type csrfLookup struct {
+	source string
+	name   string
+}
+
+// csrfState 请求的令牌和表单字段名称，保存在 WebContext 中供模板使用
+type csrfState struct {
+	token string
+	field string
+}
+
+// csrfFilter CSRF 过滤器
+type csrfFilter struct {
+	cfg     CsrfConfig
+	secret  []byte
+	lookups []csrfLookup
+	field   string // 模板中隐藏表单字段的名称
+	exempt  []pathPattern
+}
+
+// CsrfFilter 返回防止跨站请求伪造的过滤器。GET、HEAD、OPTIONS、TRACE 请求只下发
+// 令牌，其他请求需要提交和 Cookie 或者会话中相同的令牌，否则返回 403。处理函数和
+// 模板可以通过 CsrfToken、CsrfField 或者 CsrfFuncMap 获取令牌。
+func CsrfFilter(cfg CsrfConfig) Filter {
+
+	if cfg.Store != nil && cfg.Session == nil {
+		panic(errors.New("csrf store requires a session"))
+	}
+
+	if cfg.TokenLookup == "" {
+		cfg.TokenLookup = "header:X-CSRF-Token,form:_csrf"
+	}
+	if cfg.TokenLength <= 0 {
+		cfg.TokenLength = 32
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "_csrf"
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.CookieMaxAge == 0 {
+		cfg.CookieMaxAge = 24 * time.Hour
+	}
+	if cfg.CookieSameSite == 0 {
+		cfg.CookieSameSite = http.SameSiteLaxMode
+	}
+
+	f := &csrfFilter{cfg: cfg, secret: cfg.Secret, field: "_csrf"}
+
+	if len(f.secret) == 0 {
+		f.secret = make([]byte, 32)
+		if _, err := rand.Read(f.secret); err != nil {
+			panic(err)
+		}
+	}
+
+	for _, s := range strings.Split(cfg.TokenLookup, ",") {
+		ss := strings.SplitN(strings.TrimSpace(s), ":", 2)
+		if len(ss) != 2 || ss[1] == "" {
+			panic(fmt.Errorf("invalid csrf token lookup %q", s))
+		}
+		switch ss[0] {
+		case "header", "query":
+		case "form":
+			f.field = ss[1]
+		default:
+			panic(fmt.Errorf("invalid csrf token lookup %q", s))
+		}
+		f.lookups = append(f.lookups, csrfLookup{source: ss[0], name: ss[1]})
+	}
+
+	for _, path := range cfg.ExemptPaths {
+		p, err := compilePathPattern(path)
+		if err != nil {
+			panic(fmt.Errorf("invalid csrf exempt path %q", path))
+		}
+		f.exempt = append(f.exempt, p)
+	}
+	return f
+}
+
+func (f *csrfFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+
+	if f.skip(ctx) {
+		chain.Next(ctx)
+		return
+	}
+
+	var session string
+	if f.cfg.Session != nil {
+		session = f.cfg.Session(ctx)
+	}
+
+	expected := f.expectedToken(ctx, session)
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+	default:
+		submitted := f.submittedToken(ctx)
+		if expected == "" || submitted == "" ||
+			subtle.ConstantTimeCompare([]byte(expected), []byte(submitted)) != 1 {
+			ctx.String(http.StatusForbidden, "%s", ErrInvalidCsrfToken.Error())
+			return
+		}
+	}
+
+	// 没有令牌时生成新的令牌，同步令牌模式下没有会话的请求不能生成令牌
+	if expected == "" && (f.cfg.Store == nil || session != "") {
+		expected = f.newToken(ctx, session)
+	}
+
+	if expected != "" {
+		ctx.Set(CsrfKey, &csrfState{token: expected, field: f.field})
+	}
+	chain.Next(ctx)
+}
+
+// skip 是否跳过检查
+func (f *csrfFilter) skip(ctx WebContext) bool {
+	if f.cfg.Skipper != nil && f.cfg.Skipper(ctx) {
+		return true
+	}
+	if len(f.exempt) > 0 {
+		segments := splitPath(ctx.Request().URL.Path)
+		for _, p := range f.exempt {
+			if p.match(segments) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expectedToken 返回 Cookie 或者会话中的令牌
+func (f *csrfFilter) expectedToken(ctx WebContext, session string) string {
+	if f.cfg.Store != nil {
+		if session == "" {
+			return ""
+		}
+		return f.cfg.Store.Get(session)
+	}
+	if c, err := ctx.Cookie(f.cfg.CookieName); err == nil && f.verify(session, c.Value) {
+		return c.Value
+	}
+	return ""
+}
+
+// sign 返回带有签名的令牌，格式为 <令牌>.<签名>，签名同时覆盖会话标识
+func (f *csrfFilter) sign(session string, token string) string {
+	mac := hmac.New(sha256.New, f.secret)
+	_, _ = mac.Write([]byte(session))
+	_, _ = mac.Write([]byte{0})
+	_, _ = mac.Write([]byte(token))
+	return token + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify 检查 Cookie 中令牌的签名
+func (f *csrfFilter) verify(session string, value string) bool {
+	i := strings.LastIndexByte(value, '.')
+	if i <= 0 {
+		return false
+	}
+	return hmac.Equal([]byte(f.sign(session, value[:i])), []byte(value))
+}
+
+// submittedToken 返回请求提交的令牌
+func (f *csrfFilter) submittedToken(ctx WebContext) string {
+	for _, l := range f.lookups {
+		var token string
+		switch l.source {
+		case "header":
+			token = ctx.GetHeader(l.name)
+		case "form":
+			token = ctx.FormValue(l.name)
+		case "query":
+			token = ctx.QueryParam(l.name)
+		}
+		if token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// newToken 生成新的令牌并且保存到 Cookie 或者会话中
+func (f *csrfFilter) newToken(ctx WebContext, session string) string {
+
+	b := make([]byte, f.cfg.TokenLength)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	if f.cfg.Store != nil {
+		f.cfg.Store.Set(session, token)
+		return token
+	}
+
+	token = f.sign(session, token)
+	ctx.SetCookie(&http.Cookie{
+		Name:     f.cfg.CookieName,
+		Value:    token,
+		Path:     f.cfg.CookiePath,
+		Domain:   f.cfg.CookieDomain,
+		MaxAge:   int(f.cfg.CookieMaxAge / time.Second),
+		Expires:  time.Now().Add(f.cfg.CookieMaxAge),
+		Secure:   f.cfg.CookieSecure,
+		HttpOnly: f.cfg.CookieHTTPOnly,
+		SameSite: f.cfg.CookieSameSite,
+	})
+	return token
+}
+
+// CsrfToken 返回请求的 CSRF 令牌，没有使用 CsrfFilter 时返回空字符串
+func CsrfToken(ctx WebContext) string {
+	if s, ok := lookupValue(ctx, CsrfKey).(*csrfState); ok {
+		return s.token
+	}
+	return ""
+}
+
+// CsrfField 返回包含 CSRF 令牌的隐藏表单字段，用于注入到 HTML 表单中
+func CsrfField(ctx WebContext) template.HTML {
+	s, ok := lookupValue(ctx, CsrfKey).(*csrfState)
+	if !ok {
+		return ""
+	}
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(s.field) +
+		`" value="` + template.HTMLEscapeString(s.token) + `">`)
+}
+
+// CsrfFuncMap 返回模板函数 csrfToken 和 csrfField，例如:
+//
+//	t := template.Must(template.New("form").Funcs(SpringWeb.CsrfFuncMap(ctx)).Parse(form))
+//	<form method="post">{{csrfField}}</form>
+func CsrfFuncMap(ctx WebContext) template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": func() string { return CsrfToken(ctx) },
+		"csrfField": func() template.HTML { return CsrfField(ctx) },
+	}
+}
@@ -0,0 +1,447 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"gopkg.in/yaml.v2"
+)
+
+// SpecChange 两个版本文档之间的一处差异
+type SpecChange struct {
+	Breaking bool   // 是否为不兼容的变更
+	Location string // 变更的位置，例如 GET /pet/{id} response 200 .name
+	Message  string // 变更的描述
+}
+
+func (c SpecChange) String() string {
+	level := "non-breaking"
+	if c.Breaking {
+		level = "breaking"
+	}
+	return fmt.Sprintf("[%s] %s: %s", level, c.Location, c.Message)
+}
+
+// SpecDiff 两个版本文档之间的差异
+type SpecDiff struct {
+	Changes []SpecChange
+}
+
+// HasBreaking 是否存在不兼容的变更
+func (d *SpecDiff) HasBreaking() bool {
+	for _, c := range d.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Breaking 返回不兼容的变更
+func (d *SpecDiff) Breaking() []SpecChange {
+	var r []SpecChange
+	for _, c := range d.Changes {
+		if c.Breaking {
+			r = append(r, c)
+		}
+	}
+	return r
+}
+
+func (d *SpecDiff) String() string {
+	var sb strings.Builder
+	for _, c := range d.Changes {
+		sb.WriteString(c.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func (d *SpecDiff) add(breaking bool, location string, format string, args ...interface{}) {
+	d.Changes = append(d.Changes, SpecChange{
+		Breaking: breaking,
+		Location: location,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// LoadSpec 解析 JSON 或者 YAML 格式的 Swagger 2.0 文档，OpenAPI 3 文档会先转换成
+// Swagger 2.0 文档，无法表示的内容 (例如 Cookie 参数) 会被忽略。
+func LoadSpec(b []byte) (*spec.Swagger, error) {
+
+	var v interface{}
+	if t := bytes.TrimSpace(b); len(t) > 0 && t[0] == '{' {
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := yaml.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		v = yamlToJsonValue(v)
+	}
+
+	if m, ok := v.(map[string]interface{}); ok {
+		if _, ok = m["openapi"]; ok {
+			v = openApiToSwagger(m)
+		}
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	s := new(spec.Swagger)
+	if err = s.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// yamlToJsonValue 将 YAML 解析出的 map[interface{}]interface{} 转换成 JSON 支持的类型
+func yamlToJsonValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, e := range x {
+			m[fmt.Sprint(k)] = yamlToJsonValue(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range x {
+			x[i] = yamlToJsonValue(e)
+		}
+	}
+	return v
+}
+
+// DiffSpec 比较两个 JSON 或者 YAML 格式的 Swagger 2.0 文档
+func DiffSpec(old []byte, new []byte) (*SpecDiff, error) {
+	o, err := LoadSpec(old)
+	if err != nil {
+		return nil, err
+	}
+	n, err := LoadSpec(new)
+	if err != nil {
+		return nil, err
+	}
+	return DiffSwagger(o, n), nil
+}
+
+// DiffSwagger 比较两个版本的 Swagger 2.0 文档，删除路径或者操作、新增必填的参数或者
+// 字段、修改类型、删除响应中的字段等视为不兼容的变更，新增路径、可选参数等视为兼容的变更。
+func DiffSwagger(old *spec.Swagger, new *spec.Swagger) *SpecDiff {
+	d := &SpecDiff{}
+	d.diffPaths(old, new)
+	d.diffDefinitions(old, new)
+	return d
+}
+
+// specOperations 返回路径中所有的操作
+func specOperations(item spec.PathItem) map[string]*spec.Operation {
+	ops := map[string]*spec.Operation{
+		http.MethodGet:     item.Get,
+		http.MethodPut:     item.Put,
+		http.MethodPost:    item.Post,
+		http.MethodDelete:  item.Delete,
+		http.MethodOptions: item.Options,
+		http.MethodHead:    item.Head,
+		http.MethodPatch:   item.Patch,
+	}
+	for k, v := range ops {
+		if v == nil {
+			delete(ops, k)
+		}
+	}
+	return ops
+}
+
+// sortedKeys 返回映射排序之后的键，整数键按照数值排序
+func sortedKeys(m interface{}) []string {
+
+	v := reflect.ValueOf(m)
+	keys := v.MapKeys()
+
+	if v.Type().Key().Kind() == reflect.Int {
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Int() < keys[j].Int() })
+		r := make([]string, 0, len(keys))
+		for _, k := range keys {
+			r = append(r, strconv.FormatInt(k.Int(), 10))
+		}
+		return r
+	}
+
+	r := make([]string, 0, len(keys))
+	for _, k := range keys {
+		r = append(r, k.String())
+	}
+	sort.Strings(r)
+	return r
+}
+
+// specPaths 返回文档的路径，路径为空时返回空表
+func specPaths(s *spec.Swagger) map[string]spec.PathItem {
+	if s.Paths == nil {
+		return map[string]spec.PathItem{}
+	}
+	return s.Paths.Paths
+}
+
+func (d *SpecDiff) diffPaths(old *spec.Swagger, new *spec.Swagger) {
+
+	oldPaths, newPaths := specPaths(old), specPaths(new)
+
+	for _, path := range sortedKeys(oldPaths) {
+		newItem, ok := newPaths[path]
+		if !ok {
+			d.add(true, path, "path removed")
+			continue
+		}
+		oldOps, newOps := specOperations(oldPaths[path]), specOperations(newItem)
+		for _, method := range sortedKeys(oldOps) {
+			location := method + " " + path
+			if newOp, ok := newOps[method]; !ok {
+				d.add(true, location, "operation removed")
+			} else {
+				d.diffOperation(location, oldOps[method], newOp)
+			}
+		}
+		for _, method := range sortedKeys(newOps) {
+			if _, ok := oldOps[method]; !ok {
+				d.add(false, method+" "+path, "operation added")
+			}
+		}
+	}
+
+	for _, path := range sortedKeys(newPaths) {
+		if _, ok := oldPaths[path]; !ok {
+			d.add(false, path, "path added")
+		}
+	}
+}
+
+// paramKey 参数的唯一标识
+func paramKey(p spec.Parameter) string {
+	return p.In + ":" + p.Name
+}
+
+func (d *SpecDiff) diffOperation(location string, old *spec.Operation, new *spec.Operation) {
+
+	oldParams := make(map[string]spec.Parameter)
+	for _, p := range old.Parameters {
+		oldParams[paramKey(p)] = p
+	}
+	newParams := make(map[string]spec.Parameter)
+	for _, p := range new.Parameters {
+		newParams[paramKey(p)] = p
+	}
+
+	for _, key := range sortedKeys(oldParams) {
+		p := oldParams[key]
+		loc := location + " parameter " + p.Name + " (" + p.In + ")"
+		np, ok := newParams[key]
+		if !ok {
+			d.add(false, loc, "parameter removed")
+			continue
+		}
+		if np.Required && !p.Required {
+			d.add(true, loc, "parameter became required")
+		}
+		if p.In == "body" {
+			d.diffSchema(loc, p.Schema, np.Schema, true, false)
+		} else if p.Type != np.Type || p.Format != np.Format {
+			d.add(true, loc, "type changed from %s to %s", typeName(p.Type, p.Format), typeName(np.Type, np.Format))
+		}
+	}
+
+	for _, key := range sortedKeys(newParams) {
+		if _, ok := oldParams[key]; ok {
+			continue
+		}
+		p := newParams[key]
+		loc := location + " parameter " + p.Name + " (" + p.In + ")"
+		if p.Required {
+			d.add(true, loc, "required parameter added")
+		} else {
+			d.add(false, loc, "optional parameter added")
+		}
+	}
+
+	oldResps, newResps := operationResponses(old), operationResponses(new)
+	for _, code := range sortedKeys(oldResps) {
+		c, _ := strconv.Atoi(code)
+		loc := location + " response " + responseName(code)
+		nr, ok := newResps[c]
+		if !ok {
+			d.add(true, loc, "response removed")
+			continue
+		}
+		d.diffSchema(loc, oldResps[c].Schema, nr.Schema, false, true)
+	}
+	for _, code := range sortedKeys(newResps) {
+		c, _ := strconv.Atoi(code)
+		if _, ok := oldResps[c]; !ok {
+			d.add(false, location+" response "+responseName(code), "response added")
+		}
+	}
+}
+
+// responseName 返回响应的名称，0 表示 default 响应
+func responseName(code string) string {
+	if code == "0" {
+		return "default"
+	}
+	return code
+}
+
+// operationResponses 返回操作的响应，default 响应使用 0 作为状态码
+func operationResponses(op *spec.Operation) map[int]spec.Response {
+	r := make(map[int]spec.Response)
+	if op.Responses == nil {
+		return r
+	}
+	for code, resp := range op.Responses.StatusCodeResponses {
+		r[code] = resp
+	}
+	if op.Responses.Default != nil {
+		r[0] = *op.Responses.Default
+	}
+	return r
+}
+
+func (d *SpecDiff) diffDefinitions(old *spec.Swagger, new *spec.Swagger) {
+	for _, name := range sortedKeys(old.Definitions) {
+		loc := "definition " + name
+		ns, ok := new.Definitions[name]
+		if !ok {
+			d.add(true, loc, "definition removed")
+			continue
+		}
+		// 定义可能同时用于请求和响应
+		od := old.Definitions[name]
+		d.diffSchema(loc, &od, &ns, true, true)
+	}
+	for _, name := range sortedKeys(new.Definitions) {
+		if _, ok := old.Definitions[name]; !ok {
+			d.add(false, "definition "+name, "definition added")
+		}
+	}
+}
+
+// typeName 返回类型的描述
+func typeName(typ string, format string) string {
+	if typ == "" {
+		typ = "any"
+	}
+	if format != "" {
+		return typ + "(" + format + ")"
+	}
+	return typ
+}
+
+// schemaTypeName 返回 Schema 类型的描述
+func schemaTypeName(s *spec.Schema) string {
+	if ref := s.Ref.String(); ref != "" {
+		return ref
+	}
+	return typeName(strings.Join(s.Type, "|"), s.Format)
+}
+
+// diffSchema 比较两个 Schema，request 和 response 表示 Schema 是否用于请求和响应：
+// 请求中新增必填字段是不兼容的，响应中删除字段是不兼容的，修改类型总是不兼容的。
+func (d *SpecDiff) diffSchema(location string, old *spec.Schema, new *spec.Schema, request bool, response bool) {
+
+	if old == nil && new == nil {
+		return
+	}
+
+	if old == nil || new == nil {
+		if old == nil {
+			d.add(request, location, "schema added")
+		} else {
+			d.add(response, location, "schema removed")
+		}
+		return
+	}
+
+	if ot, nt := schemaTypeName(old), schemaTypeName(new); ot != nt {
+		d.add(true, location, "type changed from %s to %s", ot, nt)
+		return
+	}
+
+	// 引用的差异在比较定义时处理
+	if old.Ref.String() != "" {
+		return
+	}
+
+	oldRequired := make(map[string]bool)
+	for _, name := range old.Required {
+		oldRequired[name] = true
+	}
+
+	for _, name := range new.Required {
+		if !oldRequired[name] {
+			d.add(request, location+" ."+name, "new required field")
+		}
+	}
+
+	for _, name := range sortedKeys(old.Properties) {
+		op := old.Properties[name]
+		np, ok := new.Properties[name]
+		if !ok {
+			d.add(response, location+" ."+name, "field removed")
+			continue
+		}
+		d.diffSchema(location+" ."+name, &op, &np, request, response)
+	}
+
+	for _, name := range sortedKeys(new.Properties) {
+		if _, ok := old.Properties[name]; !ok {
+			d.add(false, location+" ."+name, "field added")
+		}
+	}
+
+	if old.Items != nil && new.Items != nil {
+		d.diffSchema(location+"[]", old.Items.Schema, new.Items.Schema, request, response)
+	}
+
+	if old.AdditionalProperties != nil && new.AdditionalProperties != nil {
+		d.diffSchema(location+"{}", old.AdditionalProperties.Schema, new.AdditionalProperties.Schema, request, response)
+	}
+
+	if len(old.Enum) > 0 {
+		newEnum := make(map[string]bool)
+		for _, v := range new.Enum {
+			newEnum[fmt.Sprint(v)] = true
+		}
+		for _, v := range old.Enum {
+			if len(new.Enum) > 0 && !newEnum[fmt.Sprint(v)] {
+				d.add(request, location, "enum value %v removed", v)
+			}
+		}
+	}
+}
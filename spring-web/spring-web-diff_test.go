@@ -0,0 +1,76 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSpec(t *testing.T) {
+
+	pet := func(required ...string) *spec.Schema {
+		return new(spec.Schema).Typed("object", "").
+			SetProperty("id", *spec.Int64Property()).
+			SetProperty("name", *spec.StringProperty()).
+			WithRequired(required...)
+	}
+
+	oldDoc := SpringWeb.NewSwagger().AddDefinition("Pet", pet("id"))
+	oldDoc.AddPath("/pet/:id", SpringWeb.MethodGet, SpringWeb.NewOperation("getPet").
+		AddParam(SpringWeb.PathParam("id", "integer", "int64")).
+		RespondsWith(200, SpringWeb.NewResponse("ok").WithSchema(spec.RefSchema("#/definitions/Pet"))))
+	oldDoc.AddPath("/pet", SpringWeb.MethodPost, SpringWeb.NewOperation("addPet").
+		AddParam(SpringWeb.BodyParam("body", spec.RefSchema("#/definitions/Pet"))))
+	oldDoc.AddPath("/store", SpringWeb.MethodGet, SpringWeb.NewOperation("getStore"))
+
+	newDoc := SpringWeb.NewSwagger().AddDefinition("Pet", pet("id", "name"))
+	newDoc.AddPath("/pet/:id", SpringWeb.MethodGet, SpringWeb.NewOperation("getPet").
+		AddParam(SpringWeb.PathParam("id", "string", "")).
+		AddParam(spec.QueryParam("verbose")).
+		RespondsWith(200, SpringWeb.NewResponse("ok").WithSchema(spec.RefSchema("#/definitions/Pet"))))
+	newDoc.AddPath("/pet", SpringWeb.MethodPost, SpringWeb.NewOperation("addPet").
+		AddParam(SpringWeb.BodyParam("body", spec.RefSchema("#/definitions/Pet"))).
+		AddParam(spec.HeaderParam("X-Token").AsRequired()))
+	newDoc.AddPath("/user", SpringWeb.MethodGet, SpringWeb.NewOperation("getUser"))
+
+	o, err := oldDoc.Export(SpringWeb.SpecSwagger, "json")
+	assert.Nil(t, err)
+
+	n, err := newDoc.Export(SpringWeb.SpecSwagger, "yaml")
+	assert.Nil(t, err)
+
+	d, err := SpringWeb.DiffSpec(o, n)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "[breaking] POST /pet parameter X-Token (header): required parameter added\n"+
+		"[breaking] GET /pet/{id} parameter id (path): type changed from integer(int64) to string\n"+
+		"[non-breaking] GET /pet/{id} parameter verbose (query): optional parameter added\n"+
+		"[breaking] /store: path removed\n"+
+		"[non-breaking] /user: path added\n"+
+		"[breaking] definition Pet .name: new required field\n", d.String())
+
+	assert.True(t, d.HasBreaking())
+	assert.Len(t, d.Breaking(), 4)
+
+	d, err = SpringWeb.DiffSpec(o, o)
+	assert.Nil(t, err)
+	assert.Empty(t, d.Changes)
+}
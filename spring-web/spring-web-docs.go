@@ -0,0 +1,337 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/swaggo/http-swagger"
+)
+
+// DisabledDocPath 使用该值作为路径时不注册对应的文档接口
+const DisabledDocPath = "-"
+
+// DefaultDocContentSecurityPolicy 文档页面默认的 CSP。Swagger UI 和 ReDoc 使用内联
+// 样式、data: 图片以及 blob: Worker，脚本通过 nonce 加载，字体使用系统字体。
+const DefaultDocContentSecurityPolicy = "default-src 'self'; script-src 'self' 'nonce-{nonce}'; " +
+	"style-src 'self' 'unsafe-inline'; font-src 'self' data:; " +
+	"img-src 'self' data: https:; worker-src 'self' blob:; object-src 'none'; base-uri 'self'; frame-ancestors 'self'"
+
+// DocConfig 文档接口的配置，路径为空时使用默认值
+type DocConfig struct {
+	SwaggerPath string // Swagger UI 的路径前缀，默认 /swagger
+	ReDocPath   string // ReDoc 的路径，默认 /redoc
+	OpenAPIPath string // OpenAPI 3.1 文档的路径前缀，默认 /openapi
+
+	// Filters 文档接口使用的过滤器，例如只允许内网访问
+	Filters []Filter
+
+	// ContentSecurityPolicy 文档接口使用的 CSP，为空时使用 DefaultDocContentSecurityPolicy。
+	// 只在 SecureFilter 等前面的过滤器输出了 CSP 时替换，页面中的脚本使用其中的 nonce。
+	ContentSecurityPolicy string
+
+	// ReDocScript redoc.standalone.js 的地址，为空时使用内嵌在二进制文件中的脚本，
+	// 例如设置为 ReDocScriptURL 时从 CDN 加载
+	ReDocScript string
+
+	// ReDocBundle 替换内嵌的 redoc.standalone.js 内容，通过 {ReDocPath}/redoc.standalone.js
+	// 输出。内嵌的脚本和 Swagger UI 的静态资源都不依赖外部网络。
+	ReDocBundle []byte
+}
+
+// normalize 补全默认值
+func (cfg DocConfig) normalize() DocConfig {
+	if cfg.SwaggerPath == "" {
+		cfg.SwaggerPath = "/swagger"
+	}
+	if cfg.ReDocPath == "" {
+		cfg.ReDocPath = "/redoc"
+	}
+	if cfg.OpenAPIPath == "" {
+		cfg.OpenAPIPath = "/openapi"
+	}
+	if cfg.ContentSecurityPolicy == "" {
+		cfg.ContentSecurityPolicy = DefaultDocContentSecurityPolicy
+	}
+	cfg.SwaggerPath = strings.TrimRight(cfg.SwaggerPath, "/")
+	cfg.ReDocPath = strings.TrimRight(cfg.ReDocPath, "/")
+	cfg.OpenAPIPath = strings.TrimRight(cfg.OpenAPIPath, "/")
+	if len(cfg.ReDocBundle) == 0 && cfg.ReDocScript == "" {
+		cfg.ReDocBundle = ReDocBundle()
+	}
+	if len(cfg.ReDocBundle) > 0 {
+		cfg.ReDocScript = cfg.ReDocPath + "/redoc.standalone.js"
+	} else if cfg.ReDocScript == "" {
+		cfg.ReDocScript = ReDocScriptURL // 没有生成内嵌的脚本
+	}
+	return cfg
+}
+
+// registerDocHandlers 注册 Swagger UI、ReDoc、OpenAPI 3.1 以及 OpenRPC 文档接口，
+// 文档分组使用 {SwaggerPath}/{group}/、{ReDocPath}/{group}、{OpenAPIPath}/{group}.json 等地址。
+func (c *BaseWebContainer) registerDocHandlers() {
+
+	cfg := c.docConfig.normalize()
+	filters := append(append([]Filter(nil), cfg.Filters...), &docCspFilter{csp: cfg.ContentSecurityPolicy})
+
+	var names []string
+	for group := range c.docGroups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+
+	// 注册 swagger-ui 和 doc.json 接口
+	if cfg.SwaggerPath != DisabledDocPath {
+		uiHandlers := make(map[string]HandlerFunc)
+		for _, group := range names {
+			uiHandlers[group] = swaggerUI(c.docGroups[group], cfg.SwaggerPath+"/"+group+"/doc.json")
+		}
+		defaultUI := swaggerUI(c.Swagger(), cfg.SwaggerPath+"/doc.json")
+		c.GetMapping(cfg.SwaggerPath+"/*", func(ctx WebContext) {
+			path := ctx.PathParam("*")
+			if i := strings.Index(path, "/"); i > 0 {
+				if h, ok := uiHandlers[path[:i]]; ok {
+					h(ctx)
+					return
+				}
+			}
+			defaultUI(ctx)
+		}, filters...)
+	}
+
+	// 注册 redoc 接口，redoc 依赖 Swagger 的 doc.json 接口
+	if cfg.ReDocPath != DisabledDocPath && cfg.SwaggerPath != DisabledDocPath {
+		c.GetMapping(cfg.ReDocPath, redocHandler(cfg.SwaggerPath+"/doc.json", cfg.ReDocScript), filters...)
+		for _, group := range names {
+			url := cfg.SwaggerPath + "/" + group + "/doc.json"
+			c.GetMapping(cfg.ReDocPath+"/"+group, redocHandler(url, cfg.ReDocScript), filters...)
+		}
+		if len(cfg.ReDocBundle) > 0 {
+			c.GetMapping(cfg.ReDocScript, redocScriptHandler(cfg.ReDocBundle), filters...)
+		}
+	}
+
+	// 注册 OpenAPI 3.1 文档接口
+	if cfg.OpenAPIPath != DisabledDocPath {
+		c.GetMapping(cfg.OpenAPIPath+".json", c.Swagger().serveOpenAPI("json", MIMEApplicationJSONCharsetUTF8), filters...)
+		c.GetMapping(cfg.OpenAPIPath+".yaml", c.Swagger().serveOpenAPI("yaml", MIMEApplicationYAML), filters...)
+		for _, group := range names {
+			d := c.docGroups[group]
+			c.GetMapping(cfg.OpenAPIPath+"/"+group+".json", d.serveOpenAPI("json", MIMEApplicationJSONCharsetUTF8), filters...)
+			c.GetMapping(cfg.OpenAPIPath+"/"+group+".yaml", d.serveOpenAPI("yaml", MIMEApplicationYAML), filters...)
+		}
+	}
+
+	// 注册 JSON-RPC 端点的 OpenRPC 文档接口
+	var endpoints []*JsonRpc
+	for _, mapper := range c.Mappers() {
+		if j, ok := mapper.handler.(*JsonRpc); ok {
+			endpoints = append(endpoints, j)
+		}
+	}
+	for _, j := range endpoints {
+		c.GetMapping(strings.TrimRight(j.Path(), "/")+"/openrpc.json", j.serveOpenRPC, filters...)
+	}
+}
+
+// docCspFilter 把前面的过滤器输出的 CSP 替换成文档页面可以使用的 CSP
+type docCspFilter struct {
+	csp string
+}
+
+func (f *docCspFilter) Invoke(ctx WebContext, chain FilterChain) {
+	h := ctx.ResponseWriter().Header()
+	for _, key := range []string{HeaderContentSecurityPolicy, HeaderContentSecurityPolicyReportOnly} {
+		if h.Get(key) == "" {
+			continue
+		}
+		nonce := newCspNonce()
+		h.Set(key, strings.Replace(f.csp, CspNoncePlaceholder, nonce, -1))
+		ctx.Set(CspNonceKey, nonce)
+	}
+	chain.Next(ctx)
+}
+
+// swaggerUI 返回 Swagger UI 的处理函数，doc.json 输出 d 的内容
+func swaggerUI(d *swagger, url string) HandlerFunc {
+	ui := httpSwagger.Handler(httpSwagger.URL(url))
+	return func(ctx WebContext) {
+
+		if strings.HasSuffix(ctx.Request().URL.Path, "/doc.json") {
+			ctx.Blob(http.StatusOK, MIMEApplicationJSONCharsetUTF8, []byte(d.ReadDoc()))
+			return
+		}
+
+		nonce := CspNonce(ctx)
+		if nonce == "" || !strings.HasSuffix(ctx.Request().URL.Path, "/index.html") {
+			ui(ctx.ResponseWriter(), ctx.Request())
+			return
+		}
+
+		// 为页面中的脚本添加 nonce
+		w := &docPageWriter{ResponseWriter: ctx.ResponseWriter(), status: http.StatusOK}
+		ui(w, ctx.Request())
+		page := strings.Replace(w.body.String(), "<script", `<script nonce="`+nonce+`"`, -1)
+		w.Header().Del(HeaderContentLength)
+		ctx.ResponseWriter().WriteHeader(w.status)
+		_, _ = io.WriteString(ctx.ResponseWriter(), page)
+	}
+}
+
+// docPageWriter 缓存文档页面的内容
+type docPageWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *docPageWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *docPageWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// parseCIDRs 解析网段列表，单个 IP 地址视为只包含该地址的网段
+func parseCIDRs(cidrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range cidrs {
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// containsIP 网段列表是否包含 ip
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustedProxies 可信的反向代理网段。WebContext.ClientIP 会无条件地使用 X-Forwarded-For
+// 和 X-Real-IP 请求头，客户端可以随意伪造，因此访问控制和限流等场景需要使用
+// TrustedProxies.ClientIP 获取客户端地址。
+type TrustedProxies struct {
+	nets []*net.IPNet
+}
+
+// NewTrustedProxies TrustedProxies 的构造函数，cidrs 也可以是单个 IP 地址
+func NewTrustedProxies(cidrs ...string) *TrustedProxies {
+	return &TrustedProxies{nets: parseCIDRs(cidrs)}
+}
+
+// ClientIP 返回请求的客户端地址。对端地址不是可信代理时直接返回对端地址，否则从右向左
+// 跳过 X-Forwarded-For 中的可信代理，返回第一个不可信的地址，没有 X-Forwarded-For 时
+// 使用 X-Real-IP。p 为 nil 时不信任任何代理，总是返回对端地址。
+func (p *TrustedProxies) ClientIP(r *http.Request) string {
+
+	ip := remoteIP(r)
+	if p == nil || !p.trusted(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, v := range r.Header[HeaderXForwardedFor] {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	if len(hops) == 0 {
+		if s := strings.TrimSpace(r.Header.Get(HeaderXRealIP)); net.ParseIP(s) != nil {
+			return s
+		}
+		return ip
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		s := strings.TrimSpace(hops[i])
+		if net.ParseIP(s) == nil {
+			break
+		}
+		ip = s
+		if !p.trusted(s) {
+			break
+		}
+	}
+	return ip
+}
+
+// trusted ip 是否为可信代理的地址
+func (p *TrustedProxies) trusted(ip string) bool {
+	if v := net.ParseIP(ip); v != nil {
+		return containsIP(p.nets, v)
+	}
+	return false
+}
+
+// remoteIP 返回连接的对端地址
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// remoteIPFilter 只允许指定网段的客户端访问
+type remoteIPFilter struct {
+	nets    []*net.IPNet
+	proxies *TrustedProxies
+}
+
+// RemoteIPFilter 只允许 cidrs 网段内的客户端访问，其他客户端返回 403，
+// cidrs 也可以是单个 IP 地址。客户端地址使用连接的对端地址，不信任 X-Forwarded-For
+// 等请求头，部署在反向代理之后时使用 TrustedRemoteIPFilter。
+func RemoteIPFilter(cidrs ...string) Filter {
+	return TrustedRemoteIPFilter(nil, cidrs...)
+}
+
+// TrustedRemoteIPFilter 和 RemoteIPFilter 相同，但是通过 proxies 获取反向代理之后的客户端地址
+func TrustedRemoteIPFilter(proxies *TrustedProxies, cidrs ...string) Filter {
+	return &remoteIPFilter{nets: parseCIDRs(cidrs), proxies: proxies}
+}
+
+// InternalNetworkFilter 只允许回环地址和私有网段的客户端访问
+func InternalNetworkFilter() Filter {
+	return RemoteIPFilter("127.0.0.0/8", "::1", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")
+}
+
+func (f *remoteIPFilter) Invoke(ctx WebContext, chain FilterChain) {
+	if ip := net.ParseIP(f.proxies.ClientIP(ctx.Request())); ip != nil && containsIP(f.nets, ip) {
+		chain.Next(ctx)
+		return
+	}
+	ctx.NoContent(http.StatusForbidden)
+}
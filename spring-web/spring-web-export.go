@@ -0,0 +1,170 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	SpecSwagger = "swagger" // Swagger 2.0 文档
+	SpecOpenAPI = "openapi" // OpenAPI 3.1 文档
+)
+
+// Export 输出 kind (swagger 或 openapi) 文档的 format (json 或 yaml) 格式内容，
+// JSON 使用缩进格式，方便提交到代码仓库之后比较差异。
+func (s *swagger) Export(kind string, format string) ([]byte, error) {
+
+	var (
+		b   []byte
+		err error
+	)
+
+	switch kind {
+	case SpecSwagger:
+		b, err = s.MarshalJSON()
+	case SpecOpenAPI:
+		b, err = json.Marshal(s.OpenAPI())
+	default:
+		return nil, fmt.Errorf("unsupported spec kind %s", kind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "json":
+		var buf bytes.Buffer
+		if err = json.Indent(&buf, b, "", "  "); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	case "yaml", "yml":
+		return JsonToYaml(b)
+	}
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
+
+// ExportSpec 生成容器的文档并输出，不需要启动容器，group 为空时输出容器的文档，
+// group 不存在时返回错误
+func ExportSpec(c WebContainer, group string, kind string, format string) ([]byte, error) {
+	c.BuildDocs()
+	if group != "" {
+		groups := c.SwaggerGroups()
+		if i := sort.SearchStrings(groups, group); i >= len(groups) || groups[i] != group {
+			return nil, fmt.Errorf("doc group %q not found, available groups: %v", group, groups)
+		}
+	}
+	return c.SwaggerGroup(group).Export(kind, format)
+}
+
+// ExportSpecFile 生成容器的文档并写入文件，扩展名为 .yaml 或 .yml 时使用 YAML 格式
+func ExportSpecFile(c WebContainer, group string, kind string, file string) error {
+	b, err := ExportSpec(c, group, kind, specFormat(file))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0644)
+}
+
+// specFormat 根据文件扩展名返回文档格式
+func specFormat(file string) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return "json"
+}
+
+// ExportSpecFromArgs 处理命令行中的 -export-swagger=file、-export-openapi=file 以及
+// -export-group=name 参数，返回是否导出了文档。应用可以在 main 函数中注册路由之后
+// 调用它，导出文档之后直接退出而不用启动服务器，例如：
+//
+//	if ok, err := SpringWeb.ExportSpecFromArgs(c, os.Args[1:]); ok || err != nil {
+//		...
+//	}
+func ExportSpecFromArgs(c WebContainer, args []string) (bool, error) {
+
+	type export struct {
+		kind string
+		file string
+	}
+
+	var (
+		group   string
+		exports []export
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := strings.TrimLeft(args[i], "-")
+		if arg == args[i] {
+			continue
+		}
+
+		name, value := arg, ""
+		if j := strings.Index(arg, "="); j >= 0 {
+			name, value = arg[:j], arg[j+1:]
+		}
+
+		switch name {
+		case "export-swagger", "export-openapi", "export-group":
+		default:
+			continue
+		}
+
+		if !strings.Contains(arg, "=") {
+			if i+1 >= len(args) {
+				return false, fmt.Errorf("flag -%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch name {
+		case "export-swagger":
+			exports = append(exports, export{SpecSwagger, value})
+		case "export-openapi":
+			exports = append(exports, export{SpecOpenAPI, value})
+		case "export-group":
+			group = value
+		}
+	}
+
+	for _, e := range exports {
+		if err := ExportSpecFile(c, group, e.kind, e.file); err != nil {
+			return true, err
+		}
+	}
+	return len(exports) > 0, nil
+}
+
+// ConvertSpec 将 JSON 或者 YAML 格式的 Swagger 2.0 文档转换成 kind 文档的 format 格式
+func ConvertSpec(b []byte, kind string, format string) ([]byte, error) {
+	s, err := LoadSpec(b)
+	if err != nil {
+		return nil, err
+	}
+	return (&swagger{Swagger: *s}).Export(kind, format)
+}
@@ -0,0 +1,400 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-spring/go-spring-parent/spring-error"
+	"github.com/go-spring/go-spring-parent/spring-utils"
+)
+
+// JSON-RPC 2.0 标准错误码
+const (
+	JsonRpcParseError     = -32700 // 无效的 JSON 文本
+	JsonRpcInvalidRequest = -32600 // 无效的请求对象
+	JsonRpcMethodNotFound = -32601 // 方法不存在
+	JsonRpcInvalidParams  = -32602 // 无效的方法参数
+	JsonRpcInternalError  = -32603 // 内部错误
+	JsonRpcServerError    = -32000 // 应用错误
+)
+
+// JsonRpcVersion JSON-RPC 协议版本
+const JsonRpcVersion = "2.0"
+
+// JsonRpcDiscover OpenRPC 规范定义的服务发现方法
+const JsonRpcDiscover = "rpc.discover"
+
+// JsonRpcError JSON-RPC 2.0 错误对象，处理函数可以直接 panic 该对象
+type JsonRpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// NewJsonRpcError JsonRpcError 的构造函数
+func NewJsonRpcError(code int, message string, data interface{}) *JsonRpcError {
+	return &JsonRpcError{Code: code, Message: message, Data: data}
+}
+
+func (e *JsonRpcError) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// jsonRpcRequest JSON-RPC 2.0 请求对象
+type jsonRpcRequest struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"` // 为 nil 时表示通知
+}
+
+// jsonRpcResult JSON-RPC 2.0 成功响应
+type jsonRpcResult struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// jsonRpcFailure JSON-RPC 2.0 失败响应
+type jsonRpcFailure struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Error   *JsonRpcError   `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// jsonRpcMethod JSON-RPC 方法
+type jsonRpcMethod struct {
+	name        string
+	description string
+	handler     *bindHandler
+}
+
+// JsonRpc JSON-RPC 2.0 端点，每个方法都由一个 BIND 形式的函数实现
+type JsonRpc struct {
+	path      string
+	methods   map[string]*jsonRpcMethod
+	names     []string // 方法的注册顺序
+	validator *BuiltInValidator
+	doc       *swagger // 端点所在的 Swagger 文档
+}
+
+// newJsonRpc JsonRpc 的构造函数
+func newJsonRpc(path string) *JsonRpc {
+	return &JsonRpc{
+		path:      path,
+		methods:   make(map[string]*jsonRpcMethod),
+		validator: NewBuiltInValidator(),
+	}
+}
+
+// Path 返回端点的路径
+func (j *JsonRpc) Path() string {
+	return j.path
+}
+
+// Method 注册一个方法，fn 和 BIND 的要求相同
+func (j *JsonRpc) Method(name string, fn interface{}) *JsonRpc {
+	return j.MethodWithDescription(name, "", fn)
+}
+
+// MethodWithDescription 注册一个带描述的方法，fn 和 BIND 的要求相同
+func (j *JsonRpc) MethodWithDescription(name string, description string, fn interface{}) *JsonRpc {
+	if name == "" || name == JsonRpcDiscover {
+		panic(errors.New("invalid jsonrpc method name " + name))
+	}
+	if _, ok := j.methods[name]; ok {
+		panic(errors.New("duplicate jsonrpc method " + name))
+	}
+	j.methods[name] = &jsonRpcMethod{
+		name:        name,
+		description: description,
+		handler:     newBindHandler(fn),
+	}
+	j.names = append(j.names, name)
+	return j
+}
+
+func (j *JsonRpc) FileLine() (file string, line int, fnName string) {
+	return SpringUtils.FileLine(j.Invoke)
+}
+
+func (j *JsonRpc) Invoke(ctx WebContext) {
+
+	data, err := ctx.GetRawData()
+	if err != nil {
+		j.writeResponse(ctx, j.failure(nil, NewJsonRpcError(JsonRpcParseError, "Parse error", err.Error())))
+		return
+	}
+
+	data = bytes.TrimSpace(data)
+
+	// 批量请求
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if err = json.Unmarshal(data, &batch); err != nil {
+			j.writeResponse(ctx, j.failure(nil, NewJsonRpcError(JsonRpcParseError, "Parse error", err.Error())))
+			return
+		}
+		if len(batch) == 0 {
+			j.writeResponse(ctx, j.failure(nil, NewJsonRpcError(JsonRpcInvalidRequest, "Invalid Request", nil)))
+			return
+		}
+		var responses []interface{}
+		for _, raw := range batch {
+			if resp := j.handle(ctx, raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 { // 全部是通知
+			ctx.NoContent(http.StatusNoContent)
+			return
+		}
+		j.writeResponse(ctx, responses)
+		return
+	}
+
+	if resp := j.handle(ctx, data); resp != nil {
+		j.writeResponse(ctx, resp)
+	} else {
+		ctx.NoContent(http.StatusNoContent)
+	}
+}
+
+func (j *JsonRpc) writeResponse(ctx WebContext, resp interface{}) {
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (j *JsonRpc) failure(id json.RawMessage, err *JsonRpcError) *jsonRpcFailure {
+	return &jsonRpcFailure{JsonRpc: JsonRpcVersion, Error: err, ID: id}
+}
+
+// handle 处理单个请求，通知返回 nil
+func (j *JsonRpc) handle(ctx WebContext, raw json.RawMessage) interface{} {
+
+	var req jsonRpcRequest
+
+	if len(raw) == 0 || !json.Valid(raw) {
+		return j.failure(nil, NewJsonRpcError(JsonRpcParseError, "Parse error", nil))
+	}
+
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return j.failure(nil, NewJsonRpcError(JsonRpcInvalidRequest, "Invalid Request", err.Error()))
+	}
+
+	if req.JsonRpc != JsonRpcVersion || req.Method == "" || !validJsonRpcID(req.ID) {
+		return j.failure(nil, NewJsonRpcError(JsonRpcInvalidRequest, "Invalid Request", nil))
+	}
+
+	result, rpcErr := j.call(ctx, &req)
+
+	if req.ID == nil { // 通知不需要响应
+		if rpcErr != nil {
+			ctx.LogWarnf("jsonrpc notification %s failed: %s", req.Method, rpcErr.Message)
+		}
+		return nil
+	}
+
+	if rpcErr != nil {
+		return j.failure(req.ID, rpcErr)
+	}
+	return &jsonRpcResult{JsonRpc: JsonRpcVersion, Result: result, ID: req.ID}
+}
+
+// validJsonRpcID id 只能是字符串、数字或者 null
+func validJsonRpcID(id json.RawMessage) bool {
+	if id == nil {
+		return true
+	}
+	switch id[0] {
+	case '{', '[', 't', 'f':
+		return false
+	}
+	return true
+}
+
+// call 执行方法，处理函数的 panic 被转换成 JSON-RPC 错误
+func (j *JsonRpc) call(ctx WebContext, req *jsonRpcRequest) (result interface{}, rpcErr *JsonRpcError) {
+
+	if req.Method == JsonRpcDiscover {
+		return j.OpenRPC(), nil
+	}
+
+	m, ok := j.methods[req.Method]
+	if !ok {
+		return nil, NewJsonRpcError(JsonRpcMethodNotFound, "Method not found", req.Method)
+	}
+
+	bindVal, err := j.bindParams(m.handler, req.Params)
+	if err != nil {
+		return nil, NewJsonRpcError(JsonRpcInvalidParams, "Invalid params", err.Error())
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			rpcErr = toJsonRpcError(ctx, r)
+		}
+	}()
+
+	return m.handler.call(ctx, bindVal), nil
+}
+
+// bindParams 将 params 绑定到方法的请求参数上，支持命名参数和只有一个元素的位置参数
+func (j *JsonRpc) bindParams(h *bindHandler, params json.RawMessage) (reflect.Value, error) {
+
+	bindVal := h.newBindValue()
+
+	if params = bytes.TrimSpace(params); len(params) > 0 && !bytes.Equal(params, []byte("null")) {
+		switch params[0] {
+		case '{':
+		case '[':
+			var arr []json.RawMessage
+			if err := json.Unmarshal(params, &arr); err != nil {
+				return bindVal, err
+			}
+			if len(arr) > 1 {
+				return bindVal, errors.New("by-position params must contain exactly one object")
+			}
+			if len(arr) == 0 {
+				params = nil
+			} else {
+				params = arr[0]
+			}
+		default:
+			return bindVal, errors.New("params must be an object or an array")
+		}
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, bindVal.Interface()); err != nil {
+				return bindVal, err
+			}
+		}
+	}
+
+	return bindVal, j.validator.Validate(bindVal.Interface())
+}
+
+// toJsonRpcError 将处理函数的 panic 值转换成 JSON-RPC 错误，错误码目录中
+// 登记过的领域错误使用其错误码和按照请求语言渲染的消息
+func toJsonRpcError(ctx WebContext, r interface{}) *JsonRpcError {
+	switch v := r.(type) {
+	case *JsonRpcError:
+		return v
+	case *SpringError.RpcResult:
+		return NewJsonRpcError(JsonRpcServerError, v.Msg, v)
+	case error:
+		if f := resolveFailure(ctx, v); f.Code != SpringError.ERROR.Code {
+			return NewJsonRpcError(int(f.Code), f.Msg, nil)
+		}
+		return NewJsonRpcError(JsonRpcInternalError, "Internal error", v.Error())
+	default:
+		return NewJsonRpcError(JsonRpcInternalError, "Internal error", fmt.Sprint(r))
+	}
+}
+
+/////////////////// OpenRPC //////////////////////
+
+// OpenRpcVersion 生成的 OpenRPC 文档的版本
+const OpenRpcVersion = "1.2.6"
+
+type openRpcInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openRpcContent struct {
+	Name     string      `json:"name"`
+	Required bool        `json:"required,omitempty"`
+	Schema   spec.Schema `json:"schema"`
+}
+
+type openRpcMethod struct {
+	Name           string           `json:"name"`
+	Description    string           `json:"description,omitempty"`
+	ParamStructure string           `json:"paramStructure"`
+	Params         []openRpcContent `json:"params"`
+	Result         openRpcContent   `json:"result"`
+}
+
+type openRpcComponents struct {
+	Schemas map[string]spec.Schema `json:"schemas,omitempty"`
+}
+
+// OpenRpcDocument OpenRPC 文档
+type OpenRpcDocument struct {
+	OpenRpc    string            `json:"openrpc"`
+	Info       openRpcInfo       `json:"info"`
+	Methods    []openRpcMethod   `json:"methods"`
+	Components openRpcComponents `json:"components"`
+}
+
+// OpenRPC 生成端点的 OpenRPC 文档，标题和版本取自 Swagger 文档
+func (j *JsonRpc) OpenRPC() *OpenRpcDocument {
+
+	info := doc.Info
+	if j.doc != nil {
+		info = j.doc.Info
+	}
+
+	d := &OpenRpcDocument{
+		OpenRpc: OpenRpcVersion,
+		Info: openRpcInfo{
+			Title:   info.Title,
+			Version: info.Version,
+		},
+		Methods: make([]openRpcMethod, 0, len(j.names)),
+		Components: openRpcComponents{
+			Schemas: make(map[string]spec.Schema),
+		},
+	}
+
+	if d.Info.Title == "" {
+		d.Info.Title = j.path
+	}
+
+	b := newSchemaBuilder("#/components/schemas/", d.Components.Schemas, nil)
+
+	for _, name := range j.names {
+		m := j.methods[name]
+		fnType := m.handler.fnVal.Type()
+		d.Methods = append(d.Methods, openRpcMethod{
+			Name:           m.name,
+			Description:    m.description,
+			ParamStructure: "by-name",
+			Params: []openRpcContent{{
+				Name:     "params",
+				Required: true,
+				Schema:   b.schema(m.handler.bindType),
+			}},
+			Result: openRpcContent{
+				Name:   "result",
+				Schema: b.schema(fnType.Out(0)),
+			},
+		})
+	}
+	return d
+}
+
+// serveOpenRPC OpenRPC 文档响应函数
+func (j *JsonRpc) serveOpenRPC(ctx WebContext) {
+	ctx.JSON(http.StatusOK, j.OpenRPC())
+}
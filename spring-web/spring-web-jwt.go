@@ -0,0 +1,549 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JwtClaims JWT 的声明，数字使用 json.Number 表示
+type JwtClaims map[string]interface{}
+
+// String 返回字符串类型的声明
+func (c JwtClaims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings 返回字符串数组类型的声明，字符串类型的声明按照空格分隔，例如 OAuth2 的 scope
+func (c JwtClaims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		var r []string
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				r = append(r, s)
+			}
+		}
+		return r
+	case []string:
+		return v
+	}
+	return nil
+}
+
+// Time 返回 NumericDate 类型的声明，例如 exp、nbf、iat。声明不存在或者不是数字时返回 false
+func (c JwtClaims) Time(name string) (time.Time, bool) {
+	t, ok, err := c.numericDate(name)
+	return t, ok && err == nil
+}
+
+// numericDate 返回 NumericDate 类型的声明，声明存在但不是数字时返回错误
+func (c JwtClaims) numericDate(name string) (time.Time, bool, error) {
+
+	v, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	var f float64
+	switch n := v.(type) {
+	case json.Number:
+		var err error
+		if f, err = n.Float64(); err != nil {
+			return time.Time{}, false, errors.New("claim " + name + " is not a NumericDate")
+		}
+	case float64:
+		f = n
+	case int64:
+		f = float64(n)
+	case int:
+		f = float64(n)
+	default:
+		return time.Time{}, false, errors.New("claim " + name + " is not a NumericDate")
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, false, errors.New("claim " + name + " is not a NumericDate")
+	}
+	return time.Unix(0, int64(f*float64(time.Second))), true, nil
+}
+
+// Subject 返回 sub 声明
+func (c JwtClaims) Subject() string {
+	return c.String("sub")
+}
+
+// jwtAlgorithm JWT 签名算法
+type jwtAlgorithm struct {
+	family string // HS、RS、PS、ES
+	hash   crypto.Hash
+}
+
+// jwtAlgorithms 支持的签名算法
+var jwtAlgorithms = map[string]jwtAlgorithm{
+	"HS256": {"HS", crypto.SHA256},
+	"HS384": {"HS", crypto.SHA384},
+	"HS512": {"HS", crypto.SHA512},
+	"RS256": {"RS", crypto.SHA256},
+	"RS384": {"RS", crypto.SHA384},
+	"RS512": {"RS", crypto.SHA512},
+	"PS256": {"PS", crypto.SHA256},
+	"PS384": {"PS", crypto.SHA384},
+	"PS512": {"PS", crypto.SHA512},
+	"ES256": {"ES", crypto.SHA256},
+	"ES384": {"ES", crypto.SHA384},
+	"ES512": {"ES", crypto.SHA512},
+}
+
+// JwtKey 验证 JWT 签名的密钥，Key 为 []byte、*rsa.PublicKey 或者 *ecdsa.PublicKey
+type JwtKey struct {
+	Kid string
+	Alg string // 为空时不限制算法
+	Key interface{}
+}
+
+// LoadJwks 解析 JSON Web Key Set，支持 RSA、EC 和 oct 类型的密钥
+func LoadJwks(b []byte) ([]JwtKey, error) {
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+
+	if err := json.Unmarshal(b, &jwks); err != nil {
+		return nil, fmt.Errorf("jwks: %v", err)
+	}
+
+	var keys []JwtKey
+	for _, k := range jwks.Keys {
+
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key := JwtKey{Kid: k.Kid, Alg: k.Alg}
+
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil || len(e) > 8 {
+				return nil, fmt.Errorf("jwks: invalid RSA key %q", k.Kid)
+			}
+			key.Key = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, fmt.Errorf("jwks: unsupported curve %q", k.Crv)
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("jwks: invalid EC key %q", k.Kid)
+			}
+			key.Key = &ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil {
+				return nil, fmt.Errorf("jwks: invalid oct key %q", k.Kid)
+			}
+			key.Key = secret
+		default:
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// LoadJwksFile 从文件中加载 JSON Web Key Set
+func LoadJwksFile(file string) ([]JwtKey, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return LoadJwks(b)
+}
+
+// JwtConfig JWT 验证的配置
+type JwtConfig struct {
+
+	// Name 安全定义的名称，为空时使用 BearerAuth
+	Name string
+
+	// Secret HS256、HS384、HS512 使用的密钥
+	Secret []byte
+
+	// Keys 验证签名的密钥，和 Secret、JwksFile 中的密钥一起使用
+	Keys []JwtKey
+
+	// JwksFile JSON Web Key Set 文件
+	JwksFile string
+
+	// Algorithms 允许的签名算法，为空时允许密钥支持的所有算法
+	Algorithms []string
+
+	// Leeway 验证 exp、nbf、iat 时允许的时钟误差
+	Leeway time.Duration
+
+	// Issuer 不为空时要求 iss 声明和它相同
+	Issuer string
+
+	// Audience 不为空时要求 aud 声明包含它
+	Audience string
+
+	// RequiredClaims 必须存在的声明
+	RequiredClaims []string
+
+	// RolesClaim 保存角色的声明，为空时使用 roles
+	RolesClaim string
+
+	// Validate 自定义的声明校验函数
+	Validate func(claims JwtClaims) error
+}
+
+// JwtVerifier 验证 JWT 的签名和声明
+type JwtVerifier struct {
+	cfg        JwtConfig
+	keys       []JwtKey
+	algorithms map[string]bool
+	now        func() time.Time
+}
+
+// NewJwtVerifier JwtVerifier 的构造函数，密钥文件无法加载或者没有密钥时 panic
+func NewJwtVerifier(cfg JwtConfig) *JwtVerifier {
+
+	v := &JwtVerifier{
+		cfg:        cfg,
+		keys:       append([]JwtKey(nil), cfg.Keys...),
+		algorithms: make(map[string]bool),
+		now:        time.Now,
+	}
+
+	if len(cfg.Secret) > 0 {
+		v.keys = append(v.keys, JwtKey{Key: cfg.Secret})
+	}
+
+	if cfg.JwksFile != "" {
+		keys, err := LoadJwksFile(cfg.JwksFile)
+		if err != nil {
+			panic(err)
+		}
+		v.keys = append(v.keys, keys...)
+	}
+
+	if len(v.keys) == 0 {
+		panic(errors.New("jwt: no verification keys"))
+	}
+
+	for _, alg := range cfg.Algorithms {
+		if _, ok := jwtAlgorithms[alg]; !ok {
+			panic(fmt.Errorf("jwt: unsupported algorithm %q", alg))
+		}
+		v.algorithms[alg] = true
+	}
+	return v
+}
+
+// Verify 验证 JWT 的签名和声明，成功时返回声明
+func (v *JwtVerifier) Verify(token string) (JwtClaims, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("jwt: malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJwtPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	alg, ok := jwtAlgorithms[header.Alg]
+	if !ok || (len(v.algorithms) > 0 && !v.algorithms[header.Alg]) {
+		return nil, fmt.Errorf("jwt: algorithm %q not allowed", header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("jwt: malformed signature")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range v.keys {
+		if header.Kid != "" && key.Kid != "" && key.Kid != header.Kid {
+			continue
+		}
+		if key.Alg != "" && key.Alg != header.Alg {
+			continue
+		}
+		if verifyJwt(alg, key.Key, signed, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("jwt: invalid signature")
+	}
+
+	var claims JwtClaims
+	if err = decodeJwtPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	if err = v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate 校验声明
+func (v *JwtVerifier) validate(claims JwtClaims) error {
+
+	now := v.now()
+	leeway := v.cfg.Leeway
+
+	// 时间声明存在时必须是数字，否则无法判断令牌是否有效
+	exp, hasExp, err := claims.numericDate("exp")
+	if err != nil {
+		return errors.New("jwt: " + err.Error())
+	}
+	nbf, hasNbf, err := claims.numericDate("nbf")
+	if err != nil {
+		return errors.New("jwt: " + err.Error())
+	}
+	iat, hasIat, err := claims.numericDate("iat")
+	if err != nil {
+		return errors.New("jwt: " + err.Error())
+	}
+
+	if hasExp && !now.Before(exp.Add(leeway)) {
+		return errors.New("jwt: token is expired")
+	}
+	if hasNbf && now.Add(leeway).Before(nbf) {
+		return errors.New("jwt: token is not valid yet")
+	}
+	if hasIat && now.Add(leeway).Before(iat) {
+		return errors.New("jwt: token used before issued")
+	}
+
+	if v.cfg.Issuer != "" && claims.String("iss") != v.cfg.Issuer {
+		return errors.New("jwt: invalid issuer")
+	}
+
+	if v.cfg.Audience != "" {
+		found := false
+		for _, aud := range claims.Strings("aud") {
+			if aud == v.cfg.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("jwt: invalid audience")
+		}
+	}
+
+	for _, name := range v.cfg.RequiredClaims {
+		if _, ok := claims[name]; !ok {
+			return fmt.Errorf("jwt: claim %q is required", name)
+		}
+	}
+
+	if v.cfg.Validate != nil {
+		return v.cfg.Validate(claims)
+	}
+	return nil
+}
+
+// decodeJwtPart 解码 JWT 的头部或者声明
+func decodeJwtPart(s string, i interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return errors.New("jwt: malformed token")
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err = d.Decode(i); err != nil {
+		return errors.New("jwt: malformed token")
+	}
+	return nil
+}
+
+// verifyJwt 使用 key 验证签名，密钥的类型和算法不匹配时返回 false
+func verifyJwt(alg jwtAlgorithm, key interface{}, signed, sig []byte) bool {
+
+	if alg.family == "HS" {
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(alg.hash.New, secret)
+		mac.Write(signed)
+		return hmac.Equal(sig, mac.Sum(nil))
+	}
+
+	h := alg.hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg.family {
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		if alg.family == "RS" {
+			return rsa.VerifyPKCS1v15(pub, alg.hash, digest, sig) == nil
+		}
+		opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: alg.hash}
+		return rsa.VerifyPSS(pub, alg.hash, digest, sig, opts) == nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return false
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(pub, digest, r, s)
+	}
+	return false
+}
+
+// SignJwt 使用 alg 算法签发 JWT，key 为 []byte、*rsa.PrivateKey 或者 *ecdsa.PrivateKey，
+// kid 不为空时写入头部，主要用于测试和签发内部使用的令牌。
+func SignJwt(alg string, kid string, key interface{}, claims JwtClaims) (string, error) {
+
+	a, ok := jwtAlgorithms[alg]
+	if !ok {
+		return "", fmt.Errorf("jwt: unsupported algorithm %q", alg)
+	}
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+
+	hb, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	cb, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(hb) + "." + base64.RawURLEncoding.EncodeToString(cb)
+
+	var sig []byte
+	if a.family == "HS" {
+		secret, ok := key.([]byte)
+		if !ok {
+			return "", errors.New("jwt: HS algorithms require a []byte key")
+		}
+		mac := hmac.New(a.hash.New, secret)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	} else {
+		h := a.hash.New()
+		h.Write([]byte(signed))
+		digest := h.Sum(nil)
+
+		switch k := key.(type) {
+		case *rsa.PrivateKey:
+			if a.family == "RS" {
+				sig, err = rsa.SignPKCS1v15(rand.Reader, k, a.hash, digest)
+			} else if a.family == "PS" {
+				opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: a.hash}
+				sig, err = rsa.SignPSS(rand.Reader, k, a.hash, digest, opts)
+			} else {
+				err = fmt.Errorf("jwt: %s requires an ECDSA key", alg)
+			}
+		case *ecdsa.PrivateKey:
+			if a.family != "ES" {
+				return "", fmt.Errorf("jwt: %s requires an RSA key", alg)
+			}
+			var r, s *big.Int
+			if r, s, err = ecdsa.Sign(rand.Reader, k, digest); err == nil {
+				size := (k.Curve.Params().BitSize + 7) / 8
+				sig = make([]byte, 2*size)
+				rb, sb := r.Bytes(), s.Bytes()
+				copy(sig[size-len(rb):size], rb)
+				copy(sig[2*size-len(sb):], sb)
+			}
+		default:
+			err = errors.New("jwt: unsupported key type")
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
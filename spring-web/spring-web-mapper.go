@@ -27,6 +27,7 @@ type Mapper struct {
 	handler Handler  // 处理函数
 	filters []Filter // 过滤器列表
 	swagger *Operation
+	router  *Router // 所属的路由分组，可能为 nil
 }
 
 // NewMapper Mapper 的构造函数
@@ -64,6 +65,11 @@ func (m *Mapper) Filters() []Filter {
 	return m.filters
 }
 
+// Router 返回 Mapper 所属的路由分组，直接注册在 WebMapping 上的返回 nil
+func (m *Mapper) Router() *Router {
+	return m.router
+}
+
 // Swagger 生成并返回 Operation 对象
 func (m *Mapper) Swagger(id string) *Operation {
 	m.swagger = NewOperation(id)
@@ -79,6 +79,12 @@ type WebMapping interface {
 
 	// OPTIONS 注册 OPTIONS 方法处理函数
 	OPTIONS(path string, fn interface{}, filters ...Filter) *Mapper
+
+	// JSONRPC 注册 JSON-RPC 2.0 端点，返回的端点用于注册方法
+	JSONRPC(path string, filters ...Filter) *JsonRpc
+
+	// Service 将服务对象所有 BIND 形式的导出方法注册为处理函数
+	Service(prefix string, svc interface{}, filters ...Filter) *ServiceRegistration
 }
 
 // defaultWebMapping 路由表的默认实现
@@ -197,3 +203,15 @@ func (w *defaultWebMapping) HEAD(path string, fn interface{}, filters ...Filter)
 func (w *defaultWebMapping) OPTIONS(path string, fn interface{}, filters ...Filter) *Mapper {
 	return w.Request(MethodOptions, path, fn, filters...)
 }
+
+// JSONRPC 注册 JSON-RPC 2.0 端点，返回的端点用于注册方法
+func (w *defaultWebMapping) JSONRPC(path string, filters ...Filter) *JsonRpc {
+	j := newJsonRpc(path)
+	w.Request(MethodPost, path, j, filters...)
+	return j
+}
+
+// Service 将服务对象所有 BIND 形式的导出方法注册为处理函数
+func (w *defaultWebMapping) Service(prefix string, svc interface{}, filters ...Filter) *ServiceRegistration {
+	return registerService(w.Request, prefix, svc, filters)
+}
@@ -0,0 +1,459 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/spec"
+)
+
+const (
+	// HeaderMockStatus 请求头，指定模拟响应使用的状态码
+	HeaderMockStatus = "X-Mock-Status"
+
+	// HeaderMockExample 请求头，指定模拟响应使用的命名示例
+	HeaderMockExample = "X-Mock-Example"
+)
+
+// MockConfig 模拟服务器的配置
+type MockConfig struct {
+	Validate  bool          // 使用文档校验请求，不一致时返回 400
+	Latency   time.Duration // 每个响应的固定延迟
+	Jitter    time.Duration // 在固定延迟之上随机增加的延迟上限
+	ErrorRate float64       // 返回错误响应的概率，取值范围 [0, 1]
+	ErrorCode int           // 注入错误时使用的状态码，默认 500
+}
+
+// mockHandler 根据 Operation 返回示例或者根据 Schema 合成的响应
+type mockHandler struct {
+	cfg MockConfig
+	doc *spec.Swagger
+	op  *spec.Operation
+}
+
+// BuildMock 读取 JSON 或者 YAML 格式的 Swagger 2.0 或者 OpenAPI 3 文档，为每个
+// Operation 注册一个返回模拟响应的路由，文档中的定义同时复制到容器的文档中。
+// 响应优先使用文档中的示例，没有示例时根据 Schema 合成，请求可以使用 X-Mock-Status
+// 和 X-Mock-Example 请求头选择状态码和命名示例。
+func BuildMock(c WebContainer, b []byte, cfg MockConfig) error {
+
+	s, err := LoadSpec(b)
+	if err != nil {
+		return err
+	}
+
+	if cfg.ErrorCode == 0 {
+		cfg.ErrorCode = http.StatusInternalServerError
+	}
+
+	if cfg.Validate {
+		v := c.GetSpecValidation()
+		v.Request = true
+		c.SetSpecValidation(v)
+	}
+
+	basePath := strings.TrimRight(s.BasePath, "/")
+
+	d := c.Swagger()
+	d.Info = s.Info
+	d.BasePath = basePath
+	d.Consumes = s.Consumes
+	d.Produces = s.Produces
+	d.Tags = s.Tags
+	for name, def := range s.Definitions {
+		d.Definitions[name] = def
+	}
+	for name, def := range s.SecurityDefinitions {
+		if d.SecurityDefinitions == nil {
+			d.SecurityDefinitions = make(spec.SecurityDefinitions)
+		}
+		d.SecurityDefinitions[name] = def
+	}
+
+	var paths []string
+	if s.Paths != nil {
+		for path := range s.Paths.Paths {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		item := s.Paths.Paths[path]
+
+		fullPath := basePath + path
+		if fullPath == "" {
+			fullPath = "/"
+		}
+		if strings.ContainsAny(fullPath, ":*{") {
+			fullPath, _ = ToPathStyle(fullPath, JavaPathStyle)
+		}
+
+		for _, e := range []struct {
+			method uint32
+			op     *spec.Operation
+		}{
+			{MethodGet, item.Get},
+			{MethodPut, item.Put},
+			{MethodPost, item.Post},
+			{MethodDelete, item.Delete},
+			{MethodOptions, item.Options},
+			{MethodHead, item.Head},
+			{MethodPatch, item.Patch},
+		} {
+			if e.op == nil {
+				continue
+			}
+			op := *e.op
+			op.Parameters = mergeParameters(item.Parameters, op.Parameters)
+			h := &mockHandler{cfg: cfg, doc: s, op: &op}
+			m := c.Request(e.method, fullPath, FUNC(h.Invoke))
+			m.swagger = &Operation{operation: &op}
+		}
+	}
+	return nil
+}
+
+// BuildMockFile 读取文件中的文档并注册模拟路由
+func BuildMockFile(c WebContainer, file string, cfg MockConfig) error {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return BuildMock(c, b, cfg)
+}
+
+// mergeParameters 合并路径级别和接口级别的参数，接口级别的参数优先
+func mergeParameters(pathParams []spec.Parameter, opParams []spec.Parameter) []spec.Parameter {
+	r := append([]spec.Parameter{}, opParams...)
+	for _, p := range pathParams {
+		found := false
+		for _, o := range opParams {
+			if o.Name == p.Name && o.In == p.In {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r = append(r, p)
+		}
+	}
+	return r
+}
+
+func (h *mockHandler) Invoke(ctx WebContext) {
+
+	if delay := h.cfg.Latency; delay > 0 || h.cfg.Jitter > 0 {
+		if h.cfg.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(h.cfg.Jitter)))
+		}
+		time.Sleep(delay)
+	}
+
+	if h.cfg.ErrorRate > 0 && rand.Float64() < h.cfg.ErrorRate {
+		ctx.JSON(h.cfg.ErrorCode, map[string]string{"message": "mock error injected"})
+		return
+	}
+
+	code, resp := h.response(ctx.GetHeader(HeaderMockStatus))
+	if resp == nil {
+		ctx.NoContent(code)
+		return
+	}
+
+	names := make([]string, 0, len(resp.Headers))
+	for name := range resp.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		header := resp.Headers[name]
+		v := header.Example
+		if v == nil {
+			v = mockSimpleValue(&header.SimpleSchema, &header.CommonValidations)
+		}
+		ctx.Header(name, fmt.Sprint(v))
+	}
+
+	mediaType := h.mediaType()
+	body, ok := h.example(resp, mediaType, ctx.GetHeader(HeaderMockExample))
+	if !ok {
+		if resp.Schema == nil {
+			ctx.NoContent(code)
+			return
+		}
+		body = newMockGenerator(h.doc.Definitions).value(resp.Schema, 0)
+	}
+
+	if s, isString := body.(string); isString && !isJSONMediaType(mediaType) {
+		ctx.Blob(code, mediaType, []byte(s))
+		return
+	}
+	ctx.JSON(code, body)
+}
+
+// response 返回模拟响应的状态码和响应定义，默认使用最小的 2xx 状态码
+func (h *mockHandler) response(status string) (int, *spec.Response) {
+
+	if h.op.Responses == nil {
+		return http.StatusOK, nil
+	}
+
+	if code, err := strconv.Atoi(status); err == nil {
+		if resp, ok := h.op.Responses.StatusCodeResponses[code]; ok {
+			return code, &resp
+		}
+		return code, h.op.Responses.Default
+	}
+
+	var codes []int
+	for code := range h.op.Responses.StatusCodeResponses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		if code >= 200 && code < 300 {
+			resp := h.op.Responses.StatusCodeResponses[code]
+			return code, &resp
+		}
+	}
+
+	if h.op.Responses.Default != nil {
+		return http.StatusOK, h.op.Responses.Default
+	}
+
+	if len(codes) > 0 {
+		resp := h.op.Responses.StatusCodeResponses[codes[0]]
+		return codes[0], &resp
+	}
+	return http.StatusOK, nil
+}
+
+// mediaType 返回响应的 Content-Type，优先使用 JSON
+func (h *mockHandler) mediaType() string {
+	produces := h.op.Produces
+	if len(produces) == 0 {
+		produces = h.doc.Produces
+	}
+	for _, mt := range produces {
+		if isJSONMediaType(mt) {
+			return mt
+		}
+	}
+	if len(produces) > 0 {
+		return produces[0]
+	}
+	return MIMEApplicationJSON
+}
+
+// example 返回响应的示例，name 不为空时使用 x-examples 中的命名示例
+func (h *mockHandler) example(resp *spec.Response, mediaType string, name string) (interface{}, bool) {
+
+	if name != "" {
+		if named, ok := resp.Extensions["x-examples"].(map[string]interface{}); ok {
+			if v, ok := named[name]; ok {
+				return v, true
+			}
+		}
+	}
+
+	if v, ok := resp.Examples[mediaType]; ok {
+		return v, true
+	}
+	for mt, v := range resp.Examples {
+		if isJSONMediaType(mt) {
+			return v, true
+		}
+	}
+
+	if resp.Schema != nil && resp.Schema.Example != nil {
+		return resp.Schema.Example, true
+	}
+	return nil, false
+}
+
+// mockGenerator 根据 Schema 合成示例数据，相同的 Schema 总是生成相同的数据
+type mockGenerator struct {
+	defs spec.Definitions
+}
+
+// newMockGenerator mockGenerator 的构造函数
+func newMockGenerator(defs spec.Definitions) *mockGenerator {
+	return &mockGenerator{defs: defs}
+}
+
+// maxMockDepth 合成数据时展开 Schema 的最大深度，递归的结构在此处截断
+const maxMockDepth = 8
+
+func (g *mockGenerator) value(s *spec.Schema, depth int) interface{} {
+
+	if depth > maxMockDepth {
+		return nil
+	}
+
+	if ref := s.Ref.String(); ref != "" {
+		def, ok := g.defs[strings.TrimPrefix(ref, "#/definitions/")]
+		if !ok {
+			return nil
+		}
+		return g.value(&def, depth+1)
+	}
+
+	if s.Example != nil {
+		return s.Example
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+
+	if len(s.AllOf) > 0 {
+		r := make(map[string]interface{})
+		for i := range s.AllOf {
+			if m, ok := g.value(&s.AllOf[i], depth+1).(map[string]interface{}); ok {
+				for k, v := range m {
+					r[k] = v
+				}
+			}
+		}
+		for k, v := range g.object(s, depth) {
+			r[k] = v
+		}
+		return r
+	}
+
+	for _, schemas := range [][]spec.Schema{s.OneOf, s.AnyOf} {
+		if len(schemas) > 0 {
+			return g.value(&schemas[0], depth+1)
+		}
+	}
+
+	typ := ""
+	if len(s.Type) > 0 {
+		typ = s.Type[0]
+	} else if len(s.Properties) > 0 {
+		typ = "object"
+	}
+
+	switch typ {
+	case "object":
+		return g.object(s, depth)
+	case "array":
+		n := 1
+		if s.MinItems != nil && *s.MinItems > 1 {
+			n = int(*s.MinItems)
+		}
+		r := make([]interface{}, 0, n)
+		if s.Items != nil && s.Items.Schema != nil {
+			for i := 0; i < n; i++ {
+				r = append(r, g.value(s.Items.Schema, depth+1))
+			}
+		}
+		return r
+	}
+	return mockScalar(typ, s.Format, s.Minimum, s.ExclusiveMinimum, s.MinLength)
+}
+
+// object 合成对象的所有属性
+func (g *mockGenerator) object(s *spec.Schema, depth int) map[string]interface{} {
+	r := make(map[string]interface{})
+	for name, prop := range s.Properties {
+		p := prop
+		r[name] = g.value(&p, depth+1)
+	}
+	if ap := s.AdditionalProperties; len(s.Properties) == 0 && ap != nil && ap.Schema != nil {
+		r["additionalProp1"] = g.value(ap.Schema, depth+1)
+	}
+	return r
+}
+
+// mockSimpleValue 合成非 body 参数或者响应头的数据
+func mockSimpleValue(ss *spec.SimpleSchema, cv *spec.CommonValidations) interface{} {
+	if ss.Default != nil {
+		return ss.Default
+	}
+	if len(cv.Enum) > 0 {
+		return cv.Enum[0]
+	}
+	return mockScalar(ss.Type, ss.Format, cv.Minimum, cv.ExclusiveMinimum, cv.MinLength)
+}
+
+// mockScalar 合成标量数据
+func mockScalar(typ string, format string, min *float64, exclusiveMin bool, minLength *int64) interface{} {
+	switch typ {
+	case "integer":
+		if min == nil {
+			return 0
+		}
+		n := math.Ceil(*min)
+		if exclusiveMin && n == *min {
+			n++
+		}
+		return int64(n)
+	case "number":
+		if min == nil {
+			return 0
+		}
+		if exclusiveMin {
+			return *min + 1
+		}
+		return *min
+	case "boolean":
+		return true
+	case "file":
+		return nil
+	case "string":
+		switch format {
+		case "date-time":
+			return "2020-01-01T00:00:00Z"
+		case "date":
+			return "2020-01-01"
+		case "uuid":
+			return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+		case "email":
+			return "user@example.com"
+		case "uri", "url":
+			return "https://example.com"
+		case "hostname":
+			return "example.com"
+		case "ipv4":
+			return "127.0.0.1"
+		case "ipv6":
+			return "::1"
+		case "byte":
+			return "c3RyaW5n"
+		}
+		s := "string"
+		if minLength != nil && int64(len(s)) < *minLength {
+			s += strings.Repeat("x", int(*minLength)-len(s))
+		}
+		return s
+	}
+	return nil
+}
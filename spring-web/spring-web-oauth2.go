@@ -0,0 +1,409 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/spec"
+)
+
+// DefaultIntrospectionTimeout 调用内省端点的默认超时时间
+const DefaultIntrospectionTimeout = 5 * time.Second
+
+// DefaultIntrospectionCacheSize 默认最多缓存的内省结果数量
+const DefaultIntrospectionCacheSize = 10000
+
+// ErrIntrospectionUnavailable 内省端点无法访问或者返回了错误，此时过滤器返回 503
+var ErrIntrospectionUnavailable = errors.New("introspection: endpoint unavailable")
+
+// IntrospectionConfig RFC 7662 令牌内省的配置
+type IntrospectionConfig struct {
+
+	// Endpoint 内省端点的地址
+	Endpoint string
+
+	// ClientId 和 ClientSecret 调用内省端点时使用的 Basic 认证，为空时不认证
+	ClientId     string
+	ClientSecret string
+
+	// Client 调用内省端点使用的 http.Client，为空时使用超时时间为
+	// DefaultIntrospectionTimeout 的 http.Client
+	Client *http.Client
+
+	// CacheTTL 内省结果的缓存时间，不超过令牌的过期时间，为 0 时不缓存
+	CacheTTL time.Duration
+
+	// CacheSize 最多缓存的内省结果数量，为 0 时使用 DefaultIntrospectionCacheSize，
+	// 缓存已满时随机淘汰一个结果
+	CacheSize int
+}
+
+// OAuth2Config OAuth2 资源服务器的配置，Jwt 和 Introspection 至少设置一个，
+// 同时设置时 JWT 格式的令牌在本地验证，其他令牌调用内省端点验证。
+type OAuth2Config struct {
+
+	// Name 安全定义的名称，为空时使用 OAuth2
+	Name string
+
+	// Flow 文档中的授权流程，implicit、password、application 或者 accessCode，
+	// 为空时根据 AuthorizationUrl 和 TokenUrl 推断
+	Flow string
+
+	// AuthorizationUrl 和 TokenUrl 文档中的授权地址和令牌地址
+	AuthorizationUrl string
+	TokenUrl         string
+
+	// Scopes 文档中 scope 的描述，RequireScopes 使用的 scope 会自动添加
+	Scopes map[string]string
+
+	// ScopeClaim 保存 scope 的声明，为空时使用 scope，不存在时再尝试 scp
+	ScopeClaim string
+
+	// Jwt JWT 格式访问令牌的验证配置
+	Jwt *JwtConfig
+
+	// Introspection 令牌内省的配置
+	Introspection *IntrospectionConfig
+}
+
+// OAuth2ResourceServer OAuth2 资源服务器，验证 Bearer 访问令牌并且检查 scope
+type OAuth2ResourceServer struct {
+	name       string
+	scheme     *spec.SecurityScheme
+	scopeClaim string
+	rolesClaim string
+	verifier   *JwtVerifier
+	introspect *introspector
+	key        string // 认证主体在 WebContext 中的缓存键
+}
+
+// NewOAuth2ResourceServer OAuth2ResourceServer 的构造函数
+func NewOAuth2ResourceServer(cfg OAuth2Config) *OAuth2ResourceServer {
+
+	if cfg.Jwt == nil && cfg.Introspection == nil {
+		panic(errors.New("oauth2 requires jwt or introspection config"))
+	}
+
+	s := &OAuth2ResourceServer{
+		name:       cfg.Name,
+		scopeClaim: cfg.ScopeClaim,
+		rolesClaim: "roles",
+	}
+
+	if s.name == "" {
+		s.name = "OAuth2"
+	}
+	s.key = "@OAuth2:" + s.name
+
+	if s.scopeClaim == "" {
+		s.scopeClaim = "scope"
+	}
+
+	if cfg.Jwt != nil {
+		s.verifier = NewJwtVerifier(*cfg.Jwt)
+		if cfg.Jwt.RolesClaim != "" {
+			s.rolesClaim = cfg.Jwt.RolesClaim
+		}
+	}
+
+	if c := cfg.Introspection; c != nil {
+		if c.Endpoint == "" {
+			panic(errors.New("oauth2 introspection requires an endpoint"))
+		}
+		s.introspect = newIntrospector(*c)
+	}
+
+	flow := cfg.Flow
+	if flow == "" {
+		switch {
+		case cfg.AuthorizationUrl != "" && cfg.TokenUrl != "":
+			flow = "accessCode"
+		case cfg.AuthorizationUrl != "":
+			flow = "implicit"
+		default:
+			flow = "application"
+		}
+	}
+
+	switch flow {
+	case "implicit":
+		s.scheme = spec.OAuth2Implicit(cfg.AuthorizationUrl)
+	case "password":
+		s.scheme = spec.OAuth2Password(cfg.TokenUrl)
+	case "application":
+		s.scheme = spec.OAuth2Application(cfg.TokenUrl)
+	case "accessCode":
+		s.scheme = spec.OAuth2AccessToken(cfg.AuthorizationUrl, cfg.TokenUrl)
+	default:
+		panic(fmt.Errorf("unsupported oauth2 flow %q", flow))
+	}
+
+	for scope, description := range cfg.Scopes {
+		s.scheme.AddScope(scope, description)
+	}
+	return s
+}
+
+// RequireScopes 返回验证访问令牌并且要求令牌包含所有 scope 的过滤器，scope
+// 同时用于生成文档中处理函数的 SecuredWith。没有 scope 时只验证访问令牌。
+func (s *OAuth2ResourceServer) RequireScopes(scopes ...string) SecurityFilter {
+	for _, scope := range scopes {
+		if _, ok := s.scheme.Scopes[scope]; !ok {
+			s.scheme.AddScope(scope, "")
+		}
+	}
+	return &oauth2Filter{server: s, scopes: scopes}
+}
+
+// Authenticate 验证访问令牌，成功时返回认证主体
+func (s *OAuth2ResourceServer) Authenticate(ctx context.Context, token string) (*Principal, error) {
+
+	var (
+		claims JwtClaims
+		err    error
+	)
+
+	if s.verifier != nil && (s.introspect == nil || strings.Count(token, ".") == 2) {
+		claims, err = s.verifier.Verify(token)
+	} else {
+		claims, err = s.introspect.Introspect(ctx, token)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := claims.Strings(s.scopeClaim)
+	if _, ok := claims[s.scopeClaim]; !ok {
+		scopes = claims.Strings("scp")
+	}
+
+	name := claims.Subject()
+	if name == "" {
+		name = claims.String("username")
+	}
+
+	return &Principal{
+		Scheme: "Bearer",
+		Name:   name,
+		Roles:  claims.Strings(s.rolesClaim),
+		Scopes: scopes,
+		Claims: claims,
+	}, nil
+}
+
+// oauth2Filter 验证访问令牌和 scope 的过滤器
+type oauth2Filter struct {
+	server *OAuth2ResourceServer
+	scopes []string
+}
+
+func (f *oauth2Filter) Security() (string, *spec.SecurityScheme, []string) {
+	return f.server.name, f.server.scheme, f.scopes
+}
+
+func (f *oauth2Filter) Invoke(ctx WebContext, chain FilterChain) {
+
+	// 路由分组和处理函数上的过滤器只验证一次令牌
+	p, _ := lookupValue(ctx, f.server.key).(*Principal)
+	if p == nil {
+
+		token := bearerToken(ctx)
+		if token == "" {
+			unauthorized(ctx, "Bearer", "missing bearer token")
+			return
+		}
+
+		var err error
+		if p, err = f.server.Authenticate(ctx.Request().Context(), token); err != nil {
+			if isError(err, ErrIntrospectionUnavailable) {
+				ctx.LogError(err)
+				ctx.String(http.StatusServiceUnavailable, "%s", ErrIntrospectionUnavailable.Error())
+				return
+			}
+			unauthorized(ctx, `Bearer error="invalid_token"`, err.Error())
+			return
+		}
+
+		ctx.Set(f.server.key, p)
+		SetPrincipal(ctx, p)
+	}
+
+	if missing := missingScopes(p.Scopes, f.scopes); len(missing) > 0 {
+		challenge := fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, strings.Join(f.scopes, " "))
+		ctx.Header(HeaderWWWAuthenticate, challenge)
+		ctx.String(http.StatusForbidden, "insufficient scope: %s", strings.Join(missing, " "))
+		return
+	}
+
+	chain.Next(ctx)
+}
+
+// missingScopes 返回 granted 中缺少的 required scope
+func missingScopes(granted []string, required []string) []string {
+	var missing []string
+	for _, r := range required {
+		if !containsString(granted, r) {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// introspectionError 内省端点不可用的错误，和 ErrIntrospectionUnavailable 相同
+type introspectionError struct {
+	err error
+}
+
+func (e *introspectionError) Error() string {
+	return ErrIntrospectionUnavailable.Error() + ": " + e.err.Error()
+}
+
+func (e *introspectionError) Is(target error) bool {
+	return target == ErrIntrospectionUnavailable
+}
+
+// introspector 调用 RFC 7662 内省端点验证令牌
+type introspector struct {
+	cfg    IntrospectionConfig
+	client *http.Client
+
+	mutex     sync.Mutex
+	cache     map[string]introspectResult
+	lastSweep time.Time
+}
+
+// introspectResult 缓存的内省结果
+type introspectResult struct {
+	claims JwtClaims
+	expire time.Time
+}
+
+// newIntrospector introspector 的构造函数
+func newIntrospector(cfg IntrospectionConfig) *introspector {
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultIntrospectionTimeout}
+	}
+	if cfg.CacheSize <= 0 {
+		cfg.CacheSize = DefaultIntrospectionCacheSize
+	}
+	return &introspector{
+		cfg:    cfg,
+		client: client,
+		cache:  make(map[string]introspectResult),
+	}
+}
+
+// Introspect 调用内省端点，令牌有效时返回内省结果
+func (i *introspector) Introspect(ctx context.Context, token string) (JwtClaims, error) {
+
+	now := time.Now()
+
+	if i.cfg.CacheTTL > 0 {
+		i.mutex.Lock()
+		r, ok := i.cache[token]
+		i.mutex.Unlock()
+		if ok && now.Before(r.expire) {
+			return r.claims, nil
+		}
+	}
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequest(http.MethodPost, i.cfg.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, &introspectionError{err}
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set(HeaderContentType, MIMEApplicationForm)
+	req.Header.Set(HeaderAccept, MIMEApplicationJSON)
+	if i.cfg.ClientId != "" {
+		req.SetBasicAuth(url.QueryEscape(i.cfg.ClientId), url.QueryEscape(i.cfg.ClientSecret))
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, &introspectionError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &introspectionError{fmt.Errorf("unexpected status %d", resp.StatusCode)}
+	}
+
+	var claims JwtClaims
+	d := json.NewDecoder(resp.Body)
+	d.UseNumber()
+	if err = d.Decode(&claims); err != nil {
+		return nil, &introspectionError{err}
+	}
+
+	if active, _ := claims["active"].(bool); !active {
+		return nil, errors.New("introspection: token is not active")
+	}
+
+	exp, hasExp, err := claims.numericDate("exp")
+	if err != nil {
+		return nil, errors.New("introspection: " + err.Error())
+	}
+	if hasExp && !now.Before(exp) {
+		return nil, errors.New("introspection: token is expired")
+	}
+
+	if i.cfg.CacheTTL > 0 {
+		expire := now.Add(i.cfg.CacheTTL)
+		if hasExp && exp.Before(expire) {
+			expire = exp
+		}
+		i.put(token, introspectResult{claims: claims, expire: expire}, now)
+	}
+	return claims, nil
+}
+
+// put 缓存内省结果，每隔 CacheTTL 清理一次过期的结果，缓存已满时随机淘汰一个结果
+func (i *introspector) put(token string, r introspectResult, now time.Time) {
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if now.Sub(i.lastSweep) > i.cfg.CacheTTL {
+		for k, v := range i.cache {
+			if !now.Before(v.expire) {
+				delete(i.cache, k)
+			}
+		}
+		i.lastSweep = now
+	}
+
+	if _, ok := i.cache[token]; !ok && len(i.cache) >= i.cfg.CacheSize {
+		for k := range i.cache {
+			delete(i.cache, k)
+			break
+		}
+	}
+
+	i.cache[token] = r
+}
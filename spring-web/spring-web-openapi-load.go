@@ -0,0 +1,567 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// openApiMethods OpenAPI 文档中 Operation 的方法名
+var openApiMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// openApiSimpleKeys 非 body 参数和响应头可以使用的 Schema 字段
+var openApiSimpleKeys = []string{
+	"type", "format", "default", "enum", "example", "multipleOf",
+	"maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+	"maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems",
+}
+
+// openApiImporter 将 OpenAPI 3.0 或者 3.1 文档转换成 Swagger 2.0 文档，只转换
+// Swagger 2.0 能够表示的内容，例如 Cookie 参数和 Callback 会被忽略。
+type openApiImporter struct {
+	components map[string]interface{}
+}
+
+// openApiToSwagger 将解析后的 OpenAPI 3 文档转换成 Swagger 2.0 文档
+func openApiToSwagger(doc map[string]interface{}) map[string]interface{} {
+
+	c := &openApiImporter{components: jsonObject(doc["components"])}
+	r := map[string]interface{}{"swagger": "2.0"}
+
+	for _, key := range []string{"info", "tags", "externalDocs", "security"} {
+		if v, ok := doc[key]; ok {
+			r[key] = v
+		}
+	}
+
+	if servers, ok := doc["servers"].([]interface{}); ok && len(servers) > 0 {
+		c.importServer(r, jsonObject(servers[0]))
+	}
+
+	if schemas := jsonObject(c.components["schemas"]); len(schemas) > 0 {
+		defs := make(map[string]interface{})
+		for name, s := range schemas {
+			defs[name] = openApiToSwaggerSchema(s)
+		}
+		r["definitions"] = defs
+	}
+
+	if schemes := jsonObject(c.components["securitySchemes"]); len(schemes) > 0 {
+		defs := make(map[string]interface{})
+		for name, s := range schemes {
+			if d := importSecurityScheme(jsonObject(s)); d != nil {
+				defs[name] = d
+			}
+		}
+		r["securityDefinitions"] = defs
+	}
+
+	paths := make(map[string]interface{})
+	for path, v := range jsonObject(doc["paths"]) {
+		item := jsonObject(v)
+		pathItem := make(map[string]interface{})
+		if params := c.importParameters(item["parameters"]); len(params) > 0 {
+			pathItem["parameters"] = params
+		}
+		for _, method := range openApiMethods {
+			if op, ok := item[method].(map[string]interface{}); ok {
+				pathItem[method] = c.importOperation(op)
+			}
+		}
+		paths[path] = pathItem
+	}
+	r["paths"] = paths
+	return r
+}
+
+// jsonObject 返回 JSON 对象，不是对象时返回 nil
+func jsonObject(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+// sortedObjectKeys 返回 JSON 对象排序后的键
+func sortedObjectKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// resolve 解析指向 components 中 kind 组件的引用，不是引用时原样返回
+func (c *openApiImporter) resolve(v interface{}, kind string) map[string]interface{} {
+	m := jsonObject(v)
+	for i := 0; i < maxSchemaDepth; i++ {
+		ref, ok := m["$ref"].(string)
+		prefix := "#/components/" + kind + "/"
+		if !ok || !strings.HasPrefix(ref, prefix) {
+			break
+		}
+		m = jsonObject(jsonObject(c.components[kind])[strings.TrimPrefix(ref, prefix)])
+	}
+	return m
+}
+
+// importServer 使用第一个服务器地址设置 host、basePath 和 schemes
+func (c *openApiImporter) importServer(r map[string]interface{}, server map[string]interface{}) {
+
+	s, _ := server["url"].(string)
+	for name, v := range jsonObject(server["variables"]) {
+		if d, ok := jsonObject(v)["default"].(string); ok {
+			s = strings.Replace(s, "{"+name+"}", d, -1)
+		}
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return
+	}
+	if u.Host != "" {
+		r["host"] = u.Host
+		r["schemes"] = []interface{}{u.Scheme}
+	}
+	if p := strings.TrimRight(u.Path, "/"); p != "" {
+		r["basePath"] = p
+	}
+}
+
+// importOperation 转换一个接口
+func (c *openApiImporter) importOperation(op map[string]interface{}) map[string]interface{} {
+
+	r := make(map[string]interface{})
+	for key, v := range op {
+		switch key {
+		case "operationId", "summary", "description", "tags", "deprecated", "security", "externalDocs":
+			r[key] = v
+		default:
+			if strings.HasPrefix(key, "x-") {
+				r[key] = v
+			}
+		}
+	}
+
+	params := c.importParameters(op["parameters"])
+
+	if body := c.resolve(op["requestBody"], "requestBodies"); body != nil {
+		content := jsonObject(body["content"])
+		var consumes []interface{}
+		for _, mt := range sortedObjectKeys(content) {
+			consumes = append(consumes, mt)
+		}
+		if len(consumes) > 0 {
+			r["consumes"] = consumes
+		}
+		params = append(params, c.importRequestBody(body, content)...)
+	}
+
+	if len(params) > 0 {
+		r["parameters"] = params
+	}
+
+	var produces []interface{}
+	seen := make(map[string]bool)
+	responses := make(map[string]interface{})
+	for code, v := range jsonObject(op["responses"]) {
+		resp := c.resolve(v, "responses")
+		responses[code] = c.importResponse(resp)
+		for mt := range jsonObject(resp["content"]) {
+			if !seen[mt] {
+				seen[mt] = true
+				produces = append(produces, mt)
+			}
+		}
+	}
+	r["responses"] = responses
+
+	if len(produces) > 0 {
+		sort.Slice(produces, func(i, j int) bool {
+			return produces[i].(string) < produces[j].(string)
+		})
+		r["produces"] = produces
+	}
+	return r
+}
+
+// importParameters 转换路径、查询和请求头参数，忽略 Cookie 参数
+func (c *openApiImporter) importParameters(v interface{}) []interface{} {
+
+	var r []interface{}
+	list, _ := v.([]interface{})
+
+	for _, e := range list {
+		p := c.resolve(e, "parameters")
+		in, _ := p["in"].(string)
+		if p == nil || in == "cookie" {
+			continue
+		}
+
+		param := map[string]interface{}{"name": p["name"], "in": in}
+		for _, key := range []string{"description", "required"} {
+			if v, ok := p[key]; ok {
+				param[key] = v
+			}
+		}
+
+		schema := c.simpleSchema(openApiToSwaggerSchema(p["schema"]))
+		for k, v := range schema {
+			param[k] = v
+		}
+		if ex, ok := p["example"]; ok {
+			param["x-example"] = ex
+		}
+
+		if param["type"] == "array" {
+			param["collectionFormat"] = collectionFormat(in, p)
+		}
+		r = append(r, param)
+	}
+	return r
+}
+
+// collectionFormat 根据 style 和 explode 返回数组参数的 collectionFormat
+func collectionFormat(in string, p map[string]interface{}) string {
+	switch p["style"] {
+	case "spaceDelimited":
+		return "ssv"
+	case "pipeDelimited":
+		return "pipes"
+	}
+	explode, ok := p["explode"].(bool)
+	if !ok {
+		explode = in == "query" // form 风格默认 explode
+	}
+	if explode && in == "query" {
+		return "multi"
+	}
+	return "csv"
+}
+
+// simpleSchema 将 Schema 转换成非 body 参数或者响应头可以使用的简单类型
+func (c *openApiImporter) simpleSchema(v interface{}) map[string]interface{} {
+
+	s := jsonObject(v)
+	if ref, ok := s["$ref"].(string); ok && strings.HasPrefix(ref, "#/definitions/") {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		s = jsonObject(openApiToSwaggerSchema(jsonObject(c.components["schemas"])[name]))
+	}
+
+	r := make(map[string]interface{})
+	for _, key := range openApiSimpleKeys {
+		if v, ok := s[key]; ok {
+			r[key] = v
+		}
+	}
+	if items, ok := s["items"]; ok {
+		r["items"] = c.simpleSchema(items)
+	}
+	if t, ok := r["type"].([]interface{}); ok && len(t) > 0 {
+		r["type"] = t[0]
+	}
+	return r
+}
+
+// importRequestBody 将请求体转换成 body 参数，表单请求体转换成 formData 参数
+func (c *openApiImporter) importRequestBody(body map[string]interface{}, content map[string]interface{}) []interface{} {
+
+	var mediaType string
+	for _, mt := range sortedObjectKeys(content) {
+		if isJSONMediaType(mt) {
+			mediaType = mt
+			break
+		}
+	}
+
+	if mediaType == "" {
+		for _, mt := range []string{MIMEApplicationForm, MIMEMultipartForm} {
+			if m := jsonObject(content[mt]); m != nil {
+				return c.importFormParams(m)
+			}
+		}
+		if keys := sortedObjectKeys(content); len(keys) > 0 {
+			mediaType = keys[0]
+		}
+	}
+
+	param := map[string]interface{}{"name": "body", "in": "body", "schema": map[string]interface{}{}}
+	for _, key := range []string{"description", "required"} {
+		if v, ok := body[key]; ok {
+			param[key] = v
+		}
+	}
+	if s, ok := jsonObject(content[mediaType])["schema"]; ok {
+		param["schema"] = openApiToSwaggerSchema(s)
+	}
+	return []interface{}{param}
+}
+
+// importFormParams 将表单的属性转换成 formData 参数
+func (c *openApiImporter) importFormParams(mt map[string]interface{}) []interface{} {
+
+	s := jsonObject(openApiToSwaggerSchema(mt["schema"]))
+	if ref, ok := s["$ref"].(string); ok && strings.HasPrefix(ref, "#/definitions/") {
+		s = jsonObject(openApiToSwaggerSchema(jsonObject(c.components["schemas"])[strings.TrimPrefix(ref, "#/definitions/")]))
+	}
+
+	required := make(map[interface{}]bool)
+	if list, ok := s["required"].([]interface{}); ok {
+		for _, name := range list {
+			required[name] = true
+		}
+	}
+
+	var r []interface{}
+	props := jsonObject(s["properties"])
+	for _, name := range sortedObjectKeys(props) {
+		prop := jsonObject(props[name])
+		param := c.simpleSchema(prop)
+		if param["format"] == "binary" {
+			param = map[string]interface{}{"type": "file"}
+		}
+		param["name"] = name
+		param["in"] = "formData"
+		if desc, ok := prop["description"]; ok {
+			param["description"] = desc
+		}
+		if required[name] {
+			param["required"] = true
+		}
+		if param["type"] == "array" {
+			param["collectionFormat"] = "multi"
+		}
+		r = append(r, param)
+	}
+	return r
+}
+
+// importResponse 转换响应，命名的示例保存在 x-examples 扩展中
+func (c *openApiImporter) importResponse(resp map[string]interface{}) map[string]interface{} {
+
+	desc, _ := resp["description"].(string)
+	r := map[string]interface{}{"description": desc}
+
+	content := jsonObject(resp["content"])
+	mediaTypes := sortedObjectKeys(content)
+
+	// 优先使用 JSON 格式的 Schema
+	sort.SliceStable(mediaTypes, func(i, j int) bool {
+		return isJSONMediaType(mediaTypes[i]) && !isJSONMediaType(mediaTypes[j])
+	})
+
+	examples := make(map[string]interface{})
+	named := make(map[string]interface{})
+
+	for _, mt := range mediaTypes {
+		m := jsonObject(content[mt])
+		if s, ok := m["schema"]; ok && r["schema"] == nil {
+			r["schema"] = openApiToSwaggerSchema(s)
+		}
+		if ex, ok := m["example"]; ok {
+			examples[mt] = ex
+		}
+		list := jsonObject(m["examples"])
+		for _, name := range sortedObjectKeys(list) {
+			ex := c.resolve(list[name], "examples")
+			if v, ok := ex["value"]; ok {
+				if _, has := named[name]; !has {
+					named[name] = v
+				}
+				if _, has := examples[mt]; !has {
+					examples[mt] = v
+				}
+			}
+		}
+	}
+
+	if len(examples) > 0 {
+		r["examples"] = examples
+	}
+	if len(named) > 0 {
+		r["x-examples"] = named
+	}
+
+	if headers := jsonObject(resp["headers"]); len(headers) > 0 {
+		hs := make(map[string]interface{})
+		for name, v := range headers {
+			h := c.resolve(v, "headers")
+			header := c.simpleSchema(openApiToSwaggerSchema(h["schema"]))
+			if d, ok := h["description"]; ok {
+				header["description"] = d
+			}
+			if ex, ok := h["example"]; ok {
+				header["example"] = ex
+			}
+			if header["type"] == nil {
+				header["type"] = "string"
+			}
+			hs[name] = header
+		}
+		r["headers"] = hs
+	}
+	return r
+}
+
+// importSecurityScheme 转换安全方案，Bearer 认证转换成 Authorization 请求头的 apiKey
+func importSecurityScheme(s map[string]interface{}) map[string]interface{} {
+
+	r := make(map[string]interface{})
+	if d, ok := s["description"]; ok {
+		r["description"] = d
+	}
+
+	switch s["type"] {
+	case "http":
+		if scheme, _ := s["scheme"].(string); strings.EqualFold(scheme, "basic") {
+			r["type"] = "basic"
+		} else {
+			r["type"] = "apiKey"
+			r["in"] = "header"
+			r["name"] = HeaderAuthorization
+			if format, ok := s["bearerFormat"]; ok {
+				r["x-bearer-format"] = format
+			}
+		}
+	case "apiKey":
+		r["type"] = "apiKey"
+		r["in"] = s["in"]
+		r["name"] = s["name"]
+	case "oauth2":
+		r["type"] = "oauth2"
+		flows := jsonObject(s["flows"])
+		for _, f := range []struct{ name, flow string }{
+			{"implicit", "implicit"},
+			{"password", "password"},
+			{"clientCredentials", "application"},
+			{"authorizationCode", "accessCode"},
+		} {
+			if flow := jsonObject(flows[f.name]); flow != nil {
+				r["flow"] = f.flow
+				for _, key := range []string{"authorizationUrl", "tokenUrl", "scopes"} {
+					if v, ok := flow[key]; ok {
+						r[key] = v
+					}
+				}
+				break
+			}
+		}
+	default:
+		return nil
+	}
+	return r
+}
+
+// openApiToSwaggerSchema 递归地将 OpenAPI 3 的 Schema 转换成 Swagger 2.0 的 Schema，
+// convertOpenApiSchema 的逆过程。示例、默认值和枚举值是数据而不是 Schema，不做转换。
+func openApiToSwaggerSchema(v interface{}) interface{} {
+
+	switch s := v.(type) {
+	case []interface{}:
+		for i := range s {
+			s[i] = openApiToSwaggerSchema(s[i])
+		}
+		return s
+
+	case map[string]interface{}:
+		for k, e := range s {
+			switch k {
+			case "example", "examples", "default", "enum", "const":
+			default:
+				s[k] = openApiToSwaggerSchema(e)
+			}
+		}
+
+		if ref, ok := s["$ref"].(string); ok && strings.HasPrefix(ref, "#/components/schemas/") {
+			s["$ref"] = "#/definitions/" + strings.TrimPrefix(ref, "#/components/schemas/")
+		}
+
+		if types, ok := s["type"].([]interface{}); ok {
+			var r []interface{}
+			for _, t := range types {
+				if t == "null" {
+					s["x-nullable"] = true
+				} else {
+					r = append(r, t)
+				}
+			}
+			if len(r) == 1 {
+				s["type"] = r[0]
+			} else {
+				s["type"] = r
+			}
+		}
+
+		if nullable, ok := s["nullable"].(bool); ok {
+			delete(s, "nullable")
+			if nullable {
+				s["x-nullable"] = true
+			}
+		}
+
+		// anyOf: [{$ref}, {type: null}] 转换成可以为 null 的引用
+		for _, key := range []string{"anyOf", "oneOf"} {
+			list, ok := s[key].([]interface{})
+			if !ok || len(list) != 2 {
+				continue
+			}
+			for i, e := range list {
+				if jsonObject(e)["type"] == "null" {
+					if ref, ok := jsonObject(list[1-i])["$ref"]; ok {
+						delete(s, key)
+						s["$ref"] = ref
+						s["x-nullable"] = true
+					}
+					break
+				}
+			}
+		}
+
+		// JSON Schema 2020-12 中 exclusiveMaximum 和 exclusiveMinimum 是数值
+		for excl, limit := range map[string]string{
+			"exclusiveMaximum": "maximum",
+			"exclusiveMinimum": "minimum",
+		} {
+			if n, ok := s[excl]; ok {
+				if _, isBool := n.(bool); !isBool {
+					s[limit] = n
+					s[excl] = true
+				}
+			}
+		}
+
+		if c, ok := s["const"]; ok {
+			delete(s, "const")
+			s["enum"] = []interface{}{c}
+		}
+
+		if list, ok := s["examples"].([]interface{}); ok {
+			delete(s, "examples")
+			if len(list) > 0 {
+				s["example"] = list[0]
+			}
+		}
+
+		if _, ok := s["contentMediaType"]; ok {
+			delete(s, "contentMediaType")
+			s["format"] = "binary"
+		}
+		return s
+	}
+	return v
+}
@@ -0,0 +1,636 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"gopkg.in/yaml.v2"
+)
+
+// OpenApiVersion 生成的 OpenAPI 文档的版本
+const OpenApiVersion = "3.1.0"
+
+// OpenApiDocument OpenAPI 3.1 文档，由 Swagger 2.0 文档转换而来，因此 Operation、
+// BindDefinitions 和安全定义只需要声明一次。Schema 使用 JSON Schema 2020-12 的
+// 通用形式表示。
+type OpenApiDocument struct {
+	OpenAPI      string                                  `json:"openapi"`
+	Info         *spec.Info                              `json:"info,omitempty"`
+	Servers      []*OpenApiServer                        `json:"servers,omitempty"`
+	Paths        map[string]map[string]*OpenApiOperation `json:"paths"`
+	Components   *OpenApiComponents                      `json:"components,omitempty"`
+	Security     []map[string][]string                   `json:"security,omitempty"`
+	Tags         []spec.Tag                              `json:"tags,omitempty"`
+	ExternalDocs *spec.ExternalDocumentation             `json:"externalDocs,omitempty"`
+}
+
+// OpenApiServer 服务器地址
+type OpenApiServer struct {
+	URL string `json:"url"`
+}
+
+// OpenApiComponents 可复用的组件
+type OpenApiComponents struct {
+	Schemas         map[string]interface{}            `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*OpenApiSecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// OpenApiOperation 一个接口
+type OpenApiOperation struct {
+	OperationID  string                      `json:"operationId,omitempty"`
+	Summary      string                      `json:"summary,omitempty"`
+	Description  string                      `json:"description,omitempty"`
+	Tags         []string                    `json:"tags,omitempty"`
+	Deprecated   bool                        `json:"deprecated,omitempty"`
+	ExternalDocs *spec.ExternalDocumentation `json:"externalDocs,omitempty"`
+	Parameters   []*OpenApiParameter         `json:"parameters,omitempty"`
+	RequestBody  *OpenApiRequestBody         `json:"requestBody,omitempty"`
+	Responses    map[string]*OpenApiResponse `json:"responses"`
+	Security     []map[string][]string       `json:"security,omitempty"`
+}
+
+// OpenApiParameter 路径、查询、请求头和 Cookie 参数
+type OpenApiParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Explode     *bool       `json:"explode,omitempty"`
+	Schema      interface{} `json:"schema,omitempty"`
+}
+
+// OpenApiRequestBody 请求体
+type OpenApiRequestBody struct {
+	Description string                       `json:"description,omitempty"`
+	Required    bool                         `json:"required,omitempty"`
+	Content     map[string]*OpenApiMediaType `json:"content"`
+}
+
+// OpenApiMediaType 一种内容类型的结构，example 和 examples 只能使用其中一个
+type OpenApiMediaType struct {
+	Schema   interface{}                `json:"schema,omitempty"`
+	Example  interface{}                `json:"example,omitempty"`
+	Examples map[string]*OpenApiExample `json:"examples,omitempty"`
+}
+
+// OpenApiExample 命名示例
+type OpenApiExample struct {
+	Value interface{} `json:"value"`
+}
+
+// OpenApiResponse 响应
+type OpenApiResponse struct {
+	Description string                       `json:"description"`
+	Headers     map[string]*OpenApiHeader    `json:"headers,omitempty"`
+	Content     map[string]*OpenApiMediaType `json:"content,omitempty"`
+}
+
+// OpenApiHeader 响应头
+type OpenApiHeader struct {
+	Description string      `json:"description,omitempty"`
+	Schema      interface{} `json:"schema,omitempty"`
+}
+
+// OpenApiSecurityScheme 安全方案
+type OpenApiSecurityScheme struct {
+	Type         string             `json:"type"`
+	Description  string             `json:"description,omitempty"`
+	Name         string             `json:"name,omitempty"`
+	In           string             `json:"in,omitempty"`
+	Scheme       string             `json:"scheme,omitempty"`
+	BearerFormat string             `json:"bearerFormat,omitempty"`
+	Flows        *OpenApiOAuthFlows `json:"flows,omitempty"`
+}
+
+// OpenApiOAuthFlows OAuth2 的授权流程
+type OpenApiOAuthFlows struct {
+	Implicit          *OpenApiOAuthFlow `json:"implicit,omitempty"`
+	Password          *OpenApiOAuthFlow `json:"password,omitempty"`
+	ClientCredentials *OpenApiOAuthFlow `json:"clientCredentials,omitempty"`
+	AuthorizationCode *OpenApiOAuthFlow `json:"authorizationCode,omitempty"`
+}
+
+// OpenApiOAuthFlow OAuth2 的一种授权流程
+type OpenApiOAuthFlow struct {
+	AuthorizationURL string            `json:"authorizationUrl,omitempty"`
+	TokenURL         string            `json:"tokenUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes"`
+}
+
+// NullableSchema 将 Schema 标记为可以为 null，OpenAPI 3.1 中转换成包含 null 的类型
+func NullableSchema(schema *spec.Schema) *spec.Schema {
+	schema.AddExtension("x-nullable", true)
+	return schema
+}
+
+// OneOfSchema 返回值为多个 Schema 之一的 Schema
+func OneOfSchema(schemas ...spec.Schema) *spec.Schema {
+	return &spec.Schema{SchemaProps: spec.SchemaProps{OneOf: schemas}}
+}
+
+// OpenAPI 将 Swagger 2.0 文档转换成 OpenAPI 3.1 文档
+func (s *swagger) OpenAPI() *OpenApiDocument {
+
+	d := &OpenApiDocument{
+		OpenAPI:      OpenApiVersion,
+		Info:         s.Info,
+		Paths:        make(map[string]map[string]*OpenApiOperation),
+		Security:     s.Security,
+		Tags:         s.Tags,
+		ExternalDocs: s.ExternalDocs,
+		Components: &OpenApiComponents{
+			Schemas:         make(map[string]interface{}),
+			SecuritySchemes: make(map[string]*OpenApiSecurityScheme),
+		},
+	}
+
+	if s.Host != "" {
+		schemes := s.Schemes
+		if len(schemes) == 0 {
+			schemes = []string{"http"}
+		}
+		for _, scheme := range schemes {
+			d.Servers = append(d.Servers, &OpenApiServer{URL: scheme + "://" + s.Host + s.BasePath})
+		}
+	} else if s.BasePath != "" {
+		d.Servers = append(d.Servers, &OpenApiServer{URL: s.BasePath})
+	}
+
+	for name, schema := range s.Definitions {
+		d.Components.Schemas[name] = openApiSchema(&schema)
+	}
+
+	for name, scheme := range s.SecurityDefinitions {
+		d.Components.SecuritySchemes[name] = openApiSecurityScheme(scheme)
+	}
+
+	if s.Paths != nil {
+		for path, item := range s.Paths.Paths {
+			ops := make(map[string]*OpenApiOperation)
+			for method, op := range map[string]*spec.Operation{
+				"get":     item.Get,
+				"put":     item.Put,
+				"post":    item.Post,
+				"delete":  item.Delete,
+				"options": item.Options,
+				"head":    item.Head,
+				"patch":   item.Patch,
+			} {
+				if op != nil {
+					ops[method] = s.openApiOperation(op, item.Parameters)
+				}
+			}
+			if strings.ContainsAny(path, ":*") {
+				path, _ = ToPathStyle(path, JavaPathStyle)
+			}
+			d.Paths[path] = ops
+		}
+	}
+
+	return d
+}
+
+// openApiOperation 转换一个接口，pathParams 是路径上声明的公共参数
+func (s *swagger) openApiOperation(op *spec.Operation, pathParams []spec.Parameter) *OpenApiOperation {
+
+	r := &OpenApiOperation{
+		OperationID:  op.ID,
+		Summary:      op.Summary,
+		Description:  op.Description,
+		Tags:         op.Tags,
+		Deprecated:   op.Deprecated,
+		ExternalDocs: op.ExternalDocs,
+		Security:     op.Security,
+		Responses:    make(map[string]*OpenApiResponse),
+	}
+
+	consumes := op.Consumes
+	if len(consumes) == 0 {
+		consumes = s.Consumes
+	}
+
+	produces := op.Produces
+	if len(produces) == 0 {
+		produces = s.Produces
+	}
+	if len(produces) == 0 {
+		produces = []string{MIMEApplicationJSON}
+	}
+
+	var formParams []spec.Parameter
+	for _, param := range append(pathParams, op.Parameters...) {
+		switch param.In {
+		case "body":
+			if len(consumes) == 0 {
+				consumes = []string{MIMEApplicationJSON}
+			}
+			body := &OpenApiRequestBody{
+				Description: param.Description,
+				Required:    param.Required,
+				Content:     make(map[string]*OpenApiMediaType),
+			}
+			for _, mime := range consumes {
+				body.Content[mime] = &OpenApiMediaType{Schema: openApiSchema(param.Schema)}
+			}
+			r.RequestBody = body
+		case "formData":
+			formParams = append(formParams, param)
+		default:
+			r.Parameters = append(r.Parameters, openApiParameter(param))
+		}
+	}
+
+	if len(formParams) > 0 {
+		r.RequestBody = openApiFormBody(formParams, consumes)
+	}
+
+	if op.Responses != nil {
+		if resp := op.Responses.Default; resp != nil {
+			r.Responses["default"] = openApiResponse(resp, produces)
+		}
+		for code, resp := range op.Responses.StatusCodeResponses {
+			r.Responses[strconv.Itoa(code)] = openApiResponse(&resp, produces)
+		}
+	}
+
+	if len(r.Responses) == 0 {
+		r.Responses["default"] = &OpenApiResponse{Description: http.StatusText(http.StatusOK)}
+	}
+	return r
+}
+
+// openApiFormBody 将 formData 参数转换成请求体，有文件参数时使用 multipart/form-data
+func openApiFormBody(params []spec.Parameter, consumes []string) *OpenApiRequestBody {
+
+	schema := map[string]interface{}{"type": "object"}
+	properties := make(map[string]interface{})
+	var required []string
+
+	multipart := false
+	for _, param := range params {
+		if param.Type == "file" {
+			multipart = true
+		}
+		if param.Required {
+			required = append(required, param.Name)
+		}
+		p := openApiParameterSchema(param)
+		if param.Description != "" {
+			p["description"] = param.Description
+		}
+		properties[param.Name] = p
+	}
+
+	schema["properties"] = properties
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	var mimes []string
+	for _, mime := range consumes {
+		if mime == MIMEMultipartForm || mime == MIMEApplicationForm {
+			mimes = append(mimes, mime)
+		}
+	}
+	if len(mimes) == 0 {
+		if multipart {
+			mimes = []string{MIMEMultipartForm}
+		} else {
+			mimes = []string{MIMEApplicationForm}
+		}
+	}
+
+	body := &OpenApiRequestBody{Required: len(required) > 0, Content: make(map[string]*OpenApiMediaType)}
+	for _, mime := range mimes {
+		body.Content[mime] = &OpenApiMediaType{Schema: schema}
+	}
+	return body
+}
+
+// openApiParameter 转换一个非 body 参数
+func openApiParameter(param spec.Parameter) *OpenApiParameter {
+
+	p := &OpenApiParameter{
+		Name:        param.Name,
+		In:          param.In,
+		Description: param.Description,
+		Required:    param.Required || param.In == "path",
+		Schema:      openApiParameterSchema(param),
+	}
+
+	switch param.CollectionFormat {
+	case "multi":
+		explode := true
+		p.Explode = &explode
+	case "csv":
+		explode := false
+		p.Explode = &explode
+	}
+	return p
+}
+
+// openApiParameterSchema 提取参数中和 Schema 有关的属性
+func openApiParameterSchema(param spec.Parameter) map[string]interface{} {
+
+	b, err := json.Marshal(param)
+	if err != nil {
+		panic(err)
+	}
+
+	var m map[string]interface{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		panic(err)
+	}
+
+	for _, key := range []string{"name", "in", "description", "required",
+		"allowEmptyValue", "collectionFormat", "schema"} {
+		delete(m, key)
+	}
+
+	return convertOpenApiSchema(m).(map[string]interface{})
+}
+
+// openApiResponse 转换一个响应，每种内容类型使用相同的 Schema
+func openApiResponse(resp *spec.Response, produces []string) *OpenApiResponse {
+
+	r := &OpenApiResponse{Description: resp.Description}
+
+	if resp.Schema != nil {
+		r.Content = make(map[string]*OpenApiMediaType)
+		schema := openApiSchema(resp.Schema)
+		named, _ := resp.Extensions["x-examples"].(map[string]interface{})
+		for _, mime := range produces {
+			mt := &OpenApiMediaType{Schema: schema, Example: resp.Examples[mime]}
+			if len(named) > 0 && isJSONMediaType(mime) {
+				mt.Example = nil
+				mt.Examples = make(map[string]*OpenApiExample)
+				for name, v := range named {
+					mt.Examples[name] = &OpenApiExample{Value: v}
+				}
+			}
+			r.Content[mime] = mt
+		}
+	}
+
+	if len(resp.Headers) > 0 {
+		r.Headers = make(map[string]*OpenApiHeader)
+		for name, h := range resp.Headers {
+			b, err := json.Marshal(h)
+			if err != nil {
+				panic(err)
+			}
+			var m map[string]interface{}
+			if err = json.Unmarshal(b, &m); err != nil {
+				panic(err)
+			}
+			delete(m, "description")
+			delete(m, "collectionFormat")
+			r.Headers[name] = &OpenApiHeader{Description: h.Description, Schema: convertOpenApiSchema(m)}
+		}
+	}
+
+	return r
+}
+
+// openApiSecurityScheme 转换一个安全定义
+func openApiSecurityScheme(s *spec.SecurityScheme) *OpenApiSecurityScheme {
+
+	r := &OpenApiSecurityScheme{Description: s.Description}
+
+	switch s.Type {
+	case "basic":
+		r.Type = "http"
+		r.Scheme = "basic"
+	case "apiKey":
+		// 带有 x-bearer-format 扩展的 Authorization 请求头表示 Bearer 认证
+		if format, ok := s.Extensions.GetString("x-bearer-format"); ok && s.In == "header" {
+			r.Type = "http"
+			r.Scheme = "bearer"
+			r.BearerFormat = format
+			break
+		}
+		r.Type = "apiKey"
+		r.Name = s.Name
+		r.In = s.In
+	case "oauth2":
+		r.Type = "oauth2"
+		scopes := s.Scopes
+		if scopes == nil {
+			scopes = map[string]string{}
+		}
+		flow := &OpenApiOAuthFlow{
+			AuthorizationURL: s.AuthorizationURL,
+			TokenURL:         s.TokenURL,
+			Scopes:           scopes,
+		}
+		r.Flows = new(OpenApiOAuthFlows)
+		switch s.Flow {
+		case "implicit":
+			r.Flows.Implicit = flow
+		case "password":
+			r.Flows.Password = flow
+		case "application":
+			r.Flows.ClientCredentials = flow
+		case "accessCode":
+			r.Flows.AuthorizationCode = flow
+		}
+	default:
+		r.Type = s.Type
+	}
+	return r
+}
+
+// openApiSchema 将 Swagger 2.0 的 Schema 转换成 JSON Schema 2020-12 的通用形式
+func openApiSchema(schema *spec.Schema) interface{} {
+	if schema == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(schema)
+	if err != nil {
+		panic(err)
+	}
+
+	var v interface{}
+	if err = json.Unmarshal(b, &v); err != nil {
+		panic(err)
+	}
+	return convertOpenApiSchema(v)
+}
+
+// convertOpenApiSchema 递归地修正 Swagger 2.0 和 OpenAPI 3.1 的 Schema 差异
+func convertOpenApiSchema(v interface{}) interface{} {
+
+	switch s := v.(type) {
+	case []interface{}:
+		for i := range s {
+			s[i] = convertOpenApiSchema(s[i])
+		}
+		return s
+
+	case map[string]interface{}:
+		for k, e := range s {
+			s[k] = convertOpenApiSchema(e)
+		}
+
+		if ref, ok := s["$ref"].(string); ok && strings.HasPrefix(ref, "#/definitions/") {
+			s["$ref"] = "#/components/schemas/" + strings.TrimPrefix(ref, "#/definitions/")
+		}
+
+		if t, ok := s["type"].(string); ok && t == "file" {
+			s["type"] = "string"
+			s["contentMediaType"] = MIMEOctetStream
+		}
+
+		if d, ok := s["discriminator"].(string); ok {
+			s["discriminator"] = map[string]interface{}{"propertyName": d}
+		}
+
+		// JSON Schema 2020-12 中 exclusiveMaximum 和 exclusiveMinimum 是数值
+		for excl, limit := range map[string]string{
+			"exclusiveMaximum": "maximum",
+			"exclusiveMinimum": "minimum",
+		} {
+			if b, ok := s[excl].(bool); ok {
+				if n, has := s[limit]; b && has {
+					s[excl] = n
+					delete(s, limit)
+				} else {
+					delete(s, excl)
+				}
+			}
+		}
+
+		if nullable, ok := s["x-nullable"].(bool); ok {
+			delete(s, "x-nullable")
+			if nullable {
+				switch t := s["type"].(type) {
+				case string:
+					s["type"] = []interface{}{t, "null"}
+				case nil:
+					if ref, ok := s["$ref"]; ok {
+						delete(s, "$ref")
+						s["anyOf"] = []interface{}{
+							map[string]interface{}{"$ref": ref},
+							map[string]interface{}{"type": "null"},
+						}
+					}
+				}
+			}
+		}
+		return s
+	}
+	return v
+}
+
+// ReadOpenAPI 获取 OpenAPI 3.1 描述内容，format 可以是 json 或者 yaml
+func (s *swagger) ReadOpenAPI(format string) ([]byte, error) {
+	b, err := json.MarshalIndent(s.OpenAPI(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "json":
+		return b, nil
+	case "yaml", "yml":
+		return JsonToYaml(b)
+	}
+	return nil, fmt.Errorf("unsupported format %s", format)
+}
+
+// serveOpenAPI 返回输出 OpenAPI 3.1 文档的处理函数
+func (s *swagger) serveOpenAPI(format string, contentType string) HandlerFunc {
+	return func(ctx WebContext) {
+		b, err := s.ReadOpenAPI(format)
+		if err != nil {
+			panic(err)
+		}
+		ctx.Blob(http.StatusOK, contentType, b)
+	}
+}
+
+// JsonToYaml 将 JSON 转换成 YAML，保持对象中键的顺序
+func JsonToYaml(b []byte) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	v, err := decodeOrderedJson(d)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// decodeOrderedJson 解码一个 JSON 值，对象解码成 yaml.MapSlice 以保持键的顺序
+func decodeOrderedJson(d *json.Decoder) (interface{}, error) {
+
+	t, err := d.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := t.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			var m yaml.MapSlice
+			for d.More() {
+				k, err := d.Token()
+				if err != nil {
+					return nil, err
+				}
+				e, err := decodeOrderedJson(d)
+				if err != nil {
+					return nil, err
+				}
+				m = append(m, yaml.MapItem{Key: k, Value: e})
+			}
+			if _, err = d.Token(); err != nil { // }
+				return nil, err
+			}
+			if m == nil {
+				return map[string]interface{}{}, nil
+			}
+			return m, nil
+		case '[':
+			a := make([]interface{}, 0)
+			for d.More() {
+				e, err := decodeOrderedJson(d)
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, e)
+			}
+			if _, err = d.Token(); err != nil { // ]
+				return nil, err
+			}
+			return a, nil
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	}
+	return t, nil
+}
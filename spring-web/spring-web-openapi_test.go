@@ -0,0 +1,110 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPI(t *testing.T) {
+
+	s := SpringWeb.NewSwagger().
+		WithTitle("Swagger Petstore").
+		WithVersion("1.0.5").
+		WithHost("petstore.swagger.io").
+		WithBasePath("/v2").
+		WithSchemes("https").
+		AddOauth2ImplicitSecurityDefinition("petstore_auth", "https://petstore.swagger.io/oauth/authorize",
+			map[string]string{"read:pets": "read your pets"}).
+		AddApiKeySecurityDefinition("api_key", "header").
+		AddDefinition("Pet", new(spec.Schema).Typed("object", "").
+			SetProperty("name", *spec.StringProperty()).
+			SetProperty("tag", *SpringWeb.NullableSchema(spec.StringProperty())).
+			SetProperty("owner", *SpringWeb.NullableSchema(spec.RefSchema("#/definitions/User"))).
+			SetProperty("age", *spec.Int32Property().WithMaximum(30, true)))
+
+	addPet := SpringWeb.NewOperation("addPet").
+		WithConsumes(SpringWeb.MIMEApplicationJSON, SpringWeb.MIMEApplicationXML).
+		AddParam(SpringWeb.BodyParam("body", spec.RefSchema("#/definitions/Pet")).AsRequired()).
+		RespondsWith(200, SpringWeb.NewResponse("ok").WithSchema(SpringWeb.OneOfSchema(
+			*spec.RefSchema("#/definitions/Pet"), *spec.StringProperty()))).
+		SecuredWith("petstore_auth", "read:pets")
+	s.AddPath("/v2/pet", SpringWeb.MethodPost, addPet)
+
+	upload := SpringWeb.NewOperation("uploadFile").
+		AddParam(SpringWeb.PathParam("petId", "integer", "int64")).
+		AddParam(spec.FormDataParam("file").Typed("file", "").AsRequired())
+	s.AddPath("/v2/pet/:petId/uploadImage", SpringWeb.MethodPost, upload)
+
+	find := SpringWeb.NewOperation("findPetsByStatus").
+		AddParam(spec.QueryParam("status").CollectionOf(spec.NewItems().Typed("string", ""), "multi"))
+	s.AddPath("/v2/pet/findByStatus", SpringWeb.MethodGet, find)
+
+	b, err := s.ReadOpenAPI("json")
+	assert.Nil(t, err)
+
+	var d map[string]interface{}
+	assert.Nil(t, json.Unmarshal(b, &d))
+
+	get := func(v interface{}, path string) interface{} {
+		for _, key := range strings.Split(path, ".") {
+			v = v.(map[string]interface{})[key]
+		}
+		return v
+	}
+
+	assert.Equal(t, "3.1.0", d["openapi"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"url": "https://petstore.swagger.io/v2"}}, d["servers"])
+
+	pet := get(d, "components.schemas.Pet")
+	assert.Equal(t, []interface{}{"string", "null"}, get(pet, "properties.tag.type"))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"$ref": "#/components/schemas/User"},
+		map[string]interface{}{"type": "null"},
+	}, get(pet, "properties.owner.anyOf"))
+	assert.Equal(t, float64(30), get(pet, "properties.age.exclusiveMaximum"))
+	assert.Nil(t, get(pet, "properties.age.maximum"))
+
+	post := get(d, "paths./pet.post")
+	assert.Equal(t, true, get(post, "requestBody.required"))
+	assert.Equal(t, "#/components/schemas/Pet", get(post, "requestBody.content.application/json.schema.$ref"))
+	assert.Equal(t, "#/components/schemas/Pet", get(post, "requestBody.content.application/xml.schema.$ref"))
+	assert.Len(t, get(post, "responses.200.content.application/json.schema.oneOf"), 2)
+
+	form := get(d, "paths./pet/{petId}/uploadImage.post")
+	assert.Equal(t, "path", get(form.(map[string]interface{})["parameters"].([]interface{})[0], "in"))
+	assert.Equal(t, "string", get(form, "requestBody.content.multipart/form-data.schema.properties.file.type"))
+	assert.Equal(t, []interface{}{"file"}, get(form, "requestBody.content.multipart/form-data.schema.required"))
+
+	param := get(d, "paths./pet/findByStatus.get").(map[string]interface{})["parameters"].([]interface{})[0]
+	assert.Equal(t, true, get(param, "explode"))
+	assert.Equal(t, "array", get(param, "schema.type"))
+
+	assert.Equal(t, "https://petstore.swagger.io/oauth/authorize",
+		get(d, "components.securitySchemes.petstore_auth.flows.implicit.authorizationUrl"))
+	assert.Equal(t, "header", get(d, "components.securitySchemes.api_key.in"))
+
+	y, err := s.ReadOpenAPI("yaml")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(y), "openapi: 3.1.0\n"))
+}
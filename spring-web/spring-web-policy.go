@@ -0,0 +1,385 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-spring/go-spring-parent/spring-logger"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	PolicyAllow = "allow" // 允许访问
+	PolicyDeny  = "deny"  // 拒绝访问，优先于 allow
+)
+
+const (
+	PolicyAnyone    = "*"         // 匹配所有认证通过的主体
+	PolicyAnonymous = "anonymous" // 匹配没有认证的请求
+)
+
+// PolicyRule 授权规则。Subject 为 role:<角色>、user:<名称>、* 或者 anonymous；
+// Method 为 Mapper.Method() 使用的方法掩码；Path 可以是任意 PathStyleEnum 风格的
+// 路径，路径参数匹配一段路径，通配符匹配剩余的所有路径。
+type PolicyRule struct {
+	Subject string
+	Method  uint32
+	Path    string
+	Effect  string // allow 或者 deny，为空时为 allow
+}
+
+// String 返回规则的描述
+func (r PolicyRule) String() string {
+	return fmt.Sprintf("%s %s %v %s", r.Effect, r.Subject, GetMethod(r.Method), r.Path)
+}
+
+// policyRule 编译后的授权规则
+type policyRule struct {
+	PolicyRule
+	pattern pathPattern
+}
+
+// compilePolicyRule 校验规则并且编译路径模式
+func compilePolicyRule(r PolicyRule) (c policyRule, err error) {
+
+	switch r.Effect {
+	case "":
+		r.Effect = PolicyAllow
+	case PolicyAllow, PolicyDeny:
+	default:
+		return c, fmt.Errorf("policy: invalid effect %q", r.Effect)
+	}
+
+	if r.Subject != PolicyAnyone && r.Subject != PolicyAnonymous &&
+		!strings.HasPrefix(r.Subject, "role:") && !strings.HasPrefix(r.Subject, "user:") {
+		return c, fmt.Errorf("policy: invalid subject %q", r.Subject)
+	}
+
+	if r.Method == 0 {
+		return c, fmt.Errorf("policy: rule %q has no method", r.Path)
+	}
+
+	pattern, err := compilePathPattern(r.Path)
+	if err != nil {
+		return c, fmt.Errorf("policy: invalid path %q", r.Path)
+	}
+
+	c.PolicyRule = r
+	c.pattern = pattern
+	return c, nil
+}
+
+// matchSubject 规则的主体是否匹配认证主体
+func (r *policyRule) matchSubject(p *Principal) bool {
+	switch {
+	case r.Subject == PolicyAnonymous:
+		return p == nil
+	case p == nil:
+		return false
+	case r.Subject == PolicyAnyone:
+		return true
+	case strings.HasPrefix(r.Subject, "user:"):
+		return p.Name == r.Subject[5:]
+	default:
+		return containsString(p.Roles, r.Subject[5:])
+	}
+}
+
+// Policy 授权策略，没有匹配的 allow 规则时拒绝访问，deny 规则优先于 allow 规则。
+type Policy struct {
+	mutex sync.RWMutex
+	rules []policyRule
+
+	file    string
+	modTime time.Time
+}
+
+// NewPolicy Policy 的构造函数，规则错误时 panic
+func NewPolicy(rules ...PolicyRule) *Policy {
+	p := new(Policy)
+	if err := p.SetRules(rules); err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// SetRules 替换所有的规则，规则错误时保持原来的规则不变
+func (p *Policy) SetRules(rules []PolicyRule) error {
+
+	compiled := make([]policyRule, 0, len(rules))
+	for _, r := range rules {
+		c, err := compilePolicyRule(r)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, c)
+	}
+
+	p.mutex.Lock()
+	p.rules = compiled
+	p.mutex.Unlock()
+	return nil
+}
+
+// Rules 返回当前的规则
+func (p *Policy) Rules() []PolicyRule {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	r := make([]PolicyRule, len(p.rules))
+	for i, c := range p.rules {
+		r[i] = c.PolicyRule
+	}
+	return r
+}
+
+// Authorize 判断认证主体 (没有认证时为 nil) 能否访问，返回起决定作用的规则，
+// 没有匹配的规则时返回 nil。
+func (p *Policy) Authorize(principal *Principal, method string, path string) (bool, *PolicyRule) {
+
+	var mask uint32
+	for k, v := range methods {
+		if v == method {
+			mask = k
+			break
+		}
+	}
+
+	segments := splitPath(path)
+
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var allow *PolicyRule
+	for i := range p.rules {
+		r := &p.rules[i]
+		if r.Method&mask == 0 || !r.matchSubject(principal) || !r.pattern.match(segments) {
+			continue
+		}
+		if r.Effect == PolicyDeny {
+			rule := r.PolicyRule
+			return false, &rule
+		}
+		if allow == nil {
+			rule := r.PolicyRule
+			allow = &rule
+		}
+	}
+	return allow != nil, allow
+}
+
+// policyFile 策略文件的格式
+type policyFile struct {
+	Rules []struct {
+		Subject string   `yaml:"subject"`
+		Methods []string `yaml:"methods"` // 方法名称，* 表示所有方法
+		Path    string   `yaml:"path"`
+		Effect  string   `yaml:"effect"`
+	} `yaml:"rules"`
+}
+
+// ParsePolicy 解析 JSON 或者 YAML 格式的策略，例如:
+//
+//	rules:
+//	  - subject: role:admin
+//	    methods: ["*"]
+//	    path: /admin/*
+//	  - subject: "*"
+//	    methods: [GET]
+//	    path: /pets/{id}
+func ParsePolicy(b []byte) ([]PolicyRule, error) {
+
+	var f policyFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("policy: %v", err)
+	}
+
+	rules := make([]PolicyRule, 0, len(f.Rules))
+	for _, r := range f.Rules {
+		var mask uint32
+		for _, m := range r.Methods {
+			if m == "*" {
+				mask = MethodAny
+				continue
+			}
+			found := false
+			for k, v := range methods {
+				if strings.EqualFold(v, m) {
+					mask |= k
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("policy: invalid method %q", m)
+			}
+		}
+		rules = append(rules, PolicyRule{
+			Subject: r.Subject,
+			Method:  mask,
+			Path:    r.Path,
+			Effect:  r.Effect,
+		})
+	}
+	return rules, nil
+}
+
+// LoadPolicyFile 从 JSON 或者 YAML 文件加载策略，可以使用 Reload 或者 Watch 重新加载
+func LoadPolicyFile(file string) (*Policy, error) {
+	p := &Policy{file: file}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload 重新加载策略文件，加载失败时保持原来的规则不变
+func (p *Policy) Reload() error {
+
+	if p.file == "" {
+		return errors.New("policy: not loaded from file")
+	}
+
+	info, err := os.Stat(p.file)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(p.file)
+	if err != nil {
+		return err
+	}
+
+	rules, err := ParsePolicy(b)
+	if err != nil {
+		return err
+	}
+
+	if err = p.SetRules(rules); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	p.modTime = info.ModTime()
+	p.mutex.Unlock()
+	return nil
+}
+
+// Watch 每隔 interval 检查策略文件的修改时间，文件修改之后重新加载，返回停止检查的函数。
+// 检查时文件可能只写入了一部分，更新策略文件时应该先写入临时文件再重命名。
+func (p *Policy) Watch(interval time.Duration) (stop func()) {
+
+	p.mutex.RLock()
+	last := p.modTime
+	p.mutex.RUnlock()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				info, err := os.Stat(p.file)
+				if err != nil {
+					continue
+				}
+				if info.ModTime().Equal(last) {
+					continue
+				}
+				last = info.ModTime() // 加载失败时等待文件再次修改
+				if err = p.Reload(); err != nil {
+					SpringLogger.Errorf("reload policy %s error: %v", p.file, err)
+				} else {
+					SpringLogger.Infof("policy %s reloaded", p.file)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// AuthorizationConfig 授权过滤器的配置
+type AuthorizationConfig struct {
+
+	// Policy 授权策略
+	Policy *Policy
+
+	// DryRun 为 true 时只记录会被拒绝的请求，不拒绝访问
+	DryRun bool
+}
+
+// authorizationFilter 授权过滤器
+type authorizationFilter struct {
+	policy *Policy
+	dryRun bool
+}
+
+// AuthorizationFilter 返回根据策略授权的过滤器，需要放在认证过滤器之后。没有认证的
+// 请求被拒绝时返回 401，认证通过的请求被拒绝时返回 403。CORS 预检请求和其他请求
+// 一样需要授权，因此跨域的接口需要把 CorsFilter 放在认证和授权过滤器的前面，
+// Router.WithCors 会自动这样做。
+func AuthorizationFilter(cfg AuthorizationConfig) Filter {
+	if cfg.Policy == nil {
+		panic(errors.New("authorization requires a policy"))
+	}
+	return &authorizationFilter{policy: cfg.Policy, dryRun: cfg.DryRun}
+}
+
+func (f *authorizationFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+	p := GetPrincipal(ctx)
+	allowed, rule := f.policy.Authorize(p, r.Method, r.URL.Path)
+	if allowed {
+		chain.Next(ctx)
+		return
+	}
+
+	subject := PolicyAnonymous
+	if p != nil {
+		subject = p.Name
+	}
+
+	reason := "no matching rule"
+	if rule != nil {
+		reason = "denied by rule " + rule.String()
+	}
+
+	if f.dryRun {
+		ctx.LogWarnf("authorization dry run: would deny %s %s for %s, %s", r.Method, r.URL.Path, subject, reason)
+		chain.Next(ctx)
+		return
+	}
+
+	if p == nil {
+		unauthorized(ctx, "", "unauthorized")
+		return
+	}
+	ctx.String(http.StatusForbidden, "forbidden")
+}
@@ -0,0 +1,340 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitAlgorithm 限流算法
+type RateLimitAlgorithm int
+
+const (
+	RateLimitTokenBucket   = RateLimitAlgorithm(0) // 令牌桶，允许 Burst 大小的突发请求
+	RateLimitSlidingWindow = RateLimitAlgorithm(1) // 滑动窗口计数
+)
+
+// RateLimitQuota 限流配额，每 Period 时间允许 Limit 个请求
+type RateLimitQuota struct {
+	Limit     int
+	Period    time.Duration
+	Burst     int // 令牌桶的容量，为 0 时等于 Limit
+	Algorithm RateLimitAlgorithm
+}
+
+// RateLimitResult 一次请求的限流结果
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // 配额完全恢复需要的时间
+	RetryAfter time.Duration // 被拒绝时下次可以请求需要等待的时间
+}
+
+// RateLimitStore 保存限流状态的存储，多实例部署时可以使用 Redis 等共享存储实现，
+// Take 需要原子地完成读取、计算和保存。
+type RateLimitStore interface {
+
+	// Take 为 key 消耗一个请求的配额
+	Take(key string, quota RateLimitQuota, now time.Time) (RateLimitResult, error)
+}
+
+// rateLimitEntry 一个 key 的限流状态
+type rateLimitEntry struct {
+	tokens float64   // 令牌桶剩余的令牌
+	last   time.Time // 令牌桶上次更新的时间
+	start  time.Time // 滑动窗口当前窗口的开始时间
+	prev   int       // 滑动窗口上一个窗口的请求数
+	curr   int       // 滑动窗口当前窗口的请求数
+	expire time.Time // 状态过期之后等同于没有请求过
+}
+
+// rateLimitShard 内存存储的分片
+type rateLimitShard struct {
+	mutex     sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+// memoryRateLimitStore 分片的内存存储
+type memoryRateLimitStore struct {
+	shards []*rateLimitShard
+}
+
+// NewMemoryRateLimitStore 返回分片的内存存储，shards 为 0 时使用 64 个分片
+func NewMemoryRateLimitStore(shards int) RateLimitStore {
+	if shards <= 0 {
+		shards = 64
+	}
+	s := &memoryRateLimitStore{shards: make([]*rateLimitShard, shards)}
+	for i := range s.shards {
+		s.shards[i] = &rateLimitShard{entries: make(map[string]*rateLimitEntry)}
+	}
+	return s
+}
+
+func (s *memoryRateLimitStore) Take(key string, quota RateLimitQuota, now time.Time) (RateLimitResult, error) {
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	shard := s.shards[h.Sum32()%uint32(len(s.shards))]
+
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+
+	// 定期清理过期的状态
+	if now.Sub(shard.lastSweep) > time.Minute {
+		for k, e := range shard.entries {
+			if !now.Before(e.expire) {
+				delete(shard.entries, k)
+			}
+		}
+		shard.lastSweep = now
+	}
+
+	e, ok := shard.entries[key]
+	if !ok || !now.Before(e.expire) {
+		e = &rateLimitEntry{}
+		shard.entries[key] = e
+	}
+
+	if quota.Algorithm == RateLimitSlidingWindow {
+		return e.slidingWindow(quota, now), nil
+	}
+	return e.tokenBucket(quota, now), nil
+}
+
+// tokenBucket 令牌桶算法
+func (e *rateLimitEntry) tokenBucket(quota RateLimitQuota, now time.Time) RateLimitResult {
+
+	capacity := float64(quota.Burst)
+	rate := float64(quota.Limit) / float64(quota.Period) // 每纳秒恢复的令牌
+
+	if e.last.IsZero() {
+		e.tokens = capacity
+	} else if elapsed := now.Sub(e.last); elapsed > 0 {
+		e.tokens = math.Min(capacity, e.tokens+float64(elapsed)*rate)
+	}
+	e.last = now
+
+	r := RateLimitResult{Limit: quota.Burst}
+
+	if e.tokens >= 1 {
+		e.tokens--
+		r.Allowed = true
+	} else {
+		r.RetryAfter = time.Duration(math.Ceil((1 - e.tokens) / rate))
+	}
+
+	r.Remaining = int(e.tokens)
+	r.Reset = time.Duration(math.Ceil((capacity - e.tokens) / rate))
+	e.expire = now.Add(r.Reset)
+	return r
+}
+
+// slidingWindow 滑动窗口计数算法，使用上一个窗口的请求数按照时间加权估计窗口内的请求数
+func (e *rateLimitEntry) slidingWindow(quota RateLimitQuota, now time.Time) RateLimitResult {
+
+	period := quota.Period
+	start := now.Truncate(period)
+
+	if !start.Equal(e.start) {
+		if start.Sub(e.start) == period {
+			e.prev = e.curr
+		} else {
+			e.prev = 0
+		}
+		e.curr = 0
+		e.start = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(period)
+	count := float64(e.prev)*weight + float64(e.curr)
+
+	r := RateLimitResult{Limit: quota.Limit, Reset: period - elapsed}
+
+	if count+1 <= float64(quota.Limit) {
+		e.curr++
+		count++
+		r.Allowed = true
+	} else if e.curr+1 > quota.Limit || e.prev == 0 {
+		r.RetryAfter = period - elapsed
+	} else {
+		// 上一个窗口的权重下降到可以再接受一个请求的时间
+		w := float64(quota.Limit-e.curr-1) / float64(e.prev)
+		r.RetryAfter = time.Duration(math.Ceil((1-w)*float64(period))) - elapsed
+	}
+
+	if r.Remaining = quota.Limit - int(math.Ceil(count)); r.Remaining < 0 {
+		r.Remaining = 0
+	}
+	if e.prev > 0 {
+		r.Reset += period // 上一个窗口的请求完全不影响需要再经过一个窗口
+	}
+	e.expire = start.Add(2 * period)
+	return r
+}
+
+// RateLimitKeyFunc 返回请求的限流 key，返回空字符串时不限流
+type RateLimitKeyFunc func(ctx WebContext) string
+
+// RateLimitByClientIP 按照连接的对端地址限流，不信任 X-Forwarded-For 等客户端可以伪造的
+// 请求头。部署在反向代理后面时所有请求共享代理的配额，需要使用 RateLimitByTrustedClientIP。
+func RateLimitByClientIP() RateLimitKeyFunc {
+	return RateLimitByTrustedClientIP(nil)
+}
+
+// RateLimitByTrustedClientIP 按照客户端 IP 限流，只有来自 proxies 的请求才使用
+// X-Forwarded-For 和 X-Real-IP 中的地址，其他请求使用连接的对端地址。
+func RateLimitByTrustedClientIP(proxies *TrustedProxies) RateLimitKeyFunc {
+	return func(ctx WebContext) string {
+		return "ip:" + proxies.ClientIP(ctx.Request())
+	}
+}
+
+// RateLimitByPrincipal 按照认证主体限流，没有认证时按照连接的对端地址限流
+func RateLimitByPrincipal() RateLimitKeyFunc {
+	return func(ctx WebContext) string {
+		if p := GetPrincipal(ctx); p != nil {
+			return "principal:" + p.Name
+		}
+		return "ip:" + remoteIP(ctx.Request())
+	}
+}
+
+// RateLimitByApiKey 按照 ApiKeyFilter 认证的 API Key 所有者限流，需要放在 ApiKeyFilter
+// 之后。没有通过 API Key 认证的请求按照连接的对端地址限流，客户端不能通过更换未经
+// 认证的 API Key 获得新的配额。
+func RateLimitByApiKey() RateLimitKeyFunc {
+	return func(ctx WebContext) string {
+		if p := GetPrincipal(ctx); p != nil && p.Scheme == "ApiKey" {
+			return "key:" + p.Name
+		}
+		return "ip:" + remoteIP(ctx.Request())
+	}
+}
+
+// RateLimitByRoute 按照处理函数限流，所有客户端共享配额
+func RateLimitByRoute() RateLimitKeyFunc {
+	return func(ctx WebContext) string {
+		return "route:" + ctx.Request().Method + " " + ctx.Path()
+	}
+}
+
+// RateLimitConfig 限流过滤器的配置
+type RateLimitConfig struct {
+
+	// Name 限流 key 的前缀，为空时自动生成。使用共享存储时应该为每个过滤器指定固定的名称
+	Name string
+
+	// Quota 限流配额
+	Quota RateLimitQuota
+
+	// Key 限流 key，为空时使用 RateLimitByClientIP
+	Key RateLimitKeyFunc
+
+	// Store 限流状态的存储，为空时使用过滤器独享的内存存储
+	Store RateLimitStore
+}
+
+// rateLimitCount 自动生成的过滤器名称的序号
+var rateLimitCount int32
+
+// rateLimitFilter 限流过滤器
+type rateLimitFilter struct {
+	name  string
+	quota RateLimitQuota
+	key   RateLimitKeyFunc
+	store RateLimitStore
+}
+
+// RateLimitFilter 返回限流过滤器，可以作为全局、路由分组或者单个处理函数的过滤器
+// 使用，以实现不同处理函数的配额。响应中输出 RateLimit-Limit、RateLimit-Remaining、
+// RateLimit-Reset 响应头，多个过滤器同时生效时输出剩余配额最少的；超出配额时返回
+// 429 和 Retry-After 响应头。存储出错时记录日志并放行请求。
+func RateLimitFilter(cfg RateLimitConfig) Filter {
+
+	if cfg.Quota.Limit <= 0 || cfg.Quota.Period <= 0 {
+		panic(errors.New("rate limit quota must be greater than 0"))
+	}
+	if cfg.Quota.Burst <= 0 {
+		cfg.Quota.Burst = cfg.Quota.Limit
+	}
+	if cfg.Name == "" {
+		cfg.Name = "ratelimit" + strconv.Itoa(int(atomic.AddInt32(&rateLimitCount, 1)))
+	}
+	if cfg.Key == nil {
+		cfg.Key = RateLimitByClientIP()
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRateLimitStore(0)
+	}
+
+	return &rateLimitFilter{
+		name:  cfg.Name,
+		quota: cfg.Quota,
+		key:   cfg.Key,
+		store: cfg.Store,
+	}
+}
+
+func (f *rateLimitFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	key := f.key(ctx)
+	if key == "" {
+		chain.Next(ctx)
+		return
+	}
+
+	r, err := f.store.Take(f.name+":"+key, f.quota, time.Now())
+	if err != nil {
+		ctx.LogErrorf("rate limit store error: %v", err)
+		chain.Next(ctx)
+		return
+	}
+
+	h := ctx.ResponseWriter().Header()
+
+	// 多个过滤器同时生效时输出剩余配额最少的
+	prev, err := strconv.Atoi(h.Get(HeaderRateLimitRemaining))
+	if err != nil || r.Remaining <= prev {
+		h.Set(HeaderRateLimitLimit, strconv.Itoa(r.Limit))
+		h.Set(HeaderRateLimitRemaining, strconv.Itoa(r.Remaining))
+		h.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(r.Reset)))
+	}
+
+	if !r.Allowed {
+		h.Set(HeaderRetryAfter, strconv.Itoa(ceilSeconds(r.RetryAfter)))
+		ctx.String(http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+
+	chain.Next(ctx)
+}
+
+// ceilSeconds 返回向上取整的秒数
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
@@ -17,21 +17,70 @@
 package SpringWeb
 
 import (
+	"bytes"
+	"compress/gzip"
 	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
 )
 
+//go:generate go run ./internal/redoc-bundle -version 2.1.5 -o spring-web-redoc-bundle.go
+
+// ReDocScriptURL CDN 上的 redoc.standalone.js 地址，没有内嵌 redoc 脚本时使用
+const ReDocScriptURL = "https://cdn.jsdelivr.net/npm/redoc@next/bundles/redoc.standalone.js"
+
+var (
+	redocBundleOnce sync.Once
+	redocBundle     []byte
+)
+
+// ReDocBundle 返回内嵌在二进制文件中的 redoc.standalone.js，没有内嵌时返回 nil
+func ReDocBundle() []byte {
+	redocBundleOnce.Do(func() {
+		if redocBundleGzip == "" {
+			return
+		}
+		r, err := gzip.NewReader(strings.NewReader(redocBundleGzip))
+		if err != nil {
+			panic(err)
+		}
+		if redocBundle, err = ioutil.ReadAll(r); err != nil {
+			panic(err)
+		}
+	})
+	return redocBundle
+}
+
+// redocTemplate 预先解析的 redoc 页面模板，html/template 的 Execute 可以并发执行
+var redocTemplate = template.Must(template.New("redoc.html").Parse(redocTempl))
+
 // ReDoc redoc 响应函数
 func ReDoc(ctx WebContext) {
+	redocHandler("/swagger/doc.json", ReDocScriptURL)(ctx)
+}
 
-	index, err := template.New("redoc.html").Parse(redocTempl)
-	if err != nil {
-		panic(err)
+// redocHandler 返回展示 url 处文档的 redoc 响应函数，script 为 redoc 脚本的地址
+func redocHandler(url string, script string) HandlerFunc {
+	return func(ctx WebContext) {
+		var buf bytes.Buffer
+		if err := redocTemplate.Execute(&buf, map[string]interface{}{
+			"URL":    url,
+			"Script": script,
+			"Nonce":  CspNonce(ctx),
+		}); err != nil {
+			panic(err)
+		}
+		ctx.HTMLBlob(http.StatusOK, buf.Bytes())
 	}
+}
 
-	// 不确定 Execute 是否线程安全，官方文档表示也许是线程安全的，谁知道呢
-	_ = index.Execute(ctx.ResponseWriter(), map[string]interface{}{
-		"URL": "/swagger/doc.json",
-	})
+// redocScriptHandler 返回输出内嵌 redoc 脚本的响应函数
+func redocScriptHandler(script []byte) HandlerFunc {
+	return func(ctx WebContext) {
+		ctx.Blob(http.StatusOK, MIMEApplicationJavaScriptCharsetUTF8, script)
+	}
 }
 
 const redocTempl = `
@@ -42,7 +91,6 @@ const redocTempl = `
     <!-- needed for adaptive design -->
     <meta charset="utf-8"/>
     <meta name="viewport" content="width=device-width, initial-scale=1">
-    <link href="https://fonts.googleapis.com/css?family=Montserrat:300,400,700|Roboto:300,400,700" rel="stylesheet">
 
     <!--
     ReDoc doesn't change outer page styles
@@ -55,8 +103,8 @@ const redocTempl = `
     </style>
   </head>
   <body>
-    <redoc spec-url='{{.URL}}'></redoc>
-    <script src="https://cdn.jsdelivr.net/npm/redoc@next/bundles/redoc.standalone.js"> </script>
+    <redoc spec-url='{{.URL}}' font-family='-apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif'></redoc>
+    <script src="{{.Script}}"{{if .Nonce}} nonce="{{.Nonce}}"{{end}}> </script>
   </body>
 </html>
 `
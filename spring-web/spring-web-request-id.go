@@ -0,0 +1,225 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-spring/go-spring-parent/spring-logger"
+	"github.com/go-spring/go-spring-web/spring-web/internal/spring-logger"
+)
+
+// RequestIdKey 请求 ID 在 WebContext 中的键
+const RequestIdKey = "@RequestId"
+
+// maxRequestIdLength 接受的请求 ID 的最大长度
+const maxRequestIdLength = 128
+
+// requestIdContextKey 请求 ID 在 context.Context 中的键
+type requestIdContextKey struct{}
+
+// RequestIdGenerator 请求 ID 生成器
+type RequestIdGenerator func() string
+
+// UUIDGenerator 返回生成随机 UUID (version 4) 的生成器
+func UUIDGenerator() RequestIdGenerator {
+	return func() string {
+		var b [16]byte
+		_, _ = rand.Read(b[:])
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		s := hex.EncodeToString(b[:])
+		return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+	}
+}
+
+// crockford ULID 使用的 Base32 字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULIDGenerator 返回生成 ULID 的生成器，ULID 按照生成时间排序
+func ULIDGenerator() RequestIdGenerator {
+	return func() string {
+
+		// 48 位毫秒时间戳和 80 位随机数
+		var b [16]byte
+		ms := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+		b[0], b[1], b[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+		b[3], b[4], b[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+		_, _ = rand.Read(b[6:])
+
+		// 128 位按照每 5 位一个字符编码成 26 个字符，最高位补 2 个 0
+		var s [26]byte
+		hi := binary.BigEndian.Uint64(b[:8])
+		lo := binary.BigEndian.Uint64(b[8:])
+		for i := 25; i >= 0; i-- {
+			s[i] = crockford[lo&0x1f]
+			lo = lo>>5 | hi<<59
+			hi >>= 5
+		}
+		return string(s[:])
+	}
+}
+
+// snowflakeEpoch 雪花算法的起始时间，2020-01-01 00:00:00 UTC 的毫秒数
+const snowflakeEpoch = 1577836800000
+
+// SnowflakeGenerator 返回使用雪花算法的生成器，node 为 0 到 1023 之间的节点编号，
+// 同一毫秒内最多生成 4096 个 ID，超出时等待下一毫秒。
+func SnowflakeGenerator(node int64) RequestIdGenerator {
+
+	if node < 0 || node > 1023 {
+		panic(errors.New("snowflake node must be between 0 and 1023"))
+	}
+
+	var (
+		mutex sync.Mutex
+		last  int64
+		seq   int64
+	)
+
+	return func() string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		now := time.Now().UnixNano()/int64(time.Millisecond) - snowflakeEpoch
+		if now < last { // 时钟回拨时沿用上次的时间
+			now = last
+		}
+
+		if now == last {
+			if seq = (seq + 1) & 0xfff; seq == 0 {
+				for now <= last {
+					time.Sleep(100 * time.Microsecond)
+					now = time.Now().UnixNano()/int64(time.Millisecond) - snowflakeEpoch
+				}
+			}
+		} else {
+			seq = 0
+		}
+
+		last = now
+		return strconv.FormatInt(now<<22|node<<12|seq, 10)
+	}
+}
+
+// RequestIdConfig 请求 ID 过滤器的配置
+type RequestIdConfig struct {
+
+	// Header 请求 ID 使用的请求头和响应头，为空时使用 X-Request-Id
+	Header string
+
+	// Generator 请求 ID 生成器，为 nil 时使用 UUIDGenerator
+	Generator RequestIdGenerator
+
+	// IgnoreIncoming 是否忽略客户端传入的请求 ID，总是生成新的请求 ID
+	IgnoreIncoming bool
+}
+
+// requestIdFilter 请求 ID 过滤器
+type requestIdFilter struct {
+	header         string
+	generator      RequestIdGenerator
+	ignoreIncoming bool
+}
+
+// RequestIdFilter 返回请求 ID 过滤器。客户端传入了合法的请求 ID 时沿用该 ID，否则
+// 生成一个新的 ID。请求 ID 会写入响应头，保存在 WebContext 和请求的 context.Context
+// 中，之后 WebContext 打印的日志都以 [request_id:xxx] 开头。
+func RequestIdFilter(cfg RequestIdConfig) Filter {
+
+	f := &requestIdFilter{
+		header:         cfg.Header,
+		generator:      cfg.Generator,
+		ignoreIncoming: cfg.IgnoreIncoming,
+	}
+
+	if f.header == "" {
+		f.header = HeaderXRequestId
+	}
+	if f.generator == nil {
+		f.generator = UUIDGenerator()
+	}
+	return f
+}
+
+func (f *requestIdFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	r := ctx.Request()
+
+	var id string
+	if !f.ignoreIncoming {
+		id = r.Header.Get(f.header)
+	}
+	if !validRequestId(id) {
+		id = f.generator()
+	}
+
+	ctx.Set(RequestIdKey, id)
+	ctx.Header(f.header, id)
+	ctx.SetRequest(r.WithContext(WithRequestId(r.Context(), id)))
+
+	chain.Next(ctx)
+}
+
+// validRequestId 请求 ID 是否合法，只允许字母、数字和 -_.:
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestId 返回保存了请求 ID 的 context.Context
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdContextKey{}, id)
+}
+
+// RequestIdFromContext 返回 context.Context 中保存的请求 ID，没有时返回空字符串
+func RequestIdFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdContextKey{}).(string)
+	return id
+}
+
+// NewLoggerContext 返回 WebContext 使用的 LoggerContext，ctx 中保存了合法的请求 ID
+// 时日志以 [request_id:xxx] 开头，合法的请求 ID 中不会出现格式化使用的 %。
+func NewLoggerContext(ctx context.Context) SpringLogger.LoggerContext {
+	if id := RequestIdFromContext(ctx); validRequestId(id) {
+		return PrefixLogger.NewLoggerContext(ctx, "[request_id:"+id+"] ")
+	}
+	return SpringLogger.NewDefaultLoggerContext(ctx)
+}
+
+// GetRequestId 返回请求的 ID，没有使用 RequestIdFilter 时返回空字符串
+func GetRequestId(ctx WebContext) string {
+	id, _ := lookupValue(ctx, RequestIdKey).(string)
+	return id
+}
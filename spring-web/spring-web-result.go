@@ -0,0 +1,374 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-spring/go-spring-parent/spring-error"
+)
+
+// ResultWrapperKey WebContext 中保存 ResultWrapper 的键
+const ResultWrapperKey = "@ResultWrapper"
+
+// RpcFailure 处理函数 panic 之后解析出的错误信息
+type RpcFailure struct {
+	Code       int32                  // 错误码
+	Msg        string                 // 按照请求的语言渲染之后的错误信息
+	HttpStatus int                    // HTTP 状态码
+	Err        error                  // 原始错误
+	Result     *SpringError.RpcResult // panic 值本身是 RpcResult 时不为 nil
+}
+
+// ResultWrapper 将 RPC 处理函数的结果封装成响应体，可以替换成公司内部的信封格式
+type ResultWrapper interface {
+	// Success 封装处理函数的返回值
+	Success(ctx WebContext, data interface{}) interface{}
+
+	// Failure 封装处理函数 panic 之后解析出的错误信息
+	Failure(ctx WebContext, f *RpcFailure) interface{}
+}
+
+// DefaultResultWrapper 默认的 ResultWrapper，使用 SpringError.RpcResult 格式
+var DefaultResultWrapper ResultWrapper = &rpcResultWrapper{}
+
+type rpcResultWrapper struct{}
+
+func (w *rpcResultWrapper) Success(ctx WebContext, data interface{}) interface{} {
+	return SpringError.SUCCESS.Data(data)
+}
+
+func (w *rpcResultWrapper) Failure(ctx WebContext, f *RpcFailure) interface{} {
+	if f.Result != nil {
+		return f.Result
+	}
+	result := &SpringError.RpcResult{ErrorCode: SpringError.NewErrorCode(f.Code, f.Msg)}
+	if f.Err != nil {
+		result.Err = f.Err.Error()
+	}
+	return result
+}
+
+// resultWrapperFilter 为请求指定 ResultWrapper 的过滤器
+type resultWrapperFilter struct {
+	w ResultWrapper
+}
+
+// ResultWrapperFilter 返回为请求指定 ResultWrapper 的过滤器
+func ResultWrapperFilter(w ResultWrapper) Filter {
+	return &resultWrapperFilter{w: w}
+}
+
+func (f *resultWrapperFilter) Invoke(ctx WebContext, chain FilterChain) {
+	ctx.Set(ResultWrapperKey, f.w)
+	chain.Next(ctx)
+}
+
+// GetResultWrapper 返回请求使用的 ResultWrapper
+func GetResultWrapper(ctx WebContext) ResultWrapper {
+	if w, ok := lookupValue(ctx, ResultWrapperKey).(ResultWrapper); ok && w != nil {
+		return w
+	}
+	return DefaultResultWrapper
+}
+
+/////////////////// error codes //////////////////////
+
+// CodedError 带有错误码的错误，处理函数 panic 这种错误时使用对应的错误码
+type CodedError interface {
+	error
+	ErrorCode() int32
+}
+
+// CodeError CodedError 的默认实现，错误信息由错误码目录中的模板渲染
+type CodeError struct {
+	Code  int32         // 错误码
+	Args  []interface{} // 模板参数，依次替换模板中的 {0}、{1} ...
+	Cause error         // 引起错误的原因
+}
+
+// NewCodeError CodeError 的构造函数
+func NewCodeError(code int32, args ...interface{}) *CodeError {
+	return &CodeError{Code: code, Args: args}
+}
+
+// WithCause 设置引起错误的原因
+func (e *CodeError) WithCause(err error) *CodeError {
+	e.Cause = err
+	return e
+}
+
+func (e *CodeError) ErrorCode() int32 {
+	return e.Code
+}
+
+func (e *CodeError) ErrorArgs() []interface{} {
+	return e.Args
+}
+
+func (e *CodeError) Unwrap() error {
+	return e.Cause
+}
+
+func (e *CodeError) Error() string {
+	msg := ErrorCodes().Message(e.Code, nil, e.Args...)
+	if e.Cause != nil {
+		return msg + ": " + e.Cause.Error()
+	}
+	return msg
+}
+
+// ErrorCodeEntry 错误码目录中的一项
+type ErrorCodeEntry struct {
+	Code       int32             // 错误码
+	HttpStatus int               // HTTP 状态码
+	messages   map[string]string // 语言 -> 消息模板，默认语言使用空字符串
+}
+
+// WithMessage 添加指定语言的消息模板，模板中的 {0}、{1} ... 被依次替换成参数
+func (e *ErrorCodeEntry) WithMessage(lang string, template string) *ErrorCodeEntry {
+	e.messages[strings.ToLower(lang)] = template
+	return e
+}
+
+// template 返回和语言列表最匹配的消息模板
+func (e *ErrorCodeEntry) template(languages []string) string {
+	for _, lang := range languages {
+		lang = strings.ToLower(lang)
+		if t, ok := e.messages[lang]; ok {
+			return t
+		}
+		if i := strings.Index(lang, "-"); i > 0 {
+			if t, ok := e.messages[lang[:i]]; ok {
+				return t
+			}
+		}
+	}
+	return e.messages[""]
+}
+
+// ErrorCatalog 错误码目录，支持消息模板和多语言
+type ErrorCatalog struct {
+	mutex   sync.RWMutex
+	entries map[int32]*ErrorCodeEntry
+	errs    []errorMapping
+}
+
+type errorMapping struct {
+	target error
+	code   int32
+}
+
+// NewErrorCatalog ErrorCatalog 的构造函数
+func NewErrorCatalog() *ErrorCatalog {
+	return &ErrorCatalog{entries: make(map[int32]*ErrorCodeEntry)}
+}
+
+// errorCodes 全局的错误码目录
+var errorCodes = NewErrorCatalog()
+
+// ErrorCodes 返回全局的错误码目录
+func ErrorCodes() *ErrorCatalog {
+	return errorCodes
+}
+
+// Register 注册一个错误码，template 是默认语言的消息模板，httpStatus 为 0 时使用 200
+func (c *ErrorCatalog) Register(code int32, httpStatus int, template string) *ErrorCodeEntry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e := &ErrorCodeEntry{
+		Code:       code,
+		HttpStatus: httpStatus,
+		messages:   map[string]string{"": template},
+	}
+	c.entries[code] = e
+	return e
+}
+
+// Get 获取错误码对应的项
+func (c *ErrorCatalog) Get(code int32) (*ErrorCodeEntry, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	e, ok := c.entries[code]
+	return e, ok
+}
+
+// MapError 将领域错误映射到错误码，错误链中的任一错误和 target 相同即匹配
+func (c *ErrorCatalog) MapError(target error, code int32) *ErrorCatalog {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.errs = append(c.errs, errorMapping{target: target, code: code})
+	return c
+}
+
+// Message 按照语言列表渲染错误码的消息
+func (c *ErrorCatalog) Message(code int32, languages []string, args ...interface{}) string {
+	e, ok := c.Get(code)
+	if !ok {
+		return "error code " + strconv.Itoa(int(code))
+	}
+	return formatMessage(e.template(languages), args)
+}
+
+// Resolve 将处理函数 panic 的值解析成 RpcFailure，languages 是客户端可以接受的语言
+func (c *ErrorCatalog) Resolve(r interface{}, languages []string) *RpcFailure {
+
+	if result, ok := r.(*SpringError.RpcResult); ok {
+		return &RpcFailure{
+			Code:       result.Code,
+			Msg:        result.Msg,
+			HttpStatus: http.StatusOK,
+			Result:     result,
+		}
+	}
+
+	err, ok := r.(error)
+	if !ok {
+		err = errors.New(fmt.Sprint(r))
+	}
+
+	code, args, found := c.lookup(err)
+	if !found {
+		return &RpcFailure{
+			Code:       SpringError.ERROR.Code,
+			Msg:        SpringError.ERROR.Msg,
+			HttpStatus: http.StatusOK,
+			Err:        err,
+		}
+	}
+
+	f := &RpcFailure{Code: code, HttpStatus: http.StatusOK, Err: err}
+	if e, ok := c.Get(code); ok {
+		f.Msg = formatMessage(e.template(languages), args)
+		if e.HttpStatus != 0 {
+			f.HttpStatus = e.HttpStatus
+		}
+	} else {
+		f.Msg = err.Error()
+	}
+	return f
+}
+
+// lookup 查找错误对应的错误码和模板参数
+func (c *ErrorCatalog) lookup(err error) (int32, []interface{}, bool) {
+
+	if coded, ok := asCodedError(err); ok {
+		var args []interface{}
+		if a, ok := coded.(interface{ ErrorArgs() []interface{} }); ok {
+			args = a.ErrorArgs()
+		}
+		return coded.ErrorCode(), args, true
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for _, m := range c.errs {
+		if isError(err, m.target) {
+			return m.code, nil, true
+		}
+	}
+	return 0, nil, false
+}
+
+// unwrapError 返回错误链中的下一个错误，使用和 Go 1.13 相同的 Unwrap 约定，
+// 因此 fmt.Errorf 的 %w 包装的错误也能展开
+func unwrapError(err error) error {
+	if u, ok := err.(interface{ Unwrap() error }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// isError 错误链中是否存在和 target 相同的错误，等同于 Go 1.13 的 errors.Is
+func isError(err error, target error) bool {
+	if target == nil {
+		return err == target
+	}
+	comparable := reflect.TypeOf(target).Comparable()
+	for ; err != nil; err = unwrapError(err) {
+		if comparable && err == target {
+			return true
+		}
+		if x, ok := err.(interface{ Is(error) bool }); ok && x.Is(target) {
+			return true
+		}
+	}
+	return false
+}
+
+// asCodedError 返回错误链中第一个 CodedError，等同于 Go 1.13 的 errors.As
+func asCodedError(err error) (CodedError, bool) {
+	for ; err != nil; err = unwrapError(err) {
+		if coded, ok := err.(CodedError); ok {
+			return coded, true
+		}
+	}
+	return nil, false
+}
+
+// formatMessage 将模板中的 {0}、{1} ... 替换成参数
+func formatMessage(template string, args []interface{}) string {
+	for i, arg := range args {
+		template = strings.Replace(template, "{"+strconv.Itoa(i)+"}", fmt.Sprint(arg), -1)
+	}
+	return template
+}
+
+// AcceptLanguages 按照权重从高到低解析 Accept-Language 头
+func AcceptLanguages(header string) []string {
+
+	type weighted struct {
+		lang string
+		q    float64
+	}
+
+	var langs []weighted
+	for _, s := range strings.Split(header, ",") {
+		ss := strings.Split(strings.TrimSpace(s), ";")
+		lang := strings.TrimSpace(ss[0])
+		if lang == "" || lang == "*" {
+			continue
+		}
+		q := 1.0
+		for _, p := range ss[1:] {
+			if p = strings.TrimSpace(p); strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		langs = append(langs, weighted{lang: lang, q: q})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	r := make([]string, 0, len(langs))
+	for _, l := range langs {
+		r = append(r, l.lang)
+	}
+	return r
+}
@@ -16,11 +16,20 @@
 
 package SpringWeb
 
+import (
+	"errors"
+	"strings"
+)
+
 // Router 路由分组
 type Router struct {
 	mapping  WebMapping
 	basePath string
 	filters  []Filter
+	cors     Filter // 跨域资源共享过滤器，不为 nil 时自动注册预检请求的路由
+
+	docGroup    string // Swagger 文档分组，为空时使用容器的文档
+	docExcluded bool   // 是否从 Swagger 文档中排除
 }
 
 // NewRouter Router 的构造函数，不依赖具体的 WebMapping 对象
@@ -32,10 +41,82 @@ func NewRouter(basePath string, filters ...Filter) *Router {
 	}
 }
 
+// BasePath 返回路由分组的路径前缀
+func (r *Router) BasePath() string {
+	return r.basePath
+}
+
+// WithResultWrapper 覆盖容器的 RPC 结果封装器，只对之后注册的处理函数生效
+func (r *Router) WithResultWrapper(wrapper ResultWrapper) *Router {
+	r.filters = append(r.filters, ResultWrapperFilter(wrapper))
+	return r
+}
+
+// WithCors 为路由分组启用跨域资源共享，只对之后注册的处理函数生效。同时为这些
+// 处理函数的路径自动注册 OPTIONS 方法，已经注册过 OPTIONS 方法的路径除外。跨域
+// 过滤器总是放在路由分组其他过滤器的前面，预检请求不会到达认证、授权等过滤器；
+// 容器和全局的过滤器仍然先于路由分组的过滤器执行，这些过滤器中有认证过滤器时
+// 应该改为在它们前面使用 CorsFilter。
+func (r *Router) WithCors(cfg CorsConfig) *Router {
+	filters := []Filter{CorsFilter(cfg)}
+	for _, f := range r.filters {
+		if f != r.cors { // 替换之前设置的跨域过滤器
+			filters = append(filters, f)
+		}
+	}
+	r.cors = filters[0]
+	r.filters = filters
+	return r
+}
+
+// WithSecureHeaders 为路由分组使用另外的安全响应头配置，覆盖全局 SecureFilter
+// 输出的安全响应头，只对之后注册的处理函数生效。
+func (r *Router) WithSecureHeaders(cfg SecureConfig) *Router {
+	r.filters = append(r.filters, SecureFilter(cfg))
+	return r
+}
+
+// DocGroup 返回路由分组所属的 Swagger 文档分组
+func (r *Router) DocGroup() string {
+	return r.docGroup
+}
+
+// WithDocGroup 将路由分组的处理函数放入名为 group 的 Swagger 文档，
+// 该文档通过 /swagger/{group}/、/redoc/{group} 等地址单独发布。
+func (r *Router) WithDocGroup(group string) *Router {
+	if strings.Contains(group, "/") {
+		panic(errors.New("doc group can't contain '/'"))
+	}
+	r.docGroup = group
+	return r
+}
+
+// ExcludeFromDoc 从 Swagger 文档中排除路由分组的处理函数
+func (r *Router) ExcludeFromDoc() *Router {
+	r.docExcluded = true
+	return r
+}
+
 // Request 注册任意 HTTP 方法处理函数
 func (r *Router) Request(method uint32, path string, fn interface{}, filters ...Filter) *Mapper {
-	filters = append(r.filters, filters...)
-	return r.mapping.Request(method, r.basePath+path, fn, filters...)
+	filters = append(append([]Filter{}, r.filters...), filters...)
+	m := r.mapping.Request(method, r.basePath+path, fn, filters...)
+	m.router = r
+	if r.cors != nil && method&MethodOptions == 0 {
+		r.corsPreflight(m.Path())
+	}
+	return m
+}
+
+// corsPreflight 为路径注册处理预检请求的 OPTIONS 方法
+func (r *Router) corsPreflight(path string) {
+	for _, m := range r.mapping.Mappers() {
+		if m.Path() == path && m.Method()&MethodOptions != 0 {
+			return
+		}
+	}
+	m := r.mapping.Request(MethodOptions, path, FUNC(corsPreflight), r.cors)
+	m.router = r
 }
 
 // Deprecated: 推荐使用 Get* 系列函数进行编译检查
@@ -102,3 +183,15 @@ func (r *Router) HEAD(path string, fn interface{}, filters ...Filter) *Mapper {
 func (r *Router) OPTIONS(path string, fn interface{}, filters ...Filter) *Mapper {
 	return r.Request(MethodOptions, path, fn, filters...)
 }
+
+// JSONRPC 注册 JSON-RPC 2.0 端点，返回的端点用于注册方法
+func (r *Router) JSONRPC(path string, filters ...Filter) *JsonRpc {
+	j := newJsonRpc(r.basePath + path)
+	r.Request(MethodPost, path, j, filters...)
+	return j
+}
+
+// Service 将服务对象所有 BIND 形式的导出方法注册为处理函数
+func (r *Router) Service(prefix string, svc interface{}, filters ...Filter) *ServiceRegistration {
+	return registerService(r.Request, prefix, svc, filters)
+}
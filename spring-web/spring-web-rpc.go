@@ -18,7 +18,6 @@ package SpringWeb
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"reflect"
 
@@ -48,51 +47,61 @@ type bindHandler struct {
 	fnVal    reflect.Value // 原始函数的值
 	bindType reflect.Type  // 待绑定的类型
 	ctxIndex int           // ctx 变量的位置
+	src      interface{}   // 用于获取源码位置的函数，为 nil 时使用 fn
 }
 
 func (b *bindHandler) Invoke(ctx WebContext) {
 	rpcInvoke(ctx, func() interface{} {
 
-		var (
-			err     error
-			bindVal reflect.Value
-		)
-
 		// 获取待绑定的值
-		if b.bindType.Kind() == reflect.Ptr {
-			bindVal = reflect.New(b.bindType.Elem())
-			err = ctx.Bind(bindVal.Interface())
-		} else {
-			bindVal = reflect.New(b.bindType)
-			err = ctx.Bind(bindVal.Interface())
-			bindVal = bindVal.Elem()
-		}
-
+		bindVal := b.newBindValue()
+		err := ctx.Bind(bindVal.Interface())
 		SpringError.ERROR.Panic(err).When(err != nil)
 
-		var in []reflect.Value
+		return b.call(ctx, bindVal)
+	})
+}
 
-		// 组装请求参数
-		if b.ctxIndex == 0 {
-			// func(WebContext,Request)Response
-			in = append(in, reflect.ValueOf(ctx))
-			in = append(in, bindVal)
-		} else if b.ctxIndex == 1 {
-			// func(Request,WebContext)Response
-			in = append(in, bindVal)
-			in = append(in, reflect.ValueOf(ctx))
-		} else {
-			// func(Request)Response
-			in = append(in, bindVal)
-		}
+// newBindValue 创建待绑定的值，返回值总是指针形式
+func (b *bindHandler) newBindValue() reflect.Value {
+	if b.bindType.Kind() == reflect.Ptr {
+		return reflect.New(b.bindType.Elem())
+	}
+	return reflect.New(b.bindType)
+}
 
-		// 执行处理函数，并返回结果
-		outVal := b.fnVal.Call(in)
-		return outVal[0].Interface()
-	})
+// call 组装请求参数并执行处理函数，bindVal 是 newBindValue 创建的指针
+func (b *bindHandler) call(ctx WebContext, bindVal reflect.Value) interface{} {
+
+	if b.bindType.Kind() != reflect.Ptr {
+		bindVal = bindVal.Elem()
+	}
+
+	var in []reflect.Value
+
+	// 组装请求参数
+	if b.ctxIndex == 0 {
+		// func(WebContext,Request)Response
+		in = append(in, reflect.ValueOf(ctx))
+		in = append(in, bindVal)
+	} else if b.ctxIndex == 1 {
+		// func(Request,WebContext)Response
+		in = append(in, bindVal)
+		in = append(in, reflect.ValueOf(ctx))
+	} else {
+		// func(Request)Response
+		in = append(in, bindVal)
+	}
+
+	// 执行处理函数，并返回结果
+	outVal := b.fnVal.Call(in)
+	return outVal[0].Interface()
 }
 
 func (b *bindHandler) FileLine() (file string, line int, fnName string) {
+	if b.src != nil {
+		return SpringUtils.FileLine(b.src)
+	}
 	return SpringUtils.FileLine(b.fn)
 }
 
@@ -152,6 +161,11 @@ func validBindFn(fn interface{}) (reflect.Type, int, bool) {
 
 // BIND 转换成 BIND 形式的 Web 处理接口
 func BIND(fn interface{}) Handler {
+	return newBindHandler(fn)
+}
+
+// newBindHandler bindHandler 的构造函数
+func newBindHandler(fn interface{}) *bindHandler {
 
 	var (
 		ok       bool
@@ -178,20 +192,27 @@ func rpcInvoke(webCtx WebContext, fn func() interface{}) {
 	// 目前 HTTP RPC 只能返回 json 格式的数据
 	webCtx.Header("Content-Type", "application/json")
 
+	wrapper := GetResultWrapper(webCtx)
+
 	defer func() {
 		if r := recover(); r != nil {
-			result, ok := r.(*SpringError.RpcResult)
-			if !ok {
-				var err error
-				if err, ok = r.(error); !ok {
-					err = errors.New(fmt.Sprint(r))
-				}
-				result = SpringError.ERROR.Error(err)
-			}
-			webCtx.JSON(http.StatusOK, result)
+			f := resolveFailure(webCtx, r)
+			webCtx.JSON(f.HttpStatus, wrapper.Failure(webCtx, f))
 		}
 	}()
 
-	result := SpringError.SUCCESS.Data(fn())
-	webCtx.JSON(http.StatusOK, result)
+	// 流式返回值逐个写出元素
+	v := fn()
+	if isStreamResult(v) {
+		streamInvoke(webCtx, v)
+		return
+	}
+
+	webCtx.JSON(http.StatusOK, wrapper.Success(webCtx, v))
+}
+
+// resolveFailure 使用全局的错误码目录和请求的 Accept-Language 头解析 panic 的值
+func resolveFailure(webCtx WebContext, r interface{}) *RpcFailure {
+	languages := AcceptLanguages(webCtx.GetHeader(HeaderAcceptLanguage))
+	return ErrorCodes().Resolve(r, languages)
 }
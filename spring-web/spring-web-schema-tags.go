@@ -0,0 +1,309 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// validateTags 存放校验规则的标签，binding 为 gin 的习惯用法，validate 为 validator 的默认标签
+var validateTags = []string{"binding", "validate"}
+
+// rulePatterns 可以使用正则表达式描述的校验规则
+var rulePatterns = map[string]string{
+	"alpha":       `^[a-zA-Z]+$`,
+	"alphanum":    `^[a-zA-Z0-9]+$`,
+	"numeric":     `^[-+]?[0-9]+(?:\.[0-9]+)?$`,
+	"number":      `^[0-9]+$`,
+	"hexadecimal": `^(0[xX])?[0-9a-fA-F]+$`,
+}
+
+// ruleFormats 对应 Swagger format 的校验规则
+var ruleFormats = map[string]string{
+	"email":            "email",
+	"uuid":             "uuid",
+	"uuid3":            "uuid",
+	"uuid4":            "uuid",
+	"uuid5":            "uuid",
+	"url":              "uri",
+	"uri":              "uri",
+	"http_url":         "uri",
+	"hostname":         "hostname",
+	"hostname_rfc1123": "hostname",
+	"ipv4":             "ipv4",
+	"ipv6":             "ipv6",
+}
+
+// fieldRules 返回字段 binding 和 validate 标签中的校验规则
+func fieldRules(f reflect.StructField) []string {
+	var rules []string
+	for _, key := range validateTags {
+		if v, ok := f.Tag.Lookup(key); ok && v != "" && v != "-" {
+			rules = append(rules, strings.Split(v, ",")...)
+		}
+	}
+	return rules
+}
+
+// applyFieldTags 将字段的校验规则以及 description、example、enum 标签转换成
+// Schema 的约束，返回字段是否必须。校验规则中的 required 和 omitempty 优先于
+// json 标签的 omitempty，dive 之后的规则作用于数组或者映射的元素。
+func applyFieldTags(s *spec.Schema, f reflect.StructField, required bool) bool {
+
+	target := s
+	for _, rule := range fieldRules(f) {
+
+		// 使用 | 组合的规则无法使用 Schema 描述
+		if rule == "" || strings.Contains(rule, "|") {
+			continue
+		}
+
+		name, arg := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		switch name {
+		case "required":
+			if target == s {
+				required = true
+			}
+		case "omitempty":
+			if target == s {
+				required = false
+			}
+		case "dive":
+			if target = elemSchema(target); target == nil {
+				return required
+			}
+		default:
+			applyRule(target, name, arg)
+		}
+	}
+
+	if s.Ref.String() != "" {
+		return required
+	}
+
+	if v, ok := f.Tag.Lookup("description"); ok {
+		s.WithDescription(v)
+	}
+
+	if v, ok := f.Tag.Lookup("example"); ok {
+		s.WithExample(tagValue(s, v))
+	}
+
+	if v, ok := f.Tag.Lookup("enum"); ok {
+		target = s
+		if elem := elemSchema(s); elem != nil && s.Type.Contains("array") {
+			target = elem
+		}
+		var enums []interface{}
+		for _, e := range strings.Split(v, ",") {
+			enums = append(enums, tagValue(target, strings.TrimSpace(e)))
+		}
+		target.WithEnum(enums...)
+	}
+
+	return required
+}
+
+// elemSchema 返回数组或者映射的元素的 Schema，元素为引用时返回 nil
+func elemSchema(s *spec.Schema) *spec.Schema {
+	var elem *spec.Schema
+	if s.Items != nil {
+		elem = s.Items.Schema
+	} else if s.AdditionalProperties != nil {
+		elem = s.AdditionalProperties.Schema
+	}
+	if elem == nil || elem.Ref.String() != "" {
+		return nil
+	}
+	return elem
+}
+
+// applyRule 将一条校验规则转换成 Schema 的约束，无法转换的规则被忽略
+func applyRule(s *spec.Schema, name string, arg string) {
+
+	// 引用的同级约束会被忽略
+	if s.Ref.String() != "" {
+		return
+	}
+
+	switch name {
+	case "min", "gte":
+		setLowerBound(s, arg, false)
+	case "max", "lte":
+		setUpperBound(s, arg, false)
+	case "gt":
+		setLowerBound(s, arg, true)
+	case "lt":
+		setUpperBound(s, arg, true)
+	case "len":
+		setLowerBound(s, arg, false)
+		setUpperBound(s, arg, false)
+	case "oneof":
+		var enums []interface{}
+		for _, v := range splitOneOf(arg) {
+			enums = append(enums, tagValue(s, v))
+		}
+		s.WithEnum(enums...)
+	case "startswith":
+		s.WithPattern("^" + regexp.QuoteMeta(arg))
+	case "endswith":
+		s.WithPattern(regexp.QuoteMeta(arg) + "$")
+	case "contains":
+		s.WithPattern(regexp.QuoteMeta(arg))
+	default:
+		if !s.Type.Contains("string") {
+			return
+		}
+		if format, ok := ruleFormats[name]; ok {
+			s.Format = format
+		} else if pattern, ok := rulePatterns[name]; ok {
+			s.WithPattern(pattern)
+		}
+	}
+}
+
+// setLowerBound 设置下限，字符串为长度，数组为元素个数，数字为取值
+func setLowerBound(s *spec.Schema, arg string, exclusive bool) {
+
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return
+	}
+
+	n := int64(f)
+	if exclusive {
+		n++
+	}
+
+	switch {
+	case s.Type.Contains("string"):
+		s.WithMinLength(n)
+	case s.Type.Contains("array"):
+		s.WithMinItems(n)
+	case s.Type.Contains("object"):
+		s.WithMinProperties(n)
+	case s.Type.Contains("integer"), s.Type.Contains("number"):
+		s.WithMinimum(f, exclusive)
+	}
+}
+
+// setUpperBound 设置上限，字符串为长度，数组为元素个数，数字为取值
+func setUpperBound(s *spec.Schema, arg string, exclusive bool) {
+
+	f, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return
+	}
+
+	n := int64(f)
+	if exclusive {
+		n--
+	}
+
+	switch {
+	case s.Type.Contains("string"):
+		s.WithMaxLength(n)
+	case s.Type.Contains("array"):
+		s.WithMaxItems(n)
+	case s.Type.Contains("object"):
+		s.WithMaxProperties(n)
+	case s.Type.Contains("integer"), s.Type.Contains("number"):
+		s.WithMaximum(f, exclusive)
+	}
+}
+
+// oneOfValue oneof 规则参数中的一个取值
+var oneOfValue = regexp.MustCompile(`'[^']*'|\S+`)
+
+// splitOneOf 拆分 oneof 规则的参数，和 validator 一样支持使用单引号包含空格
+func splitOneOf(arg string) []string {
+	var values []string
+	for _, v := range oneOfValue.FindAllString(arg, -1) {
+		values = append(values, strings.Trim(v, "'"))
+	}
+	return values
+}
+
+// tagValue 根据 Schema 的类型将标签中的字符串转换成对应类型的值
+func tagValue(s *spec.Schema, v string) interface{} {
+	switch {
+	case s.Type.Contains("integer"):
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	case s.Type.Contains("number"):
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case s.Type.Contains("boolean"):
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	case s.Type.Contains("array"):
+		elem := elemSchema(s)
+		if elem == nil {
+			elem = new(spec.Schema)
+		}
+		var r []interface{}
+		for _, e := range strings.Split(v, ",") {
+			r = append(r, tagValue(elem, strings.TrimSpace(e)))
+		}
+		return r
+	}
+	return v
+}
+
+// applyParamTags 将字段的标签转换成非 body 参数的约束
+func applyParamTags(param *spec.Parameter, f reflect.StructField) {
+
+	s := new(spec.Schema).Typed(param.Type, param.Format)
+	if param.Items != nil {
+		s.Items = &spec.SchemaOrArray{Schema: new(spec.Schema).Typed(param.Items.Type, param.Items.Format)}
+	}
+
+	if applyFieldTags(s, f, param.Required) {
+		param.AsRequired()
+	}
+
+	param.WithDescription(s.Description)
+	param.Format = s.Format
+	param.Maximum, param.ExclusiveMaximum = s.Maximum, s.ExclusiveMaximum
+	param.Minimum, param.ExclusiveMinimum = s.Minimum, s.ExclusiveMinimum
+	param.MaxLength, param.MinLength = s.MaxLength, s.MinLength
+	param.MaxItems, param.MinItems = s.MaxItems, s.MinItems
+	param.Pattern = s.Pattern
+	param.Enum = s.Enum
+	param.Example = s.Example
+
+	if param.Items != nil {
+		items := s.Items.Schema
+		param.Items.Maximum, param.Items.ExclusiveMaximum = items.Maximum, items.ExclusiveMaximum
+		param.Items.Minimum, param.Items.ExclusiveMinimum = items.Minimum, items.ExclusiveMinimum
+		param.Items.MaxLength, param.Items.MinLength = items.MaxLength, items.MinLength
+		param.Items.Pattern = items.Pattern
+		param.Items.Enum = items.Enum
+	}
+}
@@ -0,0 +1,350 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-openapi/spec"
+)
+
+// SpecViolation 请求或者响应和文档不一致的地方
+type SpecViolation struct {
+	In      string `json:"in"`             // 位置，path、query、header、formData、body
+	Name    string `json:"name,omitempty"` // 参数名或者字段路径，例如 body.tags[0]
+	Message string `json:"message"`        // 不一致的原因
+}
+
+func (v SpecViolation) String() string {
+	if v.Name == "" {
+		return v.In + ": " + v.Message
+	}
+	return v.In + " " + v.Name + ": " + v.Message
+}
+
+// maxSchemaDepth 校验时展开 Schema 的最大深度，避免错误的递归引用
+const maxSchemaDepth = 64
+
+// uuidPattern uuid 格式
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// schemaPatterns 编译过的 pattern 缓存
+var schemaPatterns sync.Map
+
+// schemaValidator 使用 Swagger 2.0 的 Schema 校验 JSON 解码后的值，数字需要使用
+// json.Number 表示。Go 习惯把空的切片和映射编码为 null，所以数组和对象允许为 null。
+type schemaValidator struct {
+	defs spec.Definitions
+	in   string
+}
+
+// newSchemaValidator schemaValidator 的构造函数
+func newSchemaValidator(defs spec.Definitions, in string) *schemaValidator {
+	return &schemaValidator{defs: defs, in: in}
+}
+
+// resolve 返回引用指向的定义，无法解析时返回 nil
+func (v *schemaValidator) resolve(s *spec.Schema) *spec.Schema {
+	for i := 0; s != nil && s.Ref.String() != "" && i < maxSchemaDepth; i++ {
+		ref := s.Ref.String()
+		if !strings.HasPrefix(ref, "#/definitions/") {
+			return nil
+		}
+		def, ok := v.defs[strings.TrimPrefix(ref, "#/definitions/")]
+		if !ok {
+			return nil
+		}
+		s = &def
+	}
+	return s
+}
+
+// Validate 校验 x 是否符合 s，name 为值的路径
+func (v *schemaValidator) Validate(name string, s *spec.Schema, x interface{}) []SpecViolation {
+	var r []SpecViolation
+	v.validate(name, s, x, 0, &r)
+	return r
+}
+
+func (v *schemaValidator) fail(r *[]SpecViolation, name string, format string, args ...interface{}) {
+	*r = append(*r, SpecViolation{In: v.in, Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *schemaValidator) validate(name string, s *spec.Schema, x interface{}, depth int, r *[]SpecViolation) {
+
+	if depth > maxSchemaDepth {
+		return
+	}
+
+	if s = v.resolve(s); s == nil {
+		return
+	}
+
+	for i := range s.AllOf {
+		v.validate(name, &s.AllOf[i], x, depth+1, r)
+	}
+
+	// oneOf 和 anyOf 只要求符合其中一个 Schema
+	for _, schemas := range [][]spec.Schema{s.OneOf, s.AnyOf} {
+		if len(schemas) == 0 {
+			continue
+		}
+		matched := false
+		for i := range schemas {
+			var branch []SpecViolation
+			v.validate(name, &schemas[i], x, depth+1, &branch)
+			if len(branch) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(r, name, "must match one of %d schemas", len(schemas))
+		}
+	}
+
+	if x == nil {
+		if nullable, _ := s.Extensions.GetBool("x-nullable"); nullable {
+			return
+		}
+		if len(s.Type) == 0 || s.Type.Contains("null") || s.Type.Contains("array") || s.Type.Contains("object") {
+			return
+		}
+		v.fail(r, name, "must not be null")
+		return
+	}
+
+	if len(s.Type) > 0 && !matchSchemaType(s.Type, x) {
+		v.fail(r, name, "must be %s", strings.Join(s.Type, " or "))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, x) {
+		v.fail(r, name, "must be one of %s", enumString(s.Enum))
+	}
+
+	switch t := x.(type) {
+	case string:
+		v.validateString(name, s, t, r)
+	case json.Number:
+		v.validateNumber(name, s, t, r)
+	case []interface{}:
+		if s.MinItems != nil && int64(len(t)) < *s.MinItems {
+			v.fail(r, name, "must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && int64(len(t)) > *s.MaxItems {
+			v.fail(r, name, "must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			for i, e := range t {
+				v.validate(name+"["+strconv.Itoa(i)+"]", s.Items.Schema, e, depth+1, r)
+			}
+		}
+	case map[string]interface{}:
+		v.validateObject(name, s, t, depth, r)
+	}
+}
+
+func (v *schemaValidator) validateString(name string, s *spec.Schema, x string, r *[]SpecViolation) {
+
+	n := int64(utf8.RuneCountInString(x))
+	if s.MinLength != nil && n < *s.MinLength {
+		v.fail(r, name, "length must be at least %d", *s.MinLength)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		v.fail(r, name, "length must be at most %d", *s.MaxLength)
+	}
+
+	if s.Pattern != "" {
+		if re := schemaPattern(s.Pattern); re != nil && !re.MatchString(x) {
+			v.fail(r, name, "must match pattern %s", s.Pattern)
+		}
+	}
+
+	var err error
+	switch s.Format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, x)
+	case "date":
+		_, err = time.Parse("2006-01-02", x)
+	case "byte":
+		_, err = base64.StdEncoding.DecodeString(x)
+	case "uuid":
+		if !uuidPattern.MatchString(x) {
+			err = fmt.Errorf("invalid uuid")
+		}
+	case "email":
+		if i := strings.Index(x, "@"); i <= 0 || i == len(x)-1 {
+			err = fmt.Errorf("invalid email")
+		}
+	}
+	if err != nil {
+		v.fail(r, name, "must be a valid %s", s.Format)
+	}
+}
+
+func (v *schemaValidator) validateNumber(name string, s *spec.Schema, x json.Number, r *[]SpecViolation) {
+
+	f, err := x.Float64()
+	if err != nil {
+		return
+	}
+
+	if s.Minimum != nil {
+		if f < *s.Minimum || (s.ExclusiveMinimum && f == *s.Minimum) {
+			v.fail(r, name, "must be %s %v", boundWord(s.ExclusiveMinimum, ">"), *s.Minimum)
+		}
+	}
+	if s.Maximum != nil {
+		if f > *s.Maximum || (s.ExclusiveMaximum && f == *s.Maximum) {
+			v.fail(r, name, "must be %s %v", boundWord(s.ExclusiveMaximum, "<"), *s.Maximum)
+		}
+	}
+	if s.Format == "int32" {
+		if i, err := x.Int64(); err == nil && (i < -1<<31 || i > 1<<31-1) {
+			v.fail(r, name, "must be a valid int32")
+		}
+	}
+}
+
+// boundWord 返回边界的比较符号
+func boundWord(exclusive bool, op string) string {
+	if exclusive {
+		return op
+	}
+	return op + "="
+}
+
+func (v *schemaValidator) validateObject(name string, s *spec.Schema, x map[string]interface{}, depth int, r *[]SpecViolation) {
+
+	for _, key := range s.Required {
+		if _, ok := x[key]; !ok {
+			v.fail(r, joinName(name, key), "is required")
+		}
+	}
+
+	if s.MinProperties != nil && int64(len(x)) < *s.MinProperties {
+		v.fail(r, name, "must have at least %d properties", *s.MinProperties)
+	}
+	if s.MaxProperties != nil && int64(len(x)) > *s.MaxProperties {
+		v.fail(r, name, "must have at most %d properties", *s.MaxProperties)
+	}
+
+	keys := make([]string, 0, len(x))
+	for key := range x {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if prop, ok := s.Properties[key]; ok {
+			v.validate(joinName(name, key), &prop, x[key], depth+1, r)
+			continue
+		}
+		if ap := s.AdditionalProperties; ap != nil {
+			if ap.Schema != nil {
+				v.validate(joinName(name, key), ap.Schema, x[key], depth+1, r)
+			} else if !ap.Allows {
+				v.fail(r, joinName(name, key), "is not allowed")
+			}
+		}
+	}
+}
+
+// joinName 拼接字段路径
+func joinName(name string, key string) string {
+	if name == "" {
+		return key
+	}
+	return name + "." + key
+}
+
+// schemaPattern 返回编译过的 pattern，无法编译时返回 nil
+func schemaPattern(pattern string) *regexp.Regexp {
+	if re, ok := schemaPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	schemaPatterns.Store(pattern, re)
+	return re
+}
+
+// matchSchemaType 值的类型是否符合 Schema 的类型
+func matchSchemaType(types spec.StringOrArray, x interface{}) bool {
+	for _, t := range types {
+		switch t {
+		case "string":
+			if _, ok := x.(string); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := x.(json.Number); ok {
+				if _, err := n.Int64(); err == nil {
+					return true
+				}
+			}
+		case "number":
+			if _, ok := x.(json.Number); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := x.(bool); ok {
+				return true
+			}
+		case "array":
+			if _, ok := x.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := x.(map[string]interface{}); ok {
+				return true
+			}
+		case "file":
+			return true
+		}
+	}
+	return false
+}
+
+// inEnum 值是否在枚举列表中，使用 JSON 编码之后的内容比较
+func inEnum(enum []interface{}, x interface{}) bool {
+	b, _ := json.Marshal(x)
+	for _, e := range enum {
+		if eb, _ := json.Marshal(e); string(eb) == string(b) {
+			return true
+		}
+	}
+	return false
+}
+
+// enumString 返回枚举列表的描述
+func enumString(enum []interface{}) string {
+	b, _ := json.Marshal(enum)
+	return string(b)
+}
@@ -0,0 +1,331 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-openapi/spec"
+)
+
+// SchemaProvider 类型实现该接口后使用返回的 Schema，而不是通过反射生成
+type SchemaProvider interface {
+	JSONSchema() spec.Schema
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	rawMessageType      = reflect.TypeOf(json.RawMessage{})
+	xmlNameType         = reflect.TypeOf(xml.Name{})
+	schemaProviderType  = reflect.TypeOf((*SchemaProvider)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// schemaNames 类型和定义名称之间的对应关系，不同包中的同名类型使用不同的名称
+type schemaNames struct {
+	byType map[reflect.Type]string
+	byName map[string]reflect.Type
+}
+
+// newSchemaNames schemaNames 的构造函数
+func newSchemaNames() *schemaNames {
+	return &schemaNames{
+		byType: make(map[reflect.Type]string),
+		byName: make(map[string]reflect.Type),
+	}
+}
+
+// nameOf 返回类型的定义名称，同名类型已经存在时使用包名作为前缀
+func (n *schemaNames) nameOf(t reflect.Type) string {
+
+	if name, ok := n.byType[t]; ok {
+		return name
+	}
+
+	base := definitionName(t.Name())
+	name := base
+	if owner, ok := n.byName[name]; ok && owner != t {
+		base = exportedName(path.Base(t.PkgPath())) + base
+		name = base
+		for i := 2; n.byName[name] != nil; i++ {
+			name = base + strconv.Itoa(i)
+		}
+	}
+
+	n.byType[t] = name
+	n.byName[name] = t
+	return name
+}
+
+// definitionName 将类型名称 (可能包含泛型参数) 转换成合法的定义名称
+func definitionName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// schemaBuilder 使用反射生成类型对应的 JSON Schema，命名的结构体注册到 defs 中
+// 并使用 $ref 引用，已经存在的定义不会被覆盖，递归类型也只会生成一次。
+type schemaBuilder struct {
+	refPrefix string                 // $ref 的前缀，例如 #/definitions/
+	defs      map[string]spec.Schema // 定义列表
+	names     *schemaNames           // 定义名称
+}
+
+// newSchemaBuilder schemaBuilder 的构造函数，names 为 nil 时使用新的名称表
+func newSchemaBuilder(refPrefix string, defs map[string]spec.Schema, names *schemaNames) *schemaBuilder {
+	if names == nil {
+		names = newSchemaNames()
+	}
+	return &schemaBuilder{refPrefix: refPrefix, defs: defs, names: names}
+}
+
+// schema 生成类型对应的 JSON Schema
+func (b *schemaBuilder) schema(t reflect.Type) spec.Schema {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Implements(schemaProviderType) || reflect.PtrTo(t).Implements(schemaProviderType) {
+		provided := reflect.New(t).Interface().(SchemaProvider).JSONSchema()
+		if t.Name() == "" {
+			return provided
+		}
+		return b.define(t, func() spec.Schema { return provided })
+	}
+
+	switch t {
+	case timeType:
+		return *spec.DateTimeProperty()
+	case durationType:
+		return *spec.Int64Property().WithDescription("nanoseconds")
+	case rawMessageType:
+		return spec.Schema{}
+	}
+
+	// 自定义 JSON 编码的类型无法知道其结构
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return spec.Schema{}
+	}
+
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType) {
+		return *spec.StringProperty()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return *spec.BoolProperty()
+	case reflect.Int8:
+		return *spec.Int8Property()
+	case reflect.Int16:
+		return *spec.Int16Property()
+	case reflect.Int32:
+		return *spec.Int32Property()
+	case reflect.Int, reflect.Int64:
+		return *spec.Int64Property()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		format := t.Kind().String()
+		if t.Kind() == reflect.Uint || t.Kind() == reflect.Uintptr {
+			format = "uint64"
+		}
+		return *new(spec.Schema).Typed("integer", format).WithMinimum(0, false)
+	case reflect.Float32:
+		return *spec.Float32Property()
+	case reflect.Float64:
+		return *spec.Float64Property()
+	case reflect.String:
+		return *spec.StringProperty()
+	case reflect.Interface:
+		return spec.Schema{}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 { // []byte 使用 base64 编码
+			return *new(spec.Schema).Typed("string", "byte")
+		}
+		items := b.schema(t.Elem())
+		return *spec.ArrayProperty(&items)
+	case reflect.Array:
+		items := b.schema(t.Elem())
+		return *spec.ArrayProperty(&items).
+			WithMinItems(int64(t.Len())).
+			WithMaxItems(int64(t.Len()))
+	case reflect.Map:
+		if !validMapKey(t.Key()) {
+			return spec.Schema{}
+		}
+		elem := b.schema(t.Elem())
+		return *spec.MapProperty(&elem)
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.objectSchema(t)
+		}
+		return b.define(t, func() spec.Schema { return b.objectSchema(t) })
+	}
+
+	// chan、func、complex 等类型无法使用 JSON 编码
+	return spec.Schema{}
+}
+
+// validMapKey encoding/json 是否支持该类型的键
+func validMapKey(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// define 注册命名类型的定义并返回对它的引用
+func (b *schemaBuilder) define(t reflect.Type, gen func() spec.Schema) spec.Schema {
+	_, known := b.names.byType[t]
+	name := b.names.nameOf(t)
+	if _, ok := b.defs[name]; !ok && !known {
+		b.defs[name] = spec.Schema{} // 先占位，避免递归类型无限展开
+		b.defs[name] = gen()
+	}
+	return *spec.RefSchema(b.refPrefix + name)
+}
+
+// objectSchema 生成结构体的 JSON Schema
+func (b *schemaBuilder) objectSchema(t reflect.Type) spec.Schema {
+	s := new(spec.Schema).Typed("object", "")
+	b.addFields(s, t, make(map[reflect.Type]bool))
+	return *s
+}
+
+// addFields 添加结构体的字段，和 encoding/json 一样展开没有名称的嵌入结构体，
+// 外层的字段优先于嵌入结构体中的同名字段。
+func (b *schemaBuilder) addFields(s *spec.Schema, t reflect.Type, visited map[reflect.Type]bool) {
+
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+
+	var embedded []reflect.Type
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag, _ := f.Tag.Lookup("json")
+		if tag == "-" {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		name := opts[0]
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+
+		// 忽略私有字段
+		if f.PkgPath != "" {
+			continue
+		}
+
+		// 处理 XML 标签
+		var xmlTag []string
+		if v, ok := f.Tag.Lookup("xml"); ok {
+			xmlTag = strings.Split(v, ",")
+			if f.Type == xmlNameType {
+				s.WithXMLName(xmlTag[0])
+				continue
+			}
+		}
+
+		switch f.Type.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		prop := b.schema(f.Type)
+
+		omitEmpty := false
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "omitempty":
+				omitEmpty = true
+			case "string":
+				if prop.Type.Contains("integer") || prop.Type.Contains("number") || prop.Type.Contains("boolean") {
+					prop = *spec.StringProperty()
+				}
+			}
+		}
+
+		if len(xmlTag) > 0 && prop.Items != nil && prop.Items.Schema != nil {
+			prop.Items.Schema.WithXMLName(xmlTag[0])
+		}
+
+		if len(xmlTag) > 1 {
+			for _, v := range xmlTag[1:] {
+				if v == "wrapped" {
+					prop.AsWrappedXML()
+					break
+				}
+			}
+		}
+
+		if applyFieldTags(&prop, f, !omitEmpty) {
+			s.AddRequired(name)
+		}
+		s.SetProperty(name, prop)
+	}
+
+	for _, et := range embedded {
+		inner := new(spec.Schema)
+		b.addFields(inner, et, visited)
+		for name, prop := range inner.Properties {
+			if _, ok := s.Properties[name]; ok {
+				continue
+			}
+			s.SetProperty(name, prop)
+			for _, r := range inner.Required {
+				if r == name {
+					s.AddRequired(name)
+				}
+			}
+		}
+	}
+}
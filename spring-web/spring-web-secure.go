@@ -0,0 +1,178 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// CspNonceKey CSP nonce 在 WebContext 中的键
+const CspNonceKey = "@CspNonce"
+
+// CspNoncePlaceholder ContentSecurityPolicy 中 nonce 的占位符，每个请求替换成新的 nonce
+const CspNoncePlaceholder = "{nonce}"
+
+// SecureConfig 安全响应头的配置，字符串为空的响应头不输出
+type SecureConfig struct {
+
+	// ContentTypeNosniff X-Content-Type-Options 响应头
+	ContentTypeNosniff string
+
+	// XFrameOptions X-Frame-Options 响应头，DENY 或者 SAMEORIGIN
+	XFrameOptions string
+
+	// HSTSMaxAge Strict-Transport-Security 的 max-age 秒数，为 0 时不输出，
+	// 只对 HTTPS 请求输出
+	HSTSMaxAge int
+
+	// HSTSExcludeSubdomains 为 true 时不输出 includeSubDomains
+	HSTSExcludeSubdomains bool
+
+	// HSTSPreload 为 true 时输出 preload
+	HSTSPreload bool
+
+	// HSTSTrustForwardedProto 为 true 时根据 X-Forwarded-Proto 判断反向代理后面的
+	// 请求是否为 HTTPS，否则只根据 IsTLS 判断
+	HSTSTrustForwardedProto bool
+
+	// ContentSecurityPolicy Content-Security-Policy 响应头，包含 {nonce} 时每个请求
+	// 生成新的 nonce，处理函数可以通过 CspNonce 获取
+	ContentSecurityPolicy string
+
+	// CSPReportOnly 为 true 时使用 Content-Security-Policy-Report-Only 响应头
+	CSPReportOnly bool
+
+	// ReferrerPolicy Referrer-Policy 响应头
+	ReferrerPolicy string
+
+	// PermissionsPolicy Permissions-Policy 响应头
+	PermissionsPolicy string
+}
+
+// DefaultSecureConfig 安全响应头的默认配置，文档接口的 CSP 使用 DocConfig.ContentSecurityPolicy 替换
+var DefaultSecureConfig = SecureConfig{
+	ContentTypeNosniff:      "nosniff",
+	XFrameOptions:           "SAMEORIGIN",
+	HSTSMaxAge:              31536000,
+	HSTSTrustForwardedProto: true,
+	ContentSecurityPolicy:   "default-src 'self'; script-src 'self' 'nonce-{nonce}'; object-src 'none'; base-uri 'self'; frame-ancestors 'self'",
+	ReferrerPolicy:          "strict-origin-when-cross-origin",
+	PermissionsPolicy:       "camera=(), microphone=(), geolocation=()",
+}
+
+// secureFilter 安全响应头过滤器
+type secureFilter struct {
+	cfg  SecureConfig
+	hsts string
+}
+
+// SecureFilter 返回输出安全响应头的过滤器，通常使用 DefaultSecureConfig 作为全局过滤器，
+// 路由分组可以通过 Router.WithSecureHeaders 使用另外的配置，后面的过滤器覆盖前面的
+// 过滤器输出的所有安全响应头。
+func SecureFilter(cfg SecureConfig) Filter {
+
+	f := &secureFilter{cfg: cfg}
+
+	if cfg.HSTSMaxAge > 0 {
+		hsts := "max-age=" + strconv.Itoa(cfg.HSTSMaxAge)
+		if !cfg.HSTSExcludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+		f.hsts = hsts
+	}
+	return f
+}
+
+func (f *secureFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	h := ctx.ResponseWriter().Header()
+
+	set := func(key string, value string) {
+		if value != "" {
+			h.Set(key, value)
+		} else {
+			h.Del(key)
+		}
+	}
+
+	set(HeaderXContentTypeOptions, f.cfg.ContentTypeNosniff)
+	set(HeaderXFrameOptions, f.cfg.XFrameOptions)
+	set(HeaderReferrerPolicy, f.cfg.ReferrerPolicy)
+	set(HeaderPermissionsPolicy, f.cfg.PermissionsPolicy)
+
+	if f.isHTTPS(ctx) {
+		set(HeaderStrictTransportSecurity, f.hsts)
+	} else {
+		h.Del(HeaderStrictTransportSecurity)
+	}
+
+	// 覆盖前面的过滤器时也要覆盖它生成的 nonce
+	var nonce string
+	csp := f.cfg.ContentSecurityPolicy
+	if strings.Contains(csp, CspNoncePlaceholder) {
+		nonce = newCspNonce()
+		csp = strings.Replace(csp, CspNoncePlaceholder, nonce, -1)
+	}
+	ctx.Set(CspNonceKey, nonce)
+
+	h.Del(HeaderContentSecurityPolicy)
+	h.Del(HeaderContentSecurityPolicyReportOnly)
+	if f.cfg.CSPReportOnly {
+		set(HeaderContentSecurityPolicyReportOnly, csp)
+	} else {
+		set(HeaderContentSecurityPolicy, csp)
+	}
+
+	chain.Next(ctx)
+}
+
+// isHTTPS 请求是否为 HTTPS 请求
+func (f *secureFilter) isHTTPS(ctx WebContext) bool {
+	if ctx.IsTLS() {
+		return true
+	}
+	if !f.cfg.HSTSTrustForwardedProto {
+		return false
+	}
+	proto := ctx.Request().Header.Get(HeaderXForwardedProto)
+	if i := strings.IndexByte(proto, ','); i >= 0 {
+		proto = proto[:i]
+	}
+	return strings.EqualFold(strings.TrimSpace(proto), "https")
+}
+
+// newCspNonce 生成 CSP nonce
+func newCspNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// CspNonce 返回请求的 CSP nonce，用于 <script nonce="..."> 等标签，
+// 没有使用 SecureFilter 或者策略中没有 {nonce} 时返回空字符串
+func CspNonce(ctx WebContext) string {
+	nonce, _ := lookupValue(ctx, CspNonceKey).(string)
+	return nonce
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-spring/go-spring-parent/spring-logger"
+)
+
+// ServiceRoutes 服务对象实现该接口后可以覆盖方法的默认路由。键为方法名，值为
+// "METHOD path" 形式的路由，多个 HTTP 方法使用 | 分隔，例如 "GET /pet/:id"、
+// "PUT|PATCH /pet"，path 相对于注册服务时的 prefix；值为 "-" 时不注册该方法。
+type ServiceRoutes interface {
+	Routes() map[string]string
+}
+
+// SkippedMethod 注册服务时被跳过的方法
+type SkippedMethod struct {
+	Name   string // 方法名
+	Reason string // 跳过的原因
+}
+
+// ServiceRegistration 注册服务的结果
+type ServiceRegistration struct {
+	Mappers []*Mapper       // 注册的映射器，按照方法名排序
+	Skipped []SkippedMethod // 被跳过的方法，按照方法名排序
+}
+
+// registerFunc 注册处理函数的方法，WebMapping 和 Router 的 Request 方法
+type registerFunc func(method uint32, path string, fn interface{}, filters ...Filter) *Mapper
+
+// registerService 将服务对象所有 BIND 形式的导出方法注册为处理函数，默认使用
+// POST 方法和 prefix/MethodName 路径，跳过的方法会输出警告日志。
+func registerService(request registerFunc, prefix string, svc interface{}, filters []Filter) *ServiceRegistration {
+
+	if svc == nil {
+		panic(errors.New("service should not be nil"))
+	}
+
+	svcVal := reflect.ValueOf(svc)
+	svcTyp := svcVal.Type()
+
+	typeName := svcTyp.Name()
+	if svcTyp.Kind() == reflect.Ptr {
+		typeName = svcTyp.Elem().Name()
+	}
+
+	var routes map[string]string
+	if r, ok := svc.(ServiceRoutes); ok {
+		routes = r.Routes()
+	}
+
+	// Routes 中的方法必须存在，否则很可能是拼写错误
+	for name := range routes {
+		if _, ok := svcTyp.MethodByName(name); !ok {
+			panic(fmt.Errorf("route of %s.%s but method not found", typeName, name))
+		}
+	}
+
+	prefix = strings.TrimRight(prefix, "/")
+	result := new(ServiceRegistration)
+
+	for i := 0; i < svcTyp.NumMethod(); i++ {
+		method := svcTyp.Method(i)
+
+		if method.Name == "Routes" && routes != nil {
+			continue
+		}
+
+		route, ok := routes[method.Name]
+		if route == "-" {
+			result.Skipped = append(result.Skipped, SkippedMethod{
+				Name:   method.Name,
+				Reason: "excluded by Routes()",
+			})
+			continue
+		}
+
+		fn := svcVal.Method(i).Interface()
+		if _, _, valid := validBindFn(fn); !valid {
+			result.Skipped = append(result.Skipped, SkippedMethod{
+				Name:   method.Name,
+				Reason: "signature " + reflect.TypeOf(fn).String() + " isn't BIND-compatible",
+			})
+			continue
+		}
+
+		httpMethod, path := uint32(MethodPost), "/"+method.Name
+		if ok {
+			var err error
+			if httpMethod, path, err = parseServiceRoute(route); err != nil {
+				panic(fmt.Errorf("route of %s.%s error: %v", typeName, method.Name, err))
+			}
+		}
+
+		h := newBindHandler(fn)
+		h.src = method.Func.Interface()
+
+		m := request(httpMethod, prefix+path, h, filters...)
+		m.Swagger(typeName + "." + method.Name).
+			WithSummary(method.Name).
+			WithTags(typeName)
+		result.Mappers = append(result.Mappers, m)
+	}
+
+	sort.Slice(result.Skipped, func(i, j int) bool {
+		return result.Skipped[i].Name < result.Skipped[j].Name
+	})
+
+	for _, s := range result.Skipped {
+		SpringLogger.Warnf("skip method %s.%s: %s", typeName, s.Name, s.Reason)
+	}
+	return result
+}
+
+// parseServiceRoute 解析 "METHOD path" 形式的路由
+func parseServiceRoute(route string) (uint32, string, error) {
+
+	ss := strings.Fields(route)
+	if len(ss) != 2 {
+		return 0, "", fmt.Errorf("route %q should be \"METHOD path\"", route)
+	}
+
+	var method uint32
+	for _, s := range strings.Split(ss[0], "|") {
+		found := false
+		for k, v := range methods {
+			if strings.EqualFold(s, v) {
+				method |= k
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, "", fmt.Errorf("unsupported method %s", s)
+		}
+	}
+
+	path := ss[1]
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return method, path, nil
+}
@@ -0,0 +1,486 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// maxRecordedResponse 校验响应时最多缓存的响应内容，超过之后不再校验
+const maxRecordedResponse = 1 << 20
+
+// defaultMaxValidatedBody 校验请求时默认最多读取的请求体大小
+const defaultMaxValidatedBody = 10 << 20
+
+// SpecValidation 使用容器生成的 Swagger 文档校验请求和响应的配置
+type SpecValidation struct {
+	Request  bool // 校验请求参数、请求体以及 Content-Type，不一致时返回 400
+	Response bool // 校验响应体，不一致时只记录日志
+
+	// MaxBodySize 校验请求时最多读取的请求体字节数，超出时返回 413，默认 10MB。
+	// 请求体需要完整读入内存才能校验，BodyLimitFilter 的限制更小时以它为准。
+	MaxBodySize int64
+
+	// OnResponseDrift 响应和文档不一致时的回调，默认打印 WARN 日志
+	OnResponseDrift func(ctx WebContext, err *SpecValidationError)
+}
+
+// SpecValidationError 请求和文档不一致时返回的错误内容
+type SpecValidationError struct {
+	Message    string          `json:"message"`
+	Violations []SpecViolation `json:"violations"`
+}
+
+func (e *SpecValidationError) Error() string {
+	var s []string
+	for _, v := range e.Violations {
+		s = append(s, v.String())
+	}
+	return e.Message + ": " + strings.Join(s, "; ")
+}
+
+// specValidationFilter 使用 Operation 校验请求和响应的过滤器
+type specValidationFilter struct {
+	cfg SpecValidation
+	doc *swagger
+	op  *spec.Operation
+}
+
+// SpecValidationFilter 返回使用 mapper 的文档校验请求和响应的过滤器，
+// 没有开启校验或者 mapper 没有文档时返回 nil。
+func (c *BaseWebContainer) SpecValidationFilter(mapper *Mapper) Filter {
+
+	if !c.specValidation.Request && !c.specValidation.Response {
+		return nil
+	}
+
+	c.BuildDocs()
+
+	d, ok := c.mapperDocs[mapper]
+	if !ok || mapper.swagger == nil {
+		return nil
+	}
+	return &specValidationFilter{cfg: c.specValidation, doc: d, op: mapper.swagger.operation}
+}
+
+func (f *specValidationFilter) Invoke(ctx WebContext, chain FilterChain) {
+
+	if f.cfg.Request {
+		body, err := f.readBody(ctx.Request())
+		if err == ErrBodyTooLarge {
+			writeBodyTooLarge(ctx.ResponseWriter())
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, &SpecValidationError{
+				Message:    "request does not match the API spec",
+				Violations: []SpecViolation{{In: "body", Message: err.Error()}},
+			})
+			return
+		}
+		if violations := f.validateRequest(ctx, body); len(violations) > 0 {
+			ctx.JSON(http.StatusBadRequest, &SpecValidationError{
+				Message:    "request does not match the API spec",
+				Violations: violations,
+			})
+			return
+		}
+	}
+
+	if !f.cfg.Response {
+		chain.Next(ctx)
+		return
+	}
+
+	w := ctx.ResponseWriter()
+	rw := &recordWriter{ResponseWriter: w, status: http.StatusOK}
+	ctx.SetResponseWriter(rw)
+	defer ctx.SetResponseWriter(w)
+
+	chain.Next(ctx)
+
+	if violations := f.validateResponse(rw); len(violations) > 0 {
+		err := &SpecValidationError{
+			Message:    "response does not match the API spec",
+			Violations: violations,
+		}
+		if f.cfg.OnResponseDrift != nil {
+			f.cfg.OnResponseDrift(ctx, err)
+			return
+		}
+		r := ctx.Request()
+		ctx.LogWarnf("%s %s: %s", r.Method, r.URL.Path, err.Error())
+	}
+}
+
+// consumes 返回请求可以使用的 Content-Type
+func (f *specValidationFilter) consumes() []string {
+	if len(f.op.Consumes) > 0 {
+		return f.op.Consumes
+	}
+	return f.doc.Consumes
+}
+
+// readBody 读取请求体并重新放回 Request 中，超出 MaxBodySize 时返回 ErrBodyTooLarge
+func (f *specValidationFilter) readBody(r *http.Request) ([]byte, error) {
+
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	limit := f.cfg.MaxBodySize
+	if limit <= 0 {
+		limit = defaultMaxValidatedBody
+	}
+
+	if r.ContentLength > limit {
+		return nil, ErrBodyTooLarge
+	}
+
+	// 多读一个字节用于判断是否超出限制
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, ErrBodyTooLarge
+	}
+
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+// validateRequest 使用已经读取的请求体校验请求
+func (f *specValidationFilter) validateRequest(ctx WebContext, body []byte) []SpecViolation {
+
+	var violations []SpecViolation
+
+	r := ctx.Request()
+
+	mediaType := ""
+	if ct := r.Header.Get(HeaderContentType); ct != "" {
+		mediaType, _, _ = mime.ParseMediaType(ct)
+	}
+
+	if len(body) > 0 {
+		if consumes := f.consumes(); len(consumes) > 0 && !matchMediaType(consumes, mediaType) {
+			violations = append(violations, SpecViolation{
+				In:      "header",
+				Name:    HeaderContentType,
+				Message: "must be one of " + strings.Join(consumes, ", "),
+			})
+			return violations
+		}
+	}
+
+	var form *requestForm
+	for _, p := range f.op.Parameters {
+		if p.In == "formData" {
+			form = parseRequestForm(r, mediaType, body)
+			break
+		}
+	}
+
+	for _, p := range f.op.Parameters {
+		switch p.In {
+		case "body":
+			violations = append(violations, f.validateBody(&p, mediaType, body)...)
+		case "formData":
+			if p.Type == "file" {
+				if p.Required && !form.hasFile(p.Name) {
+					violations = append(violations, SpecViolation{In: p.In, Name: p.Name, Message: "is required"})
+				}
+				continue
+			}
+			violations = append(violations, f.validateParam(&p, form.values[p.Name])...)
+		case "path":
+			var values []string
+			if v := ctx.PathParam(p.Name); v != "" {
+				values = []string{v}
+			}
+			violations = append(violations, f.validateParam(&p, values)...)
+		case "query":
+			violations = append(violations, f.validateParam(&p, r.URL.Query()[p.Name])...)
+		case "header":
+			violations = append(violations, f.validateParam(&p, r.Header[http.CanonicalHeaderKey(p.Name)])...)
+		}
+	}
+	return violations
+}
+
+// validateBody 校验 JSON 格式的请求体
+func (f *specValidationFilter) validateBody(p *spec.Parameter, mediaType string, body []byte) []SpecViolation {
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if p.Required {
+			return []SpecViolation{{In: "body", Message: "is required"}}
+		}
+		return nil
+	}
+
+	if p.Schema == nil || !isJSONMediaType(mediaType) {
+		return nil
+	}
+
+	x, err := decodeJSON(body)
+	if err != nil {
+		return []SpecViolation{{In: "body", Message: "invalid JSON: " + err.Error()}}
+	}
+	return newSchemaValidator(f.doc.Definitions, "body").Validate("", p.Schema, x)
+}
+
+// validateParam 校验 path、query、header 以及 formData 参数
+func (f *specValidationFilter) validateParam(p *spec.Parameter, values []string) []SpecViolation {
+
+	if len(values) == 0 {
+		if p.Required {
+			return []SpecViolation{{In: p.In, Name: p.Name, Message: "is required"}}
+		}
+		return nil
+	}
+
+	s := paramSchema(p)
+	var x interface{}
+	if p.Type == "array" {
+		x = splitParam(p.Items, p.CollectionFormat, values)
+	} else {
+		x = convertParam(p.Type, values[0])
+	}
+	return newSchemaValidator(f.doc.Definitions, p.In).Validate(p.Name, s, x)
+}
+
+// validateResponse 校验 JSON 格式的响应体
+func (f *specValidationFilter) validateResponse(rw *recordWriter) []SpecViolation {
+
+	if rw.overflow || f.op.Responses == nil {
+		return nil
+	}
+
+	resp, ok := f.op.Responses.StatusCodeResponses[rw.status]
+	if !ok {
+		if f.op.Responses.Default == nil {
+			return []SpecViolation{{In: "response", Message: "undocumented status " + strconv.Itoa(rw.status)}}
+		}
+		resp = *f.op.Responses.Default
+	}
+
+	if resp.Schema == nil || rw.body.Len() == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(rw.Header().Get(HeaderContentType))
+	if !isJSONMediaType(mediaType) {
+		return nil
+	}
+
+	x, err := decodeJSON(rw.body.Bytes())
+	if err != nil {
+		return []SpecViolation{{In: "response", Message: "invalid JSON: " + err.Error()}}
+	}
+	return newSchemaValidator(f.doc.Definitions, "response").Validate("", resp.Schema, x)
+}
+
+// decodeJSON 解码 JSON 内容，数字使用 json.Number 表示
+func decodeJSON(b []byte) (interface{}, error) {
+	var x interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&x); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// isJSONMediaType 是否 JSON 格式的 Content-Type
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// matchMediaType mediaType 是否在 Content-Type 列表中
+func matchMediaType(list []string, mediaType string) bool {
+	for _, s := range list {
+		if t, _, err := mime.ParseMediaType(s); err == nil && t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// paramSchema 将非 body 参数的类型和约束转换成 Schema
+func paramSchema(p *spec.Parameter) *spec.Schema {
+	s := simpleSchema(p.SimpleSchema, p.CommonValidations)
+	if p.Items != nil {
+		s.Items = &spec.SchemaOrArray{Schema: itemsSchema(p.Items)}
+	}
+	return s
+}
+
+// itemsSchema 将数组参数的元素类型转换成 Schema
+func itemsSchema(items *spec.Items) *spec.Schema {
+	s := simpleSchema(items.SimpleSchema, items.CommonValidations)
+	if items.Items != nil {
+		s.Items = &spec.SchemaOrArray{Schema: itemsSchema(items.Items)}
+	}
+	return s
+}
+
+func simpleSchema(ss spec.SimpleSchema, cv spec.CommonValidations) *spec.Schema {
+	s := &spec.Schema{}
+	if ss.Type != "" {
+		s.Type = spec.StringOrArray{ss.Type}
+	}
+	s.Format = ss.Format
+	s.Maximum = cv.Maximum
+	s.ExclusiveMaximum = cv.ExclusiveMaximum
+	s.Minimum = cv.Minimum
+	s.ExclusiveMinimum = cv.ExclusiveMinimum
+	s.MaxLength = cv.MaxLength
+	s.MinLength = cv.MinLength
+	s.Pattern = cv.Pattern
+	s.MaxItems = cv.MaxItems
+	s.MinItems = cv.MinItems
+	s.Enum = cv.Enum
+	return s
+}
+
+// splitParam 按照 collectionFormat 拆分数组参数
+func splitParam(items *spec.Items, collectionFormat string, values []string) []interface{} {
+
+	if collectionFormat != "multi" {
+		sep := ","
+		switch collectionFormat {
+		case "ssv":
+			sep = " "
+		case "tsv":
+			sep = "\t"
+		case "pipes":
+			sep = "|"
+		}
+		values = strings.Split(values[0], sep)
+	}
+
+	itemType := ""
+	if items != nil {
+		itemType = items.Type
+	}
+
+	r := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		r = append(r, convertParam(itemType, v))
+	}
+	return r
+}
+
+// convertParam 将字符串参数转换成 JSON 类型的值，无法转换时保留字符串以便报告类型错误
+func convertParam(typ string, v string) interface{} {
+	switch typ {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// requestForm 请求的表单内容
+type requestForm struct {
+	values url.Values
+	files  map[string]bool
+}
+
+func (f *requestForm) hasFile(name string) bool {
+	return f.files[name]
+}
+
+// parseRequestForm 使用请求体的副本解析表单，不影响后续处理函数读取请求体
+func parseRequestForm(r *http.Request, mediaType string, body []byte) *requestForm {
+
+	form := &requestForm{values: url.Values{}, files: map[string]bool{}}
+
+	switch mediaType {
+	case MIMEApplicationForm:
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			form.values = values
+		}
+	case MIMEMultipartForm:
+		req := *r
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.Form, req.PostForm, req.MultipartForm = nil, nil, nil
+		if err := req.ParseMultipartForm(32 << 20); err == nil {
+			for name, values := range req.MultipartForm.Value {
+				form.values[name] = values
+			}
+			for name := range req.MultipartForm.File {
+				form.files[name] = true
+			}
+			req.MultipartForm.RemoveAll()
+		}
+	}
+	return form
+}
+
+// recordWriter 记录响应状态码和响应内容的 http.ResponseWriter
+type recordWriter struct {
+	http.ResponseWriter
+
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (w *recordWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if !w.overflow {
+		if w.body.Len()+len(b) > maxRecordedResponse {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
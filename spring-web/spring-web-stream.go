@@ -0,0 +1,281 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// StreamIterator 流式返回值的迭代器，RPC 和 BIND 形式的处理函数可以返回它、
+// 接收方向的 chan 或者 io.Reader，框架会根据 Accept 头以 SSE、NDJSON 或者
+// chunked 的形式逐个写出元素。每写出一个元素并 Flush 之后才会读取下一个元素，
+// 因此生产者的速度受限于客户端的接收速度。客户端断开连接后请求的 Context 会被
+// 取消，迭代随之结束，使用 chan 的生产者也应该监听该 Context 以便及时退出。
+type StreamIterator interface {
+	// Next 返回下一个元素，没有更多元素时返回 io.EOF，返回其他错误时写出错误帧并结束
+	Next(ctx context.Context) (interface{}, error)
+}
+
+// StreamReader 为原样写出的数据流指定内容类型。实现了 io.Reader 的返回值 (例如
+// *os.File、*bytes.Buffer) 都会以 chunked 的形式原样写出，内容类型为
+// application/octet-stream，需要其他内容类型时使用 *StreamReader 封装。
+// Reader 实现了 io.Closer 时写出之后关闭。
+type StreamReader struct {
+	Reader      io.Reader
+	ContentType string // 为空时使用 application/octet-stream
+}
+
+// NewStreamReader StreamReader 的构造函数
+func NewStreamReader(r io.Reader, contentType string) *StreamReader {
+	return &StreamReader{Reader: r, ContentType: contentType}
+}
+
+// StreamIteratorFunc 函数形式的 StreamIterator
+type StreamIteratorFunc func(ctx context.Context) (interface{}, error)
+
+func (f StreamIteratorFunc) Next(ctx context.Context) (interface{}, error) {
+	return f(ctx)
+}
+
+// streamWriter 流式响应的帧格式
+type streamWriter interface {
+	contentType() string
+	writeData(w io.Writer, data []byte) error
+	writeError(w io.Writer, data []byte) error
+}
+
+// ndjsonWriter 每行一个 JSON 对象
+type ndjsonWriter struct {
+	mime string
+}
+
+func (n *ndjsonWriter) contentType() string {
+	return n.mime
+}
+
+func (n *ndjsonWriter) writeData(w io.Writer, data []byte) error {
+	_, err := w.Write(append(data, '\n'))
+	return err
+}
+
+func (n *ndjsonWriter) writeError(w io.Writer, data []byte) error {
+	return n.writeData(w, data)
+}
+
+// sseWriter Server-Sent Events 格式
+type sseWriter struct{}
+
+func (s *sseWriter) contentType() string {
+	return MIMETextEventStream
+}
+
+func (s *sseWriter) writeData(w io.Writer, data []byte) error {
+	return WriteSSEvent(w, "message", data)
+}
+
+func (s *sseWriter) writeError(w io.Writer, data []byte) error {
+	return WriteSSEvent(w, "error", data)
+}
+
+// WriteSSEvent 写出一个 Server-Sent Event，message 为 []byte 和 string 时原样写出，
+// 其他类型使用 JSON 编码。
+func WriteSSEvent(w io.Writer, name string, message interface{}) error {
+
+	var data string
+	switch v := message.(type) {
+	case []byte:
+		data = string(v)
+	case string:
+		data = v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(b)
+	}
+
+	var sb strings.Builder
+	if name != "" {
+		sb.WriteString("event: " + name + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// negotiateStream 根据 Accept 头选择帧格式，默认使用 NDJSON
+func negotiateStream(accept string) streamWriter {
+	for _, s := range strings.Split(accept, ",") {
+		mime := strings.TrimSpace(strings.Split(s, ";")[0])
+		switch mime {
+		case MIMETextEventStream:
+			return &sseWriter{}
+		case MIMEApplicationNDJSON, MIMEJsonStream:
+			return &ndjsonWriter{mime: mime}
+		}
+	}
+	return &ndjsonWriter{mime: MIMEJsonStream}
+}
+
+// isStreamResult 返回值是否需要以流的形式写出
+func isStreamResult(v interface{}) bool {
+	switch v.(type) {
+	case StreamIterator, *StreamReader, io.Reader:
+		return true
+	}
+	if v == nil {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	return t.Kind() == reflect.Chan && t.ChanDir()&reflect.RecvDir != 0
+}
+
+// streamInvoke 将流式返回值写出到响应中
+func streamInvoke(webCtx WebContext, v interface{}) {
+
+	w := webCtx.ResponseWriter()
+	flusher, _ := w.(http.Flusher)
+
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	reqCtx := webCtx.Request().Context()
+
+	// io.Reader 以 chunked 的形式原样写出，*StreamReader 可以指定内容类型
+	var (
+		r           io.Reader
+		contentType string
+	)
+	switch x := v.(type) {
+	case *StreamReader:
+		r, contentType = x.Reader, x.ContentType
+	case StreamIterator: // 实现了 io.Reader 的迭代器仍然逐个写出元素
+	case io.Reader:
+		r = x
+	}
+
+	if r != nil {
+		if c, ok := r.(io.Closer); ok {
+			defer c.Close()
+		}
+		if contentType == "" {
+			contentType = MIMEOctetStream
+		}
+		webCtx.Header(HeaderContentType, contentType)
+		webCtx.Status(http.StatusOK)
+		buf := make([]byte, 32*1024)
+		for reqCtx.Err() == nil {
+			n, err := r.Read(buf)
+			if n > 0 {
+				if _, werr := w.Write(buf[:n]); werr != nil {
+					return
+				}
+				flush()
+			}
+			if err != nil {
+				if err != io.EOF {
+					webCtx.LogError("stream read error: ", err)
+				}
+				return
+			}
+		}
+		return
+	}
+
+	sw := negotiateStream(webCtx.GetHeader("Accept"))
+	webCtx.Header(HeaderContentType, sw.contentType())
+	webCtx.Header("Cache-Control", "no-cache")
+	webCtx.Status(http.StatusOK)
+	flush()
+
+	wrapper := GetResultWrapper(webCtx)
+
+	writeError := func(r interface{}) {
+		b, _ := json.Marshal(wrapper.Failure(webCtx, resolveFailure(webCtx, r)))
+		_ = sw.writeError(w, b)
+		flush()
+	}
+
+	writeData := func(item interface{}) bool {
+		if err, ok := item.(error); ok {
+			writeError(err)
+			return false
+		}
+		b, err := json.Marshal(wrapper.Success(webCtx, item))
+		if err != nil {
+			writeError(err)
+			return false
+		}
+		if err = sw.writeData(w, b); err != nil {
+			return false // 客户端已经断开
+		}
+		flush()
+		return true
+	}
+
+	// 流已经开始之后不能再写出普通的 RPC 结果，panic 转换成错误帧
+	defer func() {
+		if r := recover(); r != nil {
+			webCtx.LogError("[STREAM PANIC] ", r)
+			writeError(r)
+		}
+	}()
+
+	if it, ok := v.(StreamIterator); ok {
+		for reqCtx.Err() == nil {
+			item, err := it.Next(reqCtx)
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				writeError(err)
+				return
+			}
+			if !writeData(item) {
+				return
+			}
+		}
+		return
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(reqCtx.Done())},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(v)},
+	}
+	for {
+		chosen, item, ok := reflect.Select(cases)
+		if chosen == 0 || !ok { // 客户端断开或者 chan 已关闭
+			return
+		}
+		if !writeData(item.Interface()) {
+			return
+		}
+	}
+}
@@ -0,0 +1,276 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-openapi/spec"
+)
+
+// deriveOperations 为 BIND 形式的处理函数生成 Operation，根据处理函数的签名补全
+// 没有显式设置的操作 ID、标签、请求参数和响应，显式设置的内容总是优先。
+func (s *swagger) deriveOperations(mappers []*Mapper) {
+
+	ids := make(map[string]bool)
+	for _, m := range mappers {
+		if m.swagger != nil && m.swagger.operation.ID != "" {
+			ids[m.swagger.operation.ID] = true
+		}
+	}
+
+	b := s.schemaBuilder()
+
+	for _, m := range mappers {
+		h, ok := m.handler.(*bindHandler)
+		if !ok {
+			continue
+		}
+		if m.swagger == nil {
+			m.swagger = NewOperation("")
+		}
+		s.deriveOperation(b, m, h, ids)
+	}
+}
+
+// deriveOperation 根据 BIND 处理函数的签名补全一个 Operation
+func (s *swagger) deriveOperation(b *schemaBuilder, m *Mapper, h *bindHandler, ids map[string]bool) {
+
+	op := m.swagger.operation
+
+	if op.ID == "" {
+		op.ID = uniqueName(bindOperationID(m, h), ids)
+	}
+
+	if len(op.Tags) == 0 && m.router != nil {
+		if tag := strings.Trim(m.router.basePath, "/"); tag != "" {
+			op.Tags = []string{tag}
+		}
+	}
+
+	if len(op.Parameters) == 0 && m.swagger.bindParam == nil {
+		s.deriveParams(b, m, h)
+	}
+
+	if !hasSuccessResponse(op) {
+		s.deriveResponse(b, m, h)
+	}
+}
+
+// hasSuccessResponse 是否声明了默认响应或者 2xx 响应，只声明了错误响应时仍然生成成功响应
+func hasSuccessResponse(op *spec.Operation) bool {
+	if op.Responses == nil {
+		return false
+	}
+	if op.Responses.Default != nil {
+		return true
+	}
+	for code := range op.Responses.StatusCodeResponses {
+		if code >= 200 && code < 300 {
+			return true
+		}
+	}
+	return false
+}
+
+// bindOperationID 使用处理函数的名称作为操作 ID，闭包等无法使用时根据方法和路径生成
+func bindOperationID(m *Mapper, h *bindHandler) string {
+
+	_, _, fnName := h.FileLine()
+	if i := strings.LastIndex(fnName, "."); i >= 0 {
+		fnName = fnName[i+1:]
+	}
+
+	if !isExportedIdent(fnName) {
+		method := "request"
+		for _, v := range clientMethodOrder {
+			if m.method&v == v {
+				method = strings.ToLower(methods[v])
+				break
+			}
+		}
+		return method + exportedName(m.path)
+	}
+
+	r := []rune(fnName)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// deriveParams 生成请求参数，GET、DELETE 和 HEAD 请求的字段作为查询参数，
+// 其他请求使用 JSON 请求体，和路径参数同名的字段作为路径参数。
+func (s *swagger) deriveParams(b *schemaBuilder, m *Mapper, h *bindHandler) {
+
+	op := m.swagger.operation
+
+	t := h.bindType
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var pathParams []string
+	if strings.ContainsAny(m.path, ":{") {
+		path, _ := ToPathStyle(m.path, EchoPathStyle)
+		for _, seg := range strings.Split(path, "/") {
+			if strings.HasPrefix(seg, ":") {
+				pathParams = append(pathParams, seg[1:])
+			}
+		}
+	}
+
+	query := m.method&(MethodPost|MethodPut|MethodPatch) == 0
+
+	var fields []*clientField
+	if t.Kind() == reflect.Struct {
+		appendClientFields(reflect.New(t).Elem(), &fields)
+	}
+
+	used := make(map[string]bool)
+	for _, name := range pathParams {
+		param := PathParam(name, "string", "")
+		for _, f := range fields {
+			if f.param == name || f.json == name || strings.EqualFold(f.name, name) {
+				if ft, ok := t.FieldByName(f.name); ok {
+					typeParam(param, ft.Type)
+					applyParamTags(param, ft)
+				}
+				used[f.name] = true
+				break
+			}
+		}
+		op.AddParam(param)
+	}
+
+	if !query {
+		schema := b.schema(h.bindType)
+		op.AddParam(BodyParam("body", &schema).AsRequired())
+		if len(op.Consumes) == 0 {
+			op.WithConsumes(MIMEApplicationJSON)
+		}
+		return
+	}
+
+	for _, f := range fields {
+		if used[f.name] {
+			continue
+		}
+		ft, ok := t.FieldByName(f.name)
+		if !ok {
+			continue
+		}
+		param := spec.QueryParam(f.query)
+		typeParam(param, ft.Type)
+		applyParamTags(param, ft)
+		op.AddParam(param)
+	}
+}
+
+// typeParam 设置非 body 参数的类型
+func typeParam(param *spec.Parameter, t reflect.Type) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		typ, format := simpleType(t.Elem())
+		param.CollectionOf(spec.NewItems().Typed(typ, format), "multi")
+		return
+	}
+
+	typ, format := simpleType(t)
+	param.Typed(typ, format)
+}
+
+// simpleType 返回查询参数等简单类型对应的 Swagger 类型
+func simpleType(t reflect.Type) (string, string) {
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return "string", "date-time"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", ""
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return "integer", "int64"
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "integer", "int32"
+	case reflect.Float32:
+		return "number", "float"
+	case reflect.Float64:
+		return "number", "double"
+	}
+	return "string", ""
+}
+
+// streamIteratorType StreamIterator 的反射类型
+var streamIteratorType = reflect.TypeOf((*StreamIterator)(nil)).Elem()
+
+// streamReaderType *StreamReader 的反射类型
+var streamReaderType = reflect.TypeOf((*StreamReader)(nil))
+
+// readerType io.Reader 的反射类型
+var readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+
+// deriveResponse 生成使用 RpcResult 封装的成功响应，流式返回值使用对应的内容类型
+func (s *swagger) deriveResponse(b *schemaBuilder, m *Mapper, h *bindHandler) {
+
+	op := m.swagger.operation
+	out := h.fnVal.Type().Out(0)
+	resp := NewResponse(http.StatusText(http.StatusOK))
+
+	var produces []string
+	switch {
+	case out.Implements(streamIteratorType):
+		resp.WithSchema(rpcResultSchema(spec.Schema{}))
+		produces = []string{MIMEJsonStream, MIMEApplicationNDJSON, MIMETextEventStream}
+	case out == streamReaderType, out.Implements(readerType):
+		resp.WithSchema(new(spec.Schema).Typed("file", ""))
+		produces = []string{MIMEOctetStream}
+	case out.Kind() == reflect.Chan:
+		resp.WithSchema(rpcResultSchema(b.schema(out.Elem())))
+		produces = []string{MIMEJsonStream, MIMEApplicationNDJSON, MIMETextEventStream}
+	default:
+		resp.WithSchema(rpcResultSchema(b.schema(out)))
+		produces = []string{MIMEApplicationJSON}
+	}
+
+	op.RespondsWith(http.StatusOK, resp)
+	if len(op.Produces) == 0 {
+		op.WithProduces(produces...)
+	}
+}
+
+// rpcResultSchema 返回使用 RpcResult 封装 data 之后的 Schema
+func rpcResultSchema(data spec.Schema) *spec.Schema {
+	return new(spec.Schema).Typed("object", "").
+		SetProperty("Code", *spec.Int32Property()).
+		SetProperty("Msg", *spec.StringProperty()).
+		SetProperty("Err", *spec.StringProperty()).
+		SetProperty("Data", data).
+		WithRequired("Code", "Msg")
+}
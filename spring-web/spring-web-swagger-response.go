@@ -0,0 +1,225 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package SpringWeb
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// GenericSchema 泛型封装类型，例如 RpcResult<Pet>，每种数据类型生成一个
+// 名为 RpcResult-Pet 的定义，Build 根据数据的 Schema 生成封装之后的 Schema。
+type GenericSchema struct {
+	Name  string
+	Build func(data spec.Schema) spec.Schema
+}
+
+// genericValue 使用 GenericSchema 封装的数据类型
+type genericValue struct {
+	g    *GenericSchema
+	data interface{}
+}
+
+// Of 返回使用 g 封装 data 类型的响应类型，用于 Operation.Responds
+func (g *GenericSchema) Of(data interface{}) interface{} {
+	return &genericValue{g: g, data: data}
+}
+
+// RpcResultSchema SpringError.RpcResult 的泛型定义
+var RpcResultSchema = &GenericSchema{
+	Name: "RpcResult",
+	Build: func(data spec.Schema) spec.Schema {
+		return *rpcResultSchema(data)
+	},
+}
+
+// RpcResultOf 返回 RpcResult<data> 的响应类型
+func RpcResultOf(data interface{}) interface{} {
+	return RpcResultSchema.Of(data)
+}
+
+// response 返回 code 对应的响应，不存在时使用状态码的描述创建，code 为 0 时表示默认响应
+func (o *Operation) response(code int) spec.Response {
+
+	if o.operation.Responses == nil {
+		o.operation.Responses = new(spec.Responses)
+	}
+
+	if code == 0 {
+		if r := o.operation.Responses.Default; r != nil {
+			return *r
+		}
+		return *NewResponse("default response")
+	}
+
+	if r, ok := o.operation.Responses.StatusCodeResponses[code]; ok {
+		return r
+	}
+	return *NewResponse(http.StatusText(code))
+}
+
+// setResponse 设置 code 对应的响应，code 为 0 时表示默认响应
+func (o *Operation) setResponse(code int, r spec.Response) {
+	if code == 0 {
+		o.operation.Responses.Default = &r
+		return
+	}
+	if o.operation.Responses.StatusCodeResponses == nil {
+		o.operation.Responses.StatusCodeResponses = make(map[int]spec.Response)
+	}
+	o.operation.Responses.StatusCodeResponses[code] = r
+}
+
+// Responds 声明 code 的响应类型，i 可以是任意值、reflect.Type、spec.Schema 或者
+// GenericSchema.Of 的返回值，类型引用的定义在添加到文档时自动注册。同一个状态码
+// 多次调用时使用 oneOf 组合所有的类型，code 为 0 时表示默认响应。
+func (o *Operation) Responds(code int, i interface{}) *Operation {
+	r := o.response(code)
+	o.setResponse(code, r)
+	if i != nil {
+		if o.responds == nil {
+			o.responds = make(map[int][]interface{})
+		}
+		o.responds[code] = append(o.responds[code], i)
+	}
+	return o
+}
+
+// RespondsFile 声明 code 的响应为文件或者二进制内容，默认使用 application/octet-stream
+func (o *Operation) RespondsFile(code int, mediaTypes ...string) *Operation {
+
+	if len(mediaTypes) == 0 {
+		mediaTypes = []string{MIMEOctetStream}
+	}
+
+	for _, mt := range mediaTypes {
+		found := false
+		for _, p := range o.operation.Produces {
+			if p == mt {
+				found = true
+				break
+			}
+		}
+		if !found {
+			o.operation.Produces = append(o.operation.Produces, mt)
+		}
+	}
+
+	r := o.response(code)
+	r.WithSchema(new(spec.Schema).Typed("file", ""))
+	r.AddHeader(HeaderContentDisposition, spec.ResponseHeader().Typed("string", "").
+		WithDescription("attachment; filename=..."))
+	o.setResponse(code, r)
+	delete(o.responds, code)
+	return o
+}
+
+// WithResponseHeader 声明 code 的响应头，typ 为 string、integer、number 或者 boolean
+func (o *Operation) WithResponseHeader(code int, name string, typ string, description string) *Operation {
+	r := o.response(code)
+	r.AddHeader(name, spec.ResponseHeader().Typed(typ, "").WithDescription(description))
+	o.setResponse(code, r)
+	return o
+}
+
+// WithResponseExample 添加 code 的命名示例，命名示例保存在 x-examples 扩展中，
+// 第一个示例同时作为 application/json 的示例，name 为空时只设置 application/json 的示例。
+func (o *Operation) WithResponseExample(code int, name string, example interface{}) *Operation {
+
+	r := o.response(code)
+
+	if name != "" {
+		named, _ := r.Extensions["x-examples"].(map[string]interface{})
+		if named == nil {
+			named = make(map[string]interface{})
+		}
+		named[name] = example
+		r.AddExtension("x-examples", named)
+	}
+
+	if _, ok := r.Examples[MIMEApplicationJSON]; !ok || name == "" {
+		r.AddExample(MIMEApplicationJSON, example)
+	}
+
+	o.setResponse(code, r)
+	return o
+}
+
+// parseResponses 生成 Responds 声明的响应 Schema，并向 b 注册引用到的定义
+func (o *Operation) parseResponses(b *schemaBuilder) {
+
+	codes := make([]int, 0, len(o.responds))
+	for code := range o.responds {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		var schemas []spec.Schema
+		for _, i := range o.responds[code] {
+			schemas = append(schemas, responseSchema(b, i))
+		}
+		r := o.response(code)
+		if len(schemas) == 1 {
+			r.WithSchema(&schemas[0])
+		} else {
+			r.WithSchema(&spec.Schema{SchemaProps: spec.SchemaProps{OneOf: schemas}})
+		}
+		o.setResponse(code, r)
+	}
+}
+
+// responseSchema 返回响应类型的 Schema
+func responseSchema(b *schemaBuilder, i interface{}) spec.Schema {
+	switch v := i.(type) {
+	case spec.Schema:
+		return v
+	case *spec.Schema:
+		return *v
+	case reflect.Type:
+		return b.schema(v)
+	case *genericValue:
+		var data spec.Schema
+		if v.data != nil {
+			data = responseSchema(b, v.data)
+		}
+		name := v.g.Name + "-" + schemaTitle(data)
+		if _, ok := b.defs[name]; !ok {
+			b.defs[name] = v.g.Build(data)
+		}
+		return *spec.RefSchema(b.refPrefix + name)
+	}
+	return b.schema(reflect.TypeOf(i))
+}
+
+// schemaTitle 返回 Schema 在泛型定义名称中使用的名称，例如 Pet、List-Pet、string
+func schemaTitle(s spec.Schema) string {
+	if ref := s.Ref.String(); ref != "" {
+		return ref[strings.LastIndex(ref, "/")+1:]
+	}
+	if s.Type.Contains("array") && s.Items != nil && s.Items.Schema != nil {
+		return "List-" + schemaTitle(*s.Items.Schema)
+	}
+	if len(s.Type) > 0 {
+		return s.Type[0]
+	}
+	return "object"
+}
@@ -17,12 +17,9 @@
 package SpringWeb
 
 import (
-	"encoding/xml"
-	"fmt"
 	"net/http"
 	"reflect"
 	"strings"
-	"time"
 
 	"github.com/go-openapi/spec"
 	"github.com/swaggo/swag"
@@ -35,7 +32,7 @@ func init() {
 	swag.Register(swag.Name, doc)
 }
 
-// Swagger 返回全局的 swagger 对象
+// Swagger 返回全局的 swagger 对象，没有单独配置文档的容器以它为模板
 func Swagger() *swagger {
 	return doc
 }
@@ -43,6 +40,7 @@ func Swagger() *swagger {
 // swagger 封装 spec.Swagger 对象，提供流式调用
 type swagger struct {
 	spec.Swagger
+	names *schemaNames // 定义名称
 }
 
 // NewSwagger swagger 的构造函数
@@ -67,6 +65,50 @@ func NewSwagger() *swagger {
 	}
 }
 
+// clone 深度复制 swagger 对象，withPaths 为 false 时不复制路径
+func (s *swagger) clone(withPaths bool) *swagger {
+
+	b, err := s.MarshalJSON()
+	if err != nil {
+		panic(err)
+	}
+
+	r := NewSwagger()
+	if err = r.UnmarshalJSON(b); err != nil {
+		panic(err)
+	}
+
+	if r.Info == nil {
+		r.Info = &spec.Info{}
+	}
+	if r.Info.Contact == nil {
+		r.Info.Contact = &spec.ContactInfo{}
+	}
+	if r.Info.License == nil {
+		r.Info.License = &spec.License{}
+	}
+	if r.Paths == nil || r.Paths.Paths == nil || !withPaths {
+		r.Paths = &spec.Paths{Paths: make(map[string]spec.PathItem)}
+	}
+	if r.Definitions == nil {
+		r.Definitions = make(map[string]spec.Schema)
+	}
+	if r.SecurityDefinitions == nil {
+		r.SecurityDefinitions = map[string]*spec.SecurityScheme{}
+	}
+
+	if s.names != nil {
+		r.names = newSchemaNames()
+		for k, v := range s.names.byType {
+			r.names.byType[k] = v
+		}
+		for k, v := range s.names.byName {
+			r.names.byName[k] = v
+		}
+	}
+	return r
+}
+
 // ReadDoc 获取应用的 Swagger 描述内容
 func (s *swagger) ReadDoc() string {
 	if b, err := s.MarshalJSON(); err == nil {
@@ -165,8 +207,13 @@ func (s *swagger) WithTags(tags ...spec.Tag) *swagger {
 func (s *swagger) AddPath(path string, method uint32, op *Operation,
 	parameters ...spec.Parameter) *swagger {
 
-	path = strings.TrimPrefix(path, doc.BasePath)
+	op.parseResponses(s.schemaBuilder())
+
+	path = strings.TrimPrefix(path, s.BasePath)
 	path = strings.TrimRight(path, "/")
+	if strings.ContainsAny(path, ":*") {
+		path, _ = ToPathStyle(path, JavaPathStyle)
+	}
 	pathItem, ok := s.Paths.Paths[path]
 
 	if !ok {
@@ -221,138 +268,53 @@ func (s *swagger) BindDefinitions(i ...interface{}) *swagger {
 	return s
 }
 
-// BindDefinitionWithTags 绑定一个定义
+// BindDefinitionWithTags 绑定一个定义，引用到的其他命名类型也会生成对应的定义
 func (s *swagger) BindDefinitionWithTags(i interface{}, attachFields map[string]DefinitionField) *swagger {
 
 	it := reflect.TypeOf(i)
-	if it.Kind() == reflect.Ptr {
+	for it.Kind() == reflect.Ptr {
 		it = it.Elem()
 	}
 
-	objSchema := new(spec.Schema).Typed("object", "")
-	for i := 0; i < it.NumField(); i++ {
-		f := it.Field(i)
-
-		// 处理 XML 标签
-		var xmlTag []string
-		if tag, ok := f.Tag.Lookup("xml"); ok {
-			xmlTag = strings.Split(tag, ",")
-			if f.Type == reflect.TypeOf(xml.Name{}) {
-				objSchema.WithXMLName(xmlTag[0])
-				continue
-			}
-		}
-
-		propName := f.Name
+	b := s.schemaBuilder()
+	name := b.names.nameOf(it)
 
-		// 处理 JSON 标签
-		var jsonTag []string
-		if tag, ok := f.Tag.Lookup("json"); ok {
-			jsonTag = strings.Split(tag, ",")
-			propName = jsonTag[0]
-		}
-
-		var propSchema *spec.Schema
-		switch k := f.Type.Kind(); k {
-		case reflect.Bool:
-			propSchema = spec.BoolProperty()
-		case reflect.Int8:
-			propSchema = spec.Int8Property()
-		case reflect.Int16:
-			propSchema = spec.Int16Property()
-		case reflect.Int32:
-			propSchema = spec.Int32Property()
-		case reflect.Int64:
-			propSchema = spec.Int64Property()
-		case reflect.String:
-			propSchema = spec.StringProperty()
-		case reflect.Struct:
-			if f.Type == reflect.TypeOf(time.Time{}) {
-				propSchema = spec.DateTimeProperty()
-			} else {
-				panic(fmt.Errorf("unsupported swagger type %s", f.Type))
-			}
-		case reflect.Ptr:
-			if et := f.Type.Elem(); et.Kind() == reflect.Struct {
-				propSchema = spec.RefSchema("#/definitions/" + et.Name())
-			} else {
-				panic(fmt.Errorf("unsupported swagger type %s", f.Type))
-			}
-		case reflect.Slice:
-			{
-				et := f.Type.Elem()
-
-				var items *spec.Schema
-				switch k := et.Kind(); k {
-				case reflect.Bool:
-					items = spec.BoolProperty()
-				case reflect.Int8:
-					items = spec.Int8Property()
-				case reflect.Int16:
-					items = spec.Int16Property()
-				case reflect.Int32:
-					items = spec.Int32Property()
-				case reflect.Int64:
-					items = spec.Int64Property()
-				case reflect.String:
-					items = spec.StringProperty()
-				case reflect.Struct:
-					items = spec.RefSchema("#/definitions/" + et.Name())
-				default:
-					panic(fmt.Errorf("unsupported swagger type %s", f.Type))
-				}
-
-				if len(xmlTag) > 0 {
-					items.WithXMLName(xmlTag[0])
-				}
-
-				propSchema = spec.ArrayProperty(items)
-			}
-		default:
-			panic(fmt.Errorf("unsupported swagger type %s", f.Type))
-		}
+	var objSchema spec.Schema
+	if it.Kind() == reflect.Struct {
+		objSchema = b.objectSchema(it)
+	} else {
+		objSchema = b.schema(it)
+	}
 
-		if len(xmlTag) > 1 {
-			for _, v := range xmlTag {
-				if v == "wrapped" {
-					propSchema.AsWrappedXML()
-					break
-				}
-			}
+	for propName, attachField := range attachFields {
+		propSchema, ok := objSchema.Properties[propName]
+		if !ok {
+			continue
 		}
-
-		required := true
-
-		for _, v := range jsonTag {
-			if v == "omitempty" {
-				required = false
-				break
-			}
+		if len(attachField.Enums) > 0 {
+			propSchema.WithEnum(attachField.Enums...)
 		}
-
-		if required {
-			objSchema.AddRequired(propName)
+		if attachField.Description != "" {
+			propSchema.WithDescription(attachField.Description)
 		}
-
-		if attachField, ok := attachFields[propName]; ok {
-			if len(attachField.Enums) > 0 {
-				propSchema.WithEnum(attachField.Enums...)
-			}
-			if attachField.Description != "" {
-				propSchema.WithDescription(attachField.Description)
-			}
-			if attachField.Example != "" {
-				propSchema.WithExample(attachField.Example)
-			}
+		if attachField.Example != "" {
+			propSchema.WithExample(attachField.Example)
 		}
-
-		objSchema.SetProperty(propName, *propSchema)
+		objSchema.Properties[propName] = propSchema
 	}
 
-	s.Definitions[it.Name()] = *objSchema
+	s.Definitions[name] = objSchema
 	return s
 }
 
+// schemaBuilder 返回向 Definitions 注册定义的 schemaBuilder，多次调用共享同一个名称表
+func (s *swagger) schemaBuilder() *schemaBuilder {
+	if s.names == nil {
+		s.names = newSchemaNames()
+	}
+	return newSchemaBuilder("#/definitions/", s.Definitions, s.names)
+}
+
 // AddBasicSecurityDefinition 添加 Basic 方式认证
 func (s *swagger) AddBasicSecurityDefinition() *swagger {
 	s.Swagger.SecurityDefinitions["BasicAuth"] = spec.BasicAuth()
@@ -428,6 +390,7 @@ type bindParam struct {
 type Operation struct {
 	operation *spec.Operation
 	bindParam *bindParam
+	responds  map[int][]interface{} // Responds 声明的响应类型
 }
 
 // NewOperation creates a new operation instance.
@@ -175,3 +175,52 @@ func ToPathStyle(path string, style PathStyleEnum) (string, string) {
 	}
 	return p.String(), p.wildCardName()
 }
+
+// pathPattern 任意风格的路径模式，路径参数匹配一段路径，通配符匹配剩余的所有路径，
+// 根路径只匹配根路径。
+type pathPattern []string
+
+// compilePathPattern 把任意风格的路径转换成 Echo 风格的路径段
+func compilePathPattern(path string) (p pathPattern, err error) {
+
+	if !strings.HasPrefix(path, "/") {
+		return nil, errors.New("error url path")
+	}
+
+	if strings.Trim(path, "/") == "" {
+		return pathPattern{}, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("error url path")
+		}
+	}()
+
+	s, _ := ToPathStyle(strings.TrimRight(path, "/"), EchoPathStyle)
+	return strings.Split(s[1:], "/"), nil
+}
+
+// splitPath 把请求的路径分割成路径段
+func splitPath(path string) []string {
+	if path = strings.Trim(path, "/"); path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// match 路径模式是否匹配请求的路径段
+func (p pathPattern) match(segments []string) bool {
+	for i, s := range p {
+		if s == "*" {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if s[0] != ':' && s != segments[i] {
+			return false
+		}
+	}
+	return len(segments) == len(p)
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// client-gen 生成 testcases.ClientService 的客户端，也是应用编写客户端生成程序的示例。
+//
+//	client-gen [-o client/client.go] [-pkg client]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/go-spring/go-spring-web/testcases"
+)
+
+// Mapping 返回需要生成客户端的路由表
+func Mapping() SpringWeb.WebMapping {
+
+	s := new(testcases.ClientService)
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetBinding("/echo", s.Echo)
+	m.GetBinding("/ptr_echo", s.PtrEcho)
+
+	r := m.Route("/v1/rpc")
+	r.PostBinding("/ctx_echo", s.CtxEcho)
+	r.GetBinding("/anonymous", func(req testcases.ClientEchoRequest) []string {
+		return []string{req.Str}
+	})
+	return m
+}
+
+func main() {
+
+	output := flag.String("o", "", "output file")
+	pkgName := flag.String("pkg", "", "package name")
+	flag.Parse()
+
+	if err := SpringWeb.GenerateClientFile(Mapping(), *output, *pkgName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
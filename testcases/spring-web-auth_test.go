@@ -0,0 +1,361 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web/spring-echo"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// whoami 返回认证主体的处理函数
+func whoami(ctx SpringWeb.WebContext) {
+	p := SpringWeb.GetPrincipal(ctx)
+	ctx.String(http.StatusOK, "%s:%s:%s", p.Scheme, p.Name, strings.Join(p.Roles, ","))
+}
+
+// bearer 返回带有 Bearer 令牌的请求头
+func bearer(token string) map[string]string {
+	return map[string]string{SpringWeb.HeaderAuthorization: "Bearer " + token}
+}
+
+func TestBasicAuthFilter(t *testing.T) {
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/me", whoami, SpringWeb.BasicAuthFilter(SpringWeb.BasicAuthConfig{
+		Realm:     "pets",
+		Validator: SpringWeb.BasicUsers(map[string]string{"admin": "secret"}),
+	}))
+
+	basic := func(s string) map[string]string {
+		return map[string]string{SpringWeb.HeaderAuthorization: "Basic " + base64.StdEncoding.EncodeToString([]byte(s))}
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m)
+
+			w, body := doRequest(h, http.MethodGet, "/me", nil, basic("admin:secret"))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "Basic:admin:", body)
+
+			for _, header := range []map[string]string{nil, basic("admin:wrong"), basic("admin:"), basic("bob:secret"), basic("admin"), {SpringWeb.HeaderAuthorization: "Basic !!"}} {
+				w, _ = doRequest(h, http.MethodGet, "/me", nil, header)
+				assert.Equal(t, http.StatusUnauthorized, w.Code)
+				assert.Equal(t, `Basic realm="pets"`, w.Header().Get(SpringWeb.HeaderWWWAuthenticate))
+			}
+		})
+	}
+}
+
+func TestApiKeyFilter(t *testing.T) {
+
+	validator := func(key string) *SpringWeb.Principal {
+		if key == "k1" {
+			return &SpringWeb.Principal{Name: "client", Roles: []string{"reader"}}
+		}
+		return nil
+	}
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/header", whoami, SpringWeb.ApiKeyFilter(SpringWeb.ApiKeyConfig{Validator: validator}))
+	m.GetMapping("/query", whoami, SpringWeb.ApiKeyFilter(SpringWeb.ApiKeyConfig{In: "query", Key: "api_key", Validator: validator}))
+	m.GetMapping("/cookie", whoami, SpringWeb.ApiKeyFilter(SpringWeb.ApiKeyConfig{In: "cookie", Key: "session", Validator: validator}))
+
+	assert.Panics(t, func() {
+		SpringWeb.ApiKeyFilter(SpringWeb.ApiKeyConfig{In: "body", Validator: validator})
+	})
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m)
+
+			_, body := doRequest(h, http.MethodGet, "/header", nil, map[string]string{"X-API-Key": "k1"})
+			assert.Equal(t, "ApiKey:client:reader", body)
+			w, _ := doRequest(h, http.MethodGet, "/header", nil, map[string]string{"X-API-Key": "k2"})
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+			_, body = doRequest(h, http.MethodGet, "/query?api_key=k1", nil, nil)
+			assert.Equal(t, "ApiKey:client:reader", body)
+			w, _ = doRequest(h, http.MethodGet, "/query", nil, map[string]string{"X-API-Key": "k1"})
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+			_, body = doRequest(h, http.MethodGet, "/cookie", nil, map[string]string{"Cookie": "session=k1"})
+			assert.Equal(t, "ApiKey:client:reader", body)
+			w, _ = doRequest(h, http.MethodGet, "/cookie", nil, nil)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+}
+
+// jwkBase64 返回 JWK 使用的大整数编码
+func jwkBase64(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func TestJwtFilter(t *testing.T) {
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	// 公钥保存在 JWKS 文件中
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": jwkBase64(rsaKey.N), "e": jwkBase64(big.NewInt(int64(rsaKey.E)))},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": jwkBase64(ecKey.X), "y": jwkBase64(ecKey.Y)},
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		},
+	})
+
+	f, err := ioutil.TempFile("", "jwks")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+	_, _ = f.Write(jwks)
+	_ = f.Close()
+
+	keys, err := SpringWeb.LoadJwksFile(f.Name())
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+
+	filter := SpringWeb.JwtFilter(SpringWeb.JwtConfig{
+		Secret:         secret,
+		JwksFile:       f.Name(),
+		Leeway:         time.Minute,
+		Issuer:         "https://auth.example.com",
+		Audience:       "pets",
+		RequiredClaims: []string{"sub"},
+		Validate: func(claims SpringWeb.JwtClaims) error {
+			if claims.String("tenant") == "banned" {
+				return SpringWeb.ErrBodyTooLarge
+			}
+			return nil
+		},
+	})
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/me", whoami, filter)
+	m.GetMapping("/claims", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, SpringWeb.GetPrincipal(ctx).Claims.String("tenant"))
+	}, filter)
+
+	now := time.Now().Unix()
+	claims := func(override map[string]interface{}) SpringWeb.JwtClaims {
+		c := SpringWeb.JwtClaims{
+			"sub":    "tom",
+			"iss":    "https://auth.example.com",
+			"aud":    []string{"pets", "stores"},
+			"exp":    now + 60,
+			"iat":    now,
+			"roles":  []string{"admin", "user"},
+			"tenant": "acme",
+		}
+		for k, v := range override {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+
+	sign := func(alg, kid string, key interface{}, c SpringWeb.JwtClaims) string {
+		token, err := SpringWeb.SignJwt(alg, kid, key, c)
+		assert.Nil(t, err)
+		return token
+	}
+
+	valid := []string{
+		sign("HS256", "", secret, claims(nil)),
+		sign("HS512", "", secret, claims(nil)),
+		sign("RS256", "rsa", rsaKey, claims(nil)),
+		sign("PS384", "rsa", rsaKey, claims(nil)),
+		sign("ES256", "ec", ecKey, claims(nil)),
+		sign("RS256", "", rsaKey, claims(nil)),                                     // 没有 kid 时尝试所有密钥
+		sign("HS256", "", secret, claims(map[string]interface{}{"exp": now - 30})), // 在允许的时钟误差内
+		sign("HS256", "", secret, claims(map[string]interface{}{"aud": "pets"})),
+	}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	invalid := []string{
+		"not-a-token",
+		sign("HS256", "", []byte("wrong"), claims(nil)),
+		sign("RS256", "rsa", otherKey, claims(nil)),
+		sign("ES256", "rsa", ecKey, claims(nil)), // 密钥类型和算法不匹配
+		sign("HS256", "", secret, claims(map[string]interface{}{"exp": now - 120})),
+		sign("HS256", "", secret, claims(map[string]interface{}{"nbf": now + 120})),
+		sign("HS256", "", secret, claims(map[string]interface{}{"exp": "tomorrow"})), // 时间声明的类型错误
+		sign("HS256", "", secret, claims(map[string]interface{}{"nbf": true})),
+		sign("HS256", "", secret, claims(map[string]interface{}{"iat": map[string]interface{}{}})),
+		sign("HS256", "", secret, claims(map[string]interface{}{"iss": "https://evil.example.com"})),
+		sign("HS256", "", secret, claims(map[string]interface{}{"aud": "stores"})),
+		sign("HS256", "", secret, claims(map[string]interface{}{"sub": nil})),
+		sign("HS256", "", secret, claims(map[string]interface{}{"tenant": "banned"})),
+	}
+
+	// alg 为 none 的令牌不被接受
+	none := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"tom"}`)) + "."
+	invalid = append(invalid, none)
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m)
+
+			for i, token := range valid {
+				w, body := doRequest(h, http.MethodGet, "/me", nil, bearer(token))
+				assert.Equal(t, http.StatusOK, w.Code, i)
+				assert.Equal(t, "Bearer:tom:admin,user", body, i)
+			}
+
+			_, body := doRequest(h, http.MethodGet, "/claims", nil, bearer(valid[0]))
+			assert.Equal(t, "acme", body)
+
+			for i, token := range invalid {
+				w, _ := doRequest(h, http.MethodGet, "/me", nil, bearer(token))
+				assert.Equal(t, http.StatusUnauthorized, w.Code, i)
+				assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get(SpringWeb.HeaderWWWAuthenticate), i)
+			}
+
+			w, _ := doRequest(h, http.MethodGet, "/me", nil, nil)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, "Bearer", w.Header().Get(SpringWeb.HeaderWWWAuthenticate))
+		})
+	}
+
+	// 限制算法之后其他算法的令牌不被接受
+	v := SpringWeb.NewJwtVerifier(SpringWeb.JwtConfig{Secret: secret, Algorithms: []string{"HS512"}})
+	_, err = v.Verify(valid[0])
+	assert.NotNil(t, err)
+	_, err = v.Verify(valid[1])
+	assert.Nil(t, err)
+
+	assert.Panics(t, func() { SpringWeb.NewJwtVerifier(SpringWeb.JwtConfig{}) })
+	assert.Panics(t, func() { SpringWeb.NewJwtVerifier(SpringWeb.JwtConfig{Secret: secret, Algorithms: []string{"none"}}) })
+}
+
+func TestAuthPreflight(t *testing.T) {
+
+	basic := SpringWeb.BasicAuthFilter(SpringWeb.BasicAuthConfig{
+		Validator: SpringWeb.BasicUsers(map[string]string{"admin": "secret"}),
+	})
+	apiKey := SpringWeb.ApiKeyFilter(SpringWeb.ApiKeyConfig{
+		Validator: func(key string) *SpringWeb.Principal { return &SpringWeb.Principal{Name: key} },
+	})
+	jwt := SpringWeb.JwtFilter(SpringWeb.JwtConfig{Secret: []byte("secret")})
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.Request(SpringWeb.MethodAny, "/basic", SpringWeb.FUNC(whoami), basic)
+	m.Request(SpringWeb.MethodAny, "/key", SpringWeb.FUNC(whoami), apiKey)
+	m.Request(SpringWeb.MethodAny, "/jwt", SpringWeb.FUNC(whoami), jwt)
+
+	// 预检请求由 CORS 过滤器直接返回，不会到达认证过滤器
+	m.Route("/cors").WithCors(SpringWeb.CorsConfig{AllowOrigins: []string{"https://app.example.com"}}).
+		Request(SpringWeb.MethodAny, "/basic", SpringWeb.FUNC(whoami), basic)
+
+	preflight := map[string]string{
+		SpringWeb.HeaderOrigin:                     "https://app.example.com",
+		SpringWeb.HeaderAccessControlRequestMethod: http.MethodDelete,
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m)
+
+			// 接受任意方法的处理函数不能通过伪造的预检请求绕过认证
+			for _, path := range []string{"/basic", "/key", "/jwt"} {
+				w, body := doRequest(h, http.MethodOptions, path, nil, preflight)
+				assert.Equal(t, http.StatusUnauthorized, w.Code, path)
+				assert.NotContains(t, body, ":", path)
+			}
+
+			w, body := doRequest(h, http.MethodOptions, "/cors/basic", nil, preflight)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Empty(t, body)
+			assert.Equal(t, "https://app.example.com", w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+		})
+	}
+}
+
+func TestSecurityFilterDocs(t *testing.T) {
+
+	basic := SpringWeb.BasicAuthFilter(SpringWeb.BasicAuthConfig{
+		Validator: SpringWeb.BasicUsers(map[string]string{"admin": "secret"}),
+	})
+	apiKey := SpringWeb.ApiKeyFilter(SpringWeb.ApiKeyConfig{
+		In:        "query",
+		Key:       "api_key",
+		Validator: func(key string) *SpringWeb.Principal { return nil },
+	})
+	jwt := SpringWeb.JwtFilter(SpringWeb.JwtConfig{Secret: []byte("secret")})
+
+	c := SpringEcho.NewContainer(SpringWeb.ContainerConfig{})
+	c.GetBinding("/pet/:id", FindDerivedPet)
+	c.Route("/admin", jwt).PostBinding("/pet", AddDerivedPet, apiKey)
+	c.PostBinding("/pet", AddDerivedPet, basic)
+
+	b, err := SpringWeb.ExportSpec(c, "", SpringWeb.SpecSwagger, "json")
+	assert.Nil(t, err)
+	s, err := SpringWeb.LoadSpec(b)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "basic", s.SecurityDefinitions["BasicAuth"].Type)
+	assert.Equal(t, "api_key", s.SecurityDefinitions["ApiKeyAuth"].Name)
+	assert.Equal(t, "query", s.SecurityDefinitions["ApiKeyAuth"].In)
+	assert.Equal(t, SpringWeb.HeaderAuthorization, s.SecurityDefinitions["BearerAuth"].Name)
+
+	assert.Empty(t, s.Paths.Paths["/pet/{id}"].Get.Security)
+	security := s.Paths.Paths["/pet"].Post.Security
+	assert.Len(t, security, 1)
+	assert.Len(t, security[0], 1)
+	assert.Contains(t, security[0], "BasicAuth")
+
+	// 路由分组和处理函数的认证过滤器需要同时满足
+	security = s.Paths.Paths["/admin/pet"].Post.Security
+	assert.Len(t, security, 1)
+	assert.Len(t, security[0], 2)
+	assert.Contains(t, security[0], "BearerAuth")
+	assert.Contains(t, security[0], "ApiKeyAuth")
+
+	// OpenAPI 3 中 JWT 认证导出为 http bearer
+	b, err = SpringWeb.ExportSpec(c, "", SpringWeb.SpecOpenAPI, "json")
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"scheme": "bearer",`)
+	assert.Contains(t, string(b), `"bearerFormat": "JWT"`)
+
+	n, err := SpringWeb.LoadSpec(b)
+	assert.Nil(t, err)
+	assert.Empty(t, SpringWeb.DiffSwagger(s, n).Changes)
+}
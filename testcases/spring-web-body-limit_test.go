@@ -0,0 +1,141 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// chunked 返回没有 Content-Length 的请求体
+func chunked(s string) io.Reader {
+	return ioutil.NopCloser(strings.NewReader(s))
+}
+
+// multipartFile 返回只包含一个文件的表单和对应的请求头
+func multipartFile(content string) ([]byte, map[string]string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	_, _ = fw.Write([]byte(content))
+	_ = mw.Close()
+	return buf.Bytes(), map[string]string{SpringWeb.HeaderContentType: mw.FormDataContentType()}
+}
+
+func TestBodyLimitFilter(t *testing.T) {
+
+	limit := func(n int64) SpringWeb.Filter {
+		return SpringWeb.BodyLimitFilter(SpringWeb.BodyLimitConfig{Limit: n})
+	}
+
+	m := SpringWeb.NewDefaultWebMapping()
+
+	m.PostBinding("/bind", func(pet ValidatedPet) string {
+		return pet.Name
+	})
+
+	m.HandlePost("/raw", SpringWeb.RPC(func(ctx SpringWeb.WebContext) interface{} {
+		b, err := ctx.GetRawData()
+		if err != nil {
+			panic(err)
+		}
+		return len(b)
+	}))
+
+	// 忽略读取错误的处理函数仍然返回 413
+	m.PostMapping("/ignore", func(ctx SpringWeb.WebContext) {
+		_, _ = ioutil.ReadAll(ctx.Request().Body)
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	m.PostMapping("/small", func(ctx SpringWeb.WebContext) {
+		b, _ := ctx.GetRawData()
+		ctx.String(http.StatusOK, string(b))
+	}, limit(4))
+
+	var onDisk bool
+	m.PostMapping("/upload", func(ctx SpringWeb.WebContext) {
+		fh, err := ctx.FormFile("file")
+		if !assert.Nil(t, err) {
+			return
+		}
+		f, err := fh.Open()
+		assert.Nil(t, err)
+		defer f.Close()
+		_, onDisk = f.(*os.File)
+		ctx.String(http.StatusOK, "%d", fh.Size)
+	}, SpringWeb.BodyLimitFilter(SpringWeb.BodyLimitConfig{Limit: 512, MultipartMemory: 1}))
+
+	json := map[string]string{SpringWeb.HeaderContentType: SpringWeb.MIMEApplicationJSON}
+	large := `{"name":"` + strings.Repeat("x", 64) + `"}`
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			h := adapter(m, limit(32))
+
+			// Content-Length 超出限制时直接拒绝
+			w, body := doRequest(h, http.MethodPost, "/bind", strings.NewReader(large), json)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			assert.Equal(t, SpringWeb.ErrBodyTooLarge.Error(), body)
+
+			// 没有 Content-Length 时读取超出限制
+			for _, path := range []string{"/bind", "/raw", "/ignore"} {
+				w, body = doRequest(h, http.MethodPost, path, chunked(large), json)
+				assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, path)
+				assert.Equal(t, SpringWeb.ErrBodyTooLarge.Error(), body, path)
+				assert.Equal(t, "close", w.Header().Get("Connection"), path)
+			}
+
+			w, body = doRequest(h, http.MethodPost, "/bind", chunked(`{"name":"kitty"}`), json)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, body, `"Data":"kitty"`)
+
+			w, body = doRequest(h, http.MethodPost, "/raw", chunked(strings.Repeat("x", 32)), nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, body, `"Data":32`)
+
+			// 处理函数的限制和全局的限制同时生效
+			w, body = doRequest(h, http.MethodPost, "/small", chunked("abcd"), nil)
+			assert.Equal(t, "abcd", body)
+			w, _ = doRequest(h, http.MethodPost, "/small", chunked("abcde"), nil)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+			// 超出内存限制的文件保存到临时文件中
+			onDisk = false
+			h = adapter(m)
+			form, contentType := multipartFile("0123456789")
+			w, body = doRequest(h, http.MethodPost, "/upload", bytes.NewReader(form), contentType)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "10", body)
+			assert.True(t, onDisk)
+
+			form, contentType = multipartFile(strings.Repeat("x", 1024))
+			w, _ = doRequest(h, http.MethodPost, "/upload", chunked(string(form)), contentType)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/go-spring/go-spring-web/testcases"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientGenerator(t *testing.T) {
+
+	rc := new(testcases.ClientService)
+
+	c := SpringWeb.NewBaseWebContainer(SpringWeb.ContainerConfig{})
+	c.GetBinding("/echo", rc.Echo)
+	c.GetBinding("/ptr_echo", rc.PtrEcho)
+	c.HandleGet("/ok", SpringWeb.RPC(new(testcases.RpcService).OK)) // 非 BIND 形式，不生成
+
+	r := c.Route("/v1/rpc")
+	{
+		r.PostBinding("/ctx_echo", rc.CtxEcho)
+		r.GetBinding("/anonymous", func(req testcases.ClientEchoRequest) []string {
+			return []string{req.Str}
+		})
+	}
+	c.AddRouter(r)
+
+	g := SpringWeb.NewClientGenerator("client")
+
+	var buf bytes.Buffer
+	err := g.Generate(c, &buf)
+	assert.NoError(t, err)
+
+	src := buf.String()
+	assert.Contains(t, src, "package client")
+	assert.Contains(t, src, `testcases "github.com/go-spring/go-spring-web/testcases"`)
+	assert.Contains(t, src, "func NewClient(baseURL string, httpClient *http.Client) *Client {")
+	assert.Contains(t, src, "func (c *Client) Echo(ctx context.Context, req testcases.ClientEchoRequest) (*testcases.EchoResponse, error) {")
+	assert.Contains(t, src, "func (c *Client) PtrEcho(ctx context.Context, req *testcases.ClientEchoRequest) (*testcases.EchoResponse, error) {")
+	assert.Contains(t, src, "func (c *V1RpcClient) CtxEcho(ctx context.Context, req *testcases.ClientEchoRequest) (*testcases.EchoResponse, error) {")
+	assert.Contains(t, src, "func (c *V1RpcClient) GetAnonymous(ctx context.Context, req testcases.ClientEchoRequest) ([]string, error) {")
+	assert.Contains(t, src, "func NewV1RpcClientWithRpcClient(rpc *SpringWeb.RpcClient) *V1RpcClient {")
+	assert.NotContains(t, src, "OK(")
+
+	// 多次生成的结果相同，导入的包不会累积
+	buf.Reset()
+	assert.NoError(t, g.Generate(c, &buf))
+	assert.Equal(t, src, buf.String())
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(adapter(c))
+			defer server.Close()
+
+			client := SpringWeb.NewRpcClient(server.URL, nil)
+
+			var resp *testcases.EchoResponse
+			err = client.Call(context.Background(), "GET", "/echo", testcases.ClientEchoRequest{Str: "abcd"}, &resp)
+			assert.NoError(t, err)
+			assert.Equal(t, "echo abcd", resp.Echo)
+
+			err = client.Call(context.Background(), "POST", "/v1/rpc/ctx_echo", &testcases.ClientEchoRequest{Str: "efgh"}, &resp)
+			assert.NoError(t, err)
+			assert.Equal(t, "echo efgh", resp.Echo)
+
+			err = client.Call(context.Background(), "GET", "/echo", testcases.ClientEchoRequest{Str: "abc"}, &resp)
+			rpcErr, ok := err.(*SpringWeb.RpcResultError)
+			assert.True(t, ok)
+			assert.Equal(t, int32(-1), rpcErr.Code)
+		})
+	}
+}
+
+// envelopeUnwrapper 和 envelopeWrapper 配套的 ResultUnwrapper
+type envelopeUnwrapper struct{}
+
+func (u *envelopeUnwrapper) Unwrap(statusCode int, body []byte, out interface{}) error {
+	var e struct {
+		Success bool            `json:"success"`
+		Code    int32           `json:"code"`
+		Message string          `json:"message"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil {
+		return &SpringWeb.HttpStatusError{StatusCode: statusCode, Body: string(body)}
+	}
+	if !e.Success {
+		return &SpringWeb.RpcResultError{Code: e.Code, Msg: e.Message}
+	}
+	if out != nil && len(e.Payload) > 0 {
+		return json.Unmarshal(e.Payload, out)
+	}
+	return nil
+}
+
+func TestRpcClientResultUnwrapper(t *testing.T) {
+
+	rc := new(testcases.ClientService)
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.Route("/v2").WithResultWrapper(&envelopeWrapper{}).GetBinding("/echo", rc.Echo)
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(adapter(m))
+			defer server.Close()
+
+			// 默认的 ResultUnwrapper 读不到自定义信封中的数据
+			var resp *testcases.EchoResponse
+			err := SpringWeb.NewRpcClient(server.URL, nil).Call(context.Background(), http.MethodGet, "/v2/echo", testcases.ClientEchoRequest{Str: "abcd"}, &resp)
+			assert.NoError(t, err)
+			assert.Nil(t, resp)
+
+			client := SpringWeb.NewRpcClient(server.URL, nil).WithResultUnwrapper(&envelopeUnwrapper{})
+
+			err = client.Call(context.Background(), http.MethodGet, "/v2/echo", testcases.ClientEchoRequest{Str: "abcd"}, &resp)
+			assert.NoError(t, err)
+			assert.Equal(t, "echo abcd", resp.Echo)
+
+			err = client.Call(context.Background(), http.MethodGet, "/v2/echo", testcases.ClientEchoRequest{Str: "abc"}, &resp)
+			rpcErr, ok := err.(*SpringWeb.RpcResultError)
+			assert.True(t, ok)
+			assert.Equal(t, int32(-1), rpcErr.Code)
+		})
+	}
+}
+
+// TestClientGenCommand 使用生成程序生成客户端代码，并且编译生成的代码
+func TestClientGenCommand(t *testing.T) {
+
+	if testing.Short() {
+		t.Skip("skipping go command in short mode")
+	}
+
+	goCmd, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	dir, err := ioutil.TempDir(".", "client")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "client.go")
+	out, err := exec.Command(goCmd, "run", "./client-gen", "-o", output, "-pkg", "client").CombinedOutput()
+	if !assert.NoError(t, err, string(out)) {
+		return
+	}
+
+	src, err := ioutil.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Contains(t, string(src), "func (c *V1RpcClient) CtxEcho(")
+
+	out, err = exec.Command(goCmd, "vet", "./"+filepath.ToSlash(dir)).CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// gunzip 解压缩 gzip 内容，允许内容还没有结束
+func gunzip(t *testing.T, b []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	var out bytes.Buffer
+	_, err = io.Copy(&out, r)
+	if err != io.ErrUnexpectedEOF {
+		assert.Nil(t, err)
+	}
+	return out.String()
+}
+
+func TestCompressFilter(t *testing.T) {
+
+	large := strings.Repeat("go-spring ", 200)
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			var rec *httptest.ResponseRecorder
+			var flushed string
+
+			m := SpringWeb.NewDefaultWebMapping()
+			m.GetMapping("/large", func(ctx SpringWeb.WebContext) {
+				ctx.JSON(http.StatusOK, map[string]string{"text": large})
+			})
+			m.GetMapping("/small", func(ctx SpringWeb.WebContext) {
+				ctx.String(http.StatusOK, "small")
+			})
+			m.GetMapping("/image", func(ctx SpringWeb.WebContext) {
+				ctx.Blob(http.StatusOK, SpringWeb.MIMEImagePng, []byte(large))
+			})
+			m.GetMapping("/empty", func(ctx SpringWeb.WebContext) {
+				ctx.NoContent(http.StatusNoContent)
+			})
+			m.GetMapping("/events", func(ctx SpringWeb.WebContext) {
+				ctx.Header(SpringWeb.HeaderContentType, SpringWeb.MIMETextEventStream)
+				ctx.Status(http.StatusOK)
+				w := ctx.ResponseWriter()
+				assert.Nil(t, SpringWeb.WriteSSEvent(w, "message", "first"))
+				w.(http.Flusher).Flush()
+				// 每次 Flush 之后客户端都能解压出已经发送的事件
+				flushed = gunzip(t, rec.Body.Bytes())
+				assert.Nil(t, SpringWeb.WriteSSEvent(w, "message", "second"))
+			})
+			m.PostMapping("/upload", func(ctx SpringWeb.WebContext) {
+				b, err := ioutil.ReadAll(ctx.Request().Body)
+				if err != nil {
+					ctx.String(http.StatusBadRequest, err.Error())
+					return
+				}
+				ctx.String(http.StatusOK, string(b))
+			})
+
+			h := adapter(m, SpringWeb.DecompressFilter(SpringWeb.DecompressConfig{MaxSize: 1024}), SpringWeb.CompressFilter(SpringWeb.CompressConfig{}))
+
+			gzipHeader := map[string]string{SpringWeb.HeaderAcceptEncoding: "gzip, deflate;q=0.5"}
+
+			w, body := doRequest(h, http.MethodGet, "/large", nil, gzipHeader)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "gzip", w.Header().Get(SpringWeb.HeaderContentEncoding))
+			assert.Contains(t, w.Header()[SpringWeb.HeaderVary], SpringWeb.HeaderAcceptEncoding)
+			assert.True(t, strings.HasPrefix(w.Header().Get(SpringWeb.HeaderContentType), SpringWeb.MIMEApplicationJSON))
+			assert.Equal(t, `{"text":"`+large+`"}`, strings.TrimSpace(gunzip(t, []byte(body))))
+
+			w, body = doRequest(h, http.MethodGet, "/large", nil, map[string]string{
+				SpringWeb.HeaderAcceptEncoding: "gzip;q=0.2, deflate",
+			})
+			assert.Equal(t, "deflate", w.Header().Get(SpringWeb.HeaderContentEncoding))
+			b, err := ioutil.ReadAll(flate.NewReader(strings.NewReader(body)))
+			assert.Nil(t, err)
+			assert.Equal(t, `{"text":"`+large+`"}`, strings.TrimSpace(string(b)))
+
+			w, body = doRequest(h, http.MethodGet, "/large", nil, map[string]string{
+				SpringWeb.HeaderAcceptEncoding: "gzip;q=0, identity",
+			})
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderContentEncoding))
+			assert.Contains(t, body, large)
+
+			w, body = doRequest(h, http.MethodGet, "/small", nil, gzipHeader)
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderContentEncoding))
+			assert.Equal(t, "small", body)
+
+			w, body = doRequest(h, http.MethodGet, "/image", nil, gzipHeader)
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderContentEncoding))
+			assert.Equal(t, large, body)
+
+			w, body = doRequest(h, http.MethodGet, "/empty", nil, gzipHeader)
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderContentEncoding))
+			assert.Empty(t, body)
+
+			rec = httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/events", nil)
+			r.Header.Set(SpringWeb.HeaderAcceptEncoding, "gzip")
+			h.ServeHTTP(rec, r)
+			assert.Equal(t, "gzip", rec.Header().Get(SpringWeb.HeaderContentEncoding))
+			assert.Equal(t, "event: message\ndata: first\n\n", flushed)
+			assert.Equal(t, "event: message\ndata: first\n\nevent: message\ndata: second\n\n", gunzip(t, rec.Body.Bytes()))
+
+			// 解压缩请求体
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			_, _ = zw.Write([]byte("compressed upload"))
+			assert.Nil(t, zw.Close())
+
+			w, body = doRequest(h, http.MethodPost, "/upload", &buf, map[string]string{
+				SpringWeb.HeaderContentEncoding: "gzip",
+			})
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "compressed upload", body)
+
+			w, _ = doRequest(h, http.MethodPost, "/upload", strings.NewReader("plain"), map[string]string{
+				SpringWeb.HeaderContentEncoding: "gzip",
+			})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			w, _ = doRequest(h, http.MethodPost, "/upload", strings.NewReader("plain"), map[string]string{
+				SpringWeb.HeaderContentEncoding: "zstd",
+			})
+			assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+
+			// 解压缩之后超出限制，压缩之后的内容很小
+			buf.Reset()
+			zw = gzip.NewWriter(&buf)
+			_, _ = zw.Write(bytes.Repeat([]byte("a"), 64*1024))
+			assert.Nil(t, zw.Close())
+			assert.True(t, buf.Len() < 1024)
+
+			w, body = doRequest(h, http.MethodPost, "/upload", &buf, map[string]string{
+				SpringWeb.HeaderContentEncoding: "gzip",
+			})
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			assert.Equal(t, SpringWeb.ErrBodyTooLarge.Error(), body)
+		})
+	}
+}
+
+// upperEncoder 把内容转换成大写的测试编码器
+type upperEncoder struct {
+	w io.Writer
+}
+
+func (e *upperEncoder) Write(b []byte) (int, error) {
+	return e.w.Write(bytes.ToUpper(b))
+}
+
+func (e *upperEncoder) Flush() error { return nil }
+func (e *upperEncoder) Close() error { return nil }
+
+func TestRegisterCompressEncoder(t *testing.T) {
+
+	SpringWeb.RegisterCompressEncoder("x-upper", func(w io.Writer, level int) (SpringWeb.CompressEncoder, error) {
+		return &upperEncoder{w: w}, nil
+	})
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/text", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "hello")
+	})
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m, SpringWeb.CompressFilter(SpringWeb.CompressConfig{
+				MinLength: -1,
+				Encodings: []string{"x-upper", "gzip"},
+			}))
+			w, body := doRequest(h, http.MethodGet, "/text", nil, map[string]string{
+				SpringWeb.HeaderAcceptEncoding: "gzip, x-upper",
+			})
+			assert.Equal(t, "x-upper", w.Header().Get(SpringWeb.HeaderContentEncoding))
+			assert.Equal(t, "HELLO", body)
+		})
+	}
+
+	assert.Panics(t, func() {
+		SpringWeb.CompressFilter(SpringWeb.CompressConfig{Encodings: []string{"br"}})
+	})
+}
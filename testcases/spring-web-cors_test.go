@@ -0,0 +1,171 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorsFilter(t *testing.T) {
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			m := SpringWeb.NewDefaultWebMapping()
+
+			api := m.Route("/api").WithCors(SpringWeb.CorsConfig{
+				AllowOrigins:        []string{"https://app.example.com", "https://*.example.org"},
+				AllowOriginPatterns: []string{`http://localhost:\d+`},
+				AllowMethods:        []string{http.MethodGet, http.MethodPost},
+				ExposeHeaders:       []string{"X-Total-Count"},
+				AllowCredentials:    true,
+				MaxAge:              10 * time.Minute,
+			})
+			api.GetMapping("/pets/:id", func(ctx SpringWeb.WebContext) {
+				ctx.String(http.StatusOK, "pet "+ctx.PathParam("id"))
+			})
+			api.PostMapping("/pets/:id", func(ctx SpringWeb.WebContext) {
+				ctx.String(http.StatusOK, "saved")
+			})
+
+			public := m.Route("/public").WithCors(SpringWeb.CorsConfig{AllowOrigins: []string{"*"}})
+			public.OPTIONS("/ping", SpringWeb.FUNC(func(ctx SpringWeb.WebContext) {
+				ctx.String(http.StatusOK, "custom")
+			}))
+			public.GetMapping("/ping", func(ctx SpringWeb.WebContext) {
+				ctx.String(http.StatusOK, "pong")
+			})
+
+			m.GetMapping("/private", func(ctx SpringWeb.WebContext) {
+				ctx.String(http.StatusOK, "private")
+			})
+
+			h := adapter(m)
+
+			// 预检请求在过滤器中直接返回
+			w, _ := doRequest(h, http.MethodOptions, "/api/pets/1", nil, map[string]string{
+				SpringWeb.HeaderOrigin:                      "https://app.example.com",
+				SpringWeb.HeaderAccessControlRequestMethod:  http.MethodPost,
+				SpringWeb.HeaderAccessControlRequestHeaders: "X-Token",
+			})
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, "https://app.example.com", w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+			assert.Equal(t, "GET, POST", w.Header().Get(SpringWeb.HeaderAccessControlAllowMethods))
+			assert.Equal(t, "X-Token", w.Header().Get(SpringWeb.HeaderAccessControlAllowHeaders))
+			assert.Equal(t, "true", w.Header().Get(SpringWeb.HeaderAccessControlAllowCredentials))
+			assert.Equal(t, "600", w.Header().Get(SpringWeb.HeaderAccessControlMaxAge))
+			assert.Contains(t, strings.Join(w.Header()[SpringWeb.HeaderVary], ","), SpringWeb.HeaderOrigin)
+
+			// 通配符和正则表达式
+			for _, origin := range []string{"https://a.b.example.org", "http://localhost:8080"} {
+				w, body := doRequest(h, http.MethodGet, "/api/pets/2", nil, map[string]string{
+					SpringWeb.HeaderOrigin: origin,
+				})
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Equal(t, "pet 2", body)
+				assert.Equal(t, origin, w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+				assert.Equal(t, "X-Total-Count", w.Header().Get(SpringWeb.HeaderAccessControlExposeHeaders))
+			}
+
+			// 不允许的源
+			w, _ = doRequest(h, http.MethodOptions, "/api/pets/1", nil, map[string]string{
+				SpringWeb.HeaderOrigin:                     "https://example.org.evil.com",
+				SpringWeb.HeaderAccessControlRequestMethod: http.MethodGet,
+			})
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+
+			w, body := doRequest(h, http.MethodGet, "/api/pets/1", nil, map[string]string{
+				SpringWeb.HeaderOrigin: "https://evil.com",
+			})
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "pet 1", body)
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+
+			// 非跨域请求
+			w, _ = doRequest(h, http.MethodGet, "/api/pets/1", nil, nil)
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+
+			// 允许所有的源，已经注册的 OPTIONS 方法不会被覆盖
+			w, body = doRequest(h, http.MethodGet, "/public/ping", nil, map[string]string{
+				SpringWeb.HeaderOrigin: "https://any.com",
+			})
+			assert.Equal(t, "pong", body)
+			assert.Equal(t, "*", w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+
+			w, body = doRequest(h, http.MethodOptions, "/public/ping", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "custom", body)
+
+			// 没有启用跨域的路由不会注册 OPTIONS 方法
+			w, _ = doRequest(h, http.MethodOptions, "/private", nil, map[string]string{
+				SpringWeb.HeaderOrigin:                     "https://app.example.com",
+				SpringWeb.HeaderAccessControlRequestMethod: http.MethodGet,
+			})
+			assert.NotEqual(t, http.StatusNoContent, w.Code)
+		})
+	}
+
+	// 允许所有的源时不能携带凭证
+	assert.Panics(t, func() {
+		SpringWeb.CorsFilter(SpringWeb.CorsConfig{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	})
+}
+
+func TestCorsFilterOrder(t *testing.T) {
+
+	m := SpringWeb.NewDefaultWebMapping()
+
+	// 跨域过滤器放在路由分组其他过滤器的前面，再次调用 WithCors 时替换之前的配置
+	m.Route("/r", &denyFilter{}).
+		WithCors(SpringWeb.CorsConfig{AllowOrigins: []string{"https://old.example.com"}}).
+		WithCors(SpringWeb.CorsConfig{AllowOrigins: []string{"https://app.example.com"}}).
+		Request(SpringWeb.MethodAny, "/any", SpringWeb.FUNC(func(ctx SpringWeb.WebContext) {
+			ctx.String(http.StatusOK, "ok")
+		}))
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m)
+
+			w, _ := doRequest(h, http.MethodOptions, "/r/any", nil, map[string]string{
+				SpringWeb.HeaderOrigin:                     "https://app.example.com",
+				SpringWeb.HeaderAccessControlRequestMethod: http.MethodPost,
+			})
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, "https://app.example.com", w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+
+			w, _ = doRequest(h, http.MethodOptions, "/r/any", nil, map[string]string{
+				SpringWeb.HeaderOrigin:                     "https://old.example.com",
+				SpringWeb.HeaderAccessControlRequestMethod: http.MethodPost,
+			})
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+
+			w, body := doRequest(h, http.MethodGet, "/r/any", nil, map[string]string{
+				SpringWeb.HeaderOrigin: "https://app.example.com",
+			})
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Equal(t, "denied", body)
+		})
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// csrfMapping 返回包含表单页面和提交接口的 WebMapping
+func csrfMapping() SpringWeb.WebMapping {
+
+	form := `<form method="post" action="/submit">{{csrfField}}</form>`
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/form", func(ctx SpringWeb.WebContext) {
+		t := template.Must(template.New("form").Funcs(SpringWeb.CsrfFuncMap(ctx)).Parse(form))
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, nil); err != nil {
+			panic(err)
+		}
+		ctx.HTML(http.StatusOK, buf.String())
+	})
+	m.PostMapping("/submit", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "submitted")
+	})
+	m.PostMapping("/webhook/:id", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "hooked")
+	})
+	return m
+}
+
+var csrfInput = regexp.MustCompile(`<input type="hidden" name="_csrf" value="([\w.-]+)">`)
+
+func TestCsrfFilter(t *testing.T) {
+
+	m := csrfMapping()
+	form := map[string]string{SpringWeb.HeaderContentType: SpringWeb.MIMEApplicationForm}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			h := adapter(m, SpringWeb.CsrfFilter(SpringWeb.CsrfConfig{
+				TokenLookup:    "header:X-CSRF-Token,form:_csrf,query:csrf",
+				CookieSecure:   true,
+				CookieSameSite: http.SameSiteStrictMode,
+				ExemptPaths:    []string{"/webhook/{id}"},
+			}))
+
+			// 安全的方法下发令牌
+			w, body := doRequest(h, http.MethodGet, "/form", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			cookie := w.Header().Get("Set-Cookie")
+			assert.Contains(t, cookie, "_csrf=")
+			assert.Contains(t, cookie, "Path=/")
+			assert.Contains(t, cookie, "Secure")
+			assert.Contains(t, cookie, "SameSite=Strict")
+
+			match := csrfInput.FindStringSubmatch(body)
+			if !assert.Len(t, match, 2) {
+				return
+			}
+			token := match[1]
+			assert.Contains(t, cookie, "_csrf="+token+";")
+
+			withCookie := func(header map[string]string) map[string]string {
+				r := map[string]string{"Cookie": "_csrf=" + token}
+				for k, v := range header {
+					r[k] = v
+				}
+				return r
+			}
+
+			// 已经有令牌时不再下发
+			w, _ = doRequest(h, http.MethodGet, "/form", nil, withCookie(nil))
+			assert.Empty(t, w.Header().Get("Set-Cookie"))
+
+			// 从请求头、表单和查询参数中读取令牌
+			_, body = doRequest(h, http.MethodPost, "/submit", nil, withCookie(map[string]string{"X-CSRF-Token": token}))
+			assert.Equal(t, "submitted", body)
+			_, body = doRequest(h, http.MethodPost, "/submit", strings.NewReader(url.Values{"_csrf": {token}}.Encode()), withCookie(form))
+			assert.Equal(t, "submitted", body)
+			_, body = doRequest(h, http.MethodPost, "/submit?csrf="+token, nil, withCookie(nil))
+			assert.Equal(t, "submitted", body)
+
+			// 令牌缺失或者不匹配
+			w, body = doRequest(h, http.MethodPost, "/submit", nil, withCookie(nil))
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Equal(t, SpringWeb.ErrInvalidCsrfToken.Error(), body)
+			w, _ = doRequest(h, http.MethodPost, "/submit", nil, withCookie(map[string]string{"X-CSRF-Token": token + "x"}))
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			w, _ = doRequest(h, http.MethodPost, "/submit", nil, map[string]string{"X-CSRF-Token": token})
+			assert.Equal(t, http.StatusForbidden, w.Code)
+
+			// 能够写入 Cookie 的攻击者无法伪造签名
+			for _, forged := range []string{"forged", "forged." + strings.SplitN(token, ".", 2)[1]} {
+				w, _ = doRequest(h, http.MethodPost, "/submit", nil, map[string]string{
+					"Cookie":       "_csrf=" + forged,
+					"X-CSRF-Token": forged,
+				})
+				assert.Equal(t, http.StatusForbidden, w.Code, forged)
+			}
+
+			// 豁免的路径不检查令牌
+			_, body = doRequest(h, http.MethodPost, "/webhook/1", nil, nil)
+			assert.Equal(t, "hooked", body)
+		})
+	}
+
+	assert.Panics(t, func() { SpringWeb.CsrfFilter(SpringWeb.CsrfConfig{TokenLookup: "cookie:_csrf"}) })
+	assert.Panics(t, func() { SpringWeb.CsrfFilter(SpringWeb.CsrfConfig{Store: SpringWeb.NewMemoryCsrfStore(0)}) })
+}
+
+func TestCsrfSessionBound(t *testing.T) {
+
+	m := csrfMapping()
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			h := adapter(m, SpringWeb.CsrfFilter(SpringWeb.CsrfConfig{
+				Secret:  []byte("csrf-secret"),
+				Session: func(ctx SpringWeb.WebContext) string { return ctx.GetHeader("X-Session") },
+			}))
+
+			_, body := doRequest(h, http.MethodGet, "/form", nil, map[string]string{"X-Session": "alice"})
+			match := csrfInput.FindStringSubmatch(body)
+			if !assert.Len(t, match, 2) {
+				return
+			}
+			token := match[1]
+
+			_, body = doRequest(h, http.MethodPost, "/submit", nil, map[string]string{
+				"X-Session": "alice", "Cookie": "_csrf=" + token, "X-CSRF-Token": token,
+			})
+			assert.Equal(t, "submitted", body)
+
+			// 其他会话不能使用该令牌
+			w, _ := doRequest(h, http.MethodPost, "/submit", nil, map[string]string{
+				"X-Session": "mallory", "Cookie": "_csrf=" + token, "X-CSRF-Token": token,
+			})
+			assert.Equal(t, http.StatusForbidden, w.Code)
+		})
+	}
+}
+
+func TestMemoryCsrfStore(t *testing.T) {
+	store := SpringWeb.NewMemoryCsrfStore(20 * time.Millisecond)
+	store.Set("s", "token")
+	assert.Equal(t, "token", store.Get("s"))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, "", store.Get("s"))
+}
+
+func TestCsrfSynchronizerToken(t *testing.T) {
+
+	m := csrfMapping()
+	store := SpringWeb.NewMemoryCsrfStore(0)
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			h := adapter(m, SpringWeb.CsrfFilter(SpringWeb.CsrfConfig{
+				Store:   store,
+				Session: func(ctx SpringWeb.WebContext) string { return ctx.GetHeader("X-Session") },
+				Skipper: func(ctx SpringWeb.WebContext) bool { return ctx.GetHeader("X-Internal") != "" },
+			}))
+
+			session := map[string]string{"X-Session": name}
+			w, body := doRequest(h, http.MethodGet, "/form", nil, session)
+			assert.Empty(t, w.Header().Get("Set-Cookie"))
+
+			match := csrfInput.FindStringSubmatch(body)
+			if !assert.Len(t, match, 2) {
+				return
+			}
+			assert.Equal(t, store.Get(name), match[1])
+
+			_, body = doRequest(h, http.MethodPost, "/submit", nil, map[string]string{"X-Session": name, "X-CSRF-Token": match[1]})
+			assert.Equal(t, "submitted", body)
+
+			// 其他会话的令牌无效
+			w, _ = doRequest(h, http.MethodPost, "/submit", nil, map[string]string{"X-Session": "other", "X-CSRF-Token": match[1]})
+			assert.Equal(t, http.StatusForbidden, w.Code)
+
+			// 没有会话时页面中没有令牌
+			_, body = doRequest(h, http.MethodGet, "/form", nil, nil)
+			assert.Equal(t, `<form method="post" action="/submit"></form>`, body)
+
+			_, body = doRequest(h, http.MethodPost, "/submit", nil, map[string]string{"X-Internal": "1"})
+			assert.Equal(t, "submitted", body)
+		})
+	}
+}
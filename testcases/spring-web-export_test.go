@@ -0,0 +1,80 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-echo"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportSpec(t *testing.T) {
+
+	c := SpringEcho.NewContainer(SpringWeb.ContainerConfig{})
+	c.GetBinding("/pet/:id", FindDerivedPet)
+	c.Route("/admin").WithDocGroup("admin").PostBinding("/pet", AddDerivedPet)
+
+	b, err := SpringWeb.ExportSpec(c, "", SpringWeb.SpecSwagger, "json")
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"/pet/{id}"`)
+	assert.NotContains(t, string(b), `"/admin/pet"`)
+	assert.Len(t, c.Mappers(), 2) // 没有注册文档接口
+
+	b, err = SpringWeb.ExportSpec(c, "admin", SpringWeb.SpecOpenAPI, "yaml")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(b), "openapi: 3.1.0\n"))
+	assert.Contains(t, string(b), "/admin/pet:")
+
+	// 分组名称写错时返回错误，而不是输出空的文档
+	_, err = SpringWeb.ExportSpec(c, "admni", SpringWeb.SpecSwagger, "json")
+	assert.EqualError(t, err, `doc group "admni" not found, available groups: [admin]`)
+
+	dir, err := ioutil.TempDir("", "spec")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	swaggerFile := filepath.Join(dir, "swagger.yaml")
+	openapiFile := filepath.Join(dir, "openapi.json")
+
+	ok, err := SpringWeb.ExportSpecFromArgs(c, []string{"-port", "8080", "--export-swagger=" + swaggerFile, "-export-openapi", openapiFile})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	b, err = ioutil.ReadFile(swaggerFile)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `swagger: "2.0"`)
+
+	b, err = ioutil.ReadFile(openapiFile)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"openapi": "3.1.0"`)
+
+	ok, err = SpringWeb.ExportSpecFromArgs(c, []string{"-port", "8080"})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	_, err = SpringWeb.ExportSpecFromArgs(c, []string{"-export-swagger"})
+	assert.NotNil(t, err)
+
+	_, err = SpringWeb.ExportSpecFromArgs(c, []string{"-export-group=admni", "-export-swagger", swaggerFile})
+	assert.NotNil(t, err)
+}
@@ -19,6 +19,7 @@ package testcases_test
 import (
 	"container/list"
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/go-spring/go-spring-parent/spring-logger"
@@ -48,3 +49,29 @@ func TestFilterChain(t *testing.T) {
 
 	assert.Equal(t, SpringUtils.NewList(2, 5, 5, 2), l)
 }
+
+// denyFilter 不调用 chain.Next 直接返回响应
+type denyFilter struct{}
+
+func (f *denyFilter) Invoke(ctx SpringWeb.WebContext, chain SpringWeb.FilterChain) {
+	ctx.String(http.StatusForbidden, "denied")
+}
+
+func TestFilterAbort(t *testing.T) {
+	for name, handler := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			called := false
+			m := SpringWeb.NewDefaultWebMapping()
+			m.GET("/deny", func(ctx SpringWeb.WebContext) {
+				called = true
+				ctx.String(http.StatusOK, "ok")
+			}, &denyFilter{})
+
+			w, body := doRequest(handler(m), http.MethodGet, "/deny", nil, nil)
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Equal(t, "denied", body)
+			assert.False(t, called)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-spring/go-spring-web/spring-echo"
+	"github.com/go-spring/go-spring-web/spring-gin"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+)
+
+// ginHandler 不启动服务器，直接使用 gin 引擎处理路由表中的请求
+func ginHandler(m SpringWeb.WebMapping, filters ...SpringWeb.Filter) http.Handler {
+	g := gin.New()
+	for _, mapper := range m.Mappers() {
+		path, wildCardName := SpringWeb.ToPathStyle(mapper.Path(), SpringWeb.GinPathStyle)
+		handlers := SpringGin.HandlerWrapper(mapper.Path(), mapper.Handler(), wildCardName, append(append([]SpringWeb.Filter{}, filters...), mapper.Filters()...))
+		for _, method := range SpringWeb.GetMethod(mapper.Method()) {
+			g.Handle(method, path, handlers...)
+		}
+	}
+	return g
+}
+
+// echoHandler 不启动服务器，直接使用 echo 引擎处理路由表中的请求
+func echoHandler(m SpringWeb.WebMapping, filters ...SpringWeb.Filter) http.Handler {
+	e := echo.New()
+	e.Validator = SpringWeb.NewBuiltInValidator()
+	for _, mapper := range m.Mappers() {
+		path, wildCardName := SpringWeb.ToPathStyle(mapper.Path(), SpringWeb.EchoPathStyle)
+		handler := SpringEcho.HandlerWrapper(mapper.Handler(), wildCardName, append(append([]SpringWeb.Filter{}, filters...), mapper.Filters()...))
+		for _, method := range SpringWeb.GetMethod(mapper.Method()) {
+			e.Add(method, path, handler)
+		}
+	}
+	return e
+}
+
+// adapters 所有的适配器
+var adapters = map[string]func(m SpringWeb.WebMapping, filters ...SpringWeb.Filter) http.Handler{
+	"SpringGin":  ginHandler,
+	"SpringEcho": echoHandler,
+}
+
+// doRequest 执行一个请求并返回响应
+func doRequest(h http.Handler, method, target string, body io.Reader, header map[string]string) (*httptest.ResponseRecorder, string) {
+	r := httptest.NewRequest(method, target, body)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	b, _ := ioutil.ReadAll(w.Result().Body)
+	return w, string(b)
+}
+
+func TestPathParam(t *testing.T) {
+	for name, handler := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			m := SpringWeb.NewDefaultWebMapping()
+			m.GET("/user/:id/*", func(ctx SpringWeb.WebContext) {
+				ctx.String(http.StatusOK, ctx.PathParam("id")+"|"+ctx.PathParam("*"))
+			})
+
+			_, body := doRequest(handler(m), http.MethodGet, "/user/42/a/b", nil, nil)
+			assert.Equal(t, "42|a/b", body)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-parent/spring-error"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/go-spring/go-spring-web/testcases"
+	"github.com/stretchr/testify/assert"
+)
+
+type JsonRpcEchoRequest struct {
+	Str string `json:"str" validate:"required,len=4"`
+}
+
+func TestJsonRpc(t *testing.T) {
+
+	rc := new(testcases.RpcService)
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.JSONRPC("/rpc").
+		Method("echo", func(req JsonRpcEchoRequest) *testcases.EchoResponse {
+			return &testcases.EchoResponse{Echo: "echo " + req.Str}
+		}).
+		Method("ctx_echo", rc.CtxEcho).
+		Method("fail", func(req *JsonRpcEchoRequest) string {
+			SpringError.ERROR.Panic(errors.New("fail")).When(true)
+			return ""
+		})
+
+	for name, adapter := range adapters {
+		h := adapter(m)
+
+		call := func(body string) (int, string) {
+			w, resp := doRequest(h, http.MethodPost, "/rpc", strings.NewReader(body),
+				map[string]string{SpringWeb.HeaderContentType: SpringWeb.MIMEApplicationJSON})
+			return w.Code, strings.TrimSpace(resp)
+		}
+
+		t.Run(name, func(t *testing.T) {
+
+			code, resp := call(`{"jsonrpc":"2.0","method":"echo","params":{"str":"abcd"},"id":1}`)
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, `{"jsonrpc":"2.0","result":{"echo":"echo abcd"},"id":1}`, resp)
+
+			_, resp = call(`{"jsonrpc":"2.0","method":"echo","params":[{"str":"abcd"}],"id":"a"}`)
+			assert.Equal(t, `{"jsonrpc":"2.0","result":{"echo":"echo abcd"},"id":"a"}`, resp)
+
+			_, resp = call(`{"jsonrpc":"2.0","method":"echo","params":{"str":"abc"},"id":2}`)
+			assert.Contains(t, resp, `"code":-32602`)
+
+			_, resp = call(`{"jsonrpc":"2.0","method":"none","id":3}`)
+			assert.Contains(t, resp, `"code":-32601`)
+
+			_, resp = call(`{"jsonrpc":"2.0","method":"fail","params":{"str":"abcd"},"id":4}`)
+			assert.Contains(t, resp, `"code":-32000`)
+			assert.Contains(t, resp, `"message":"ERROR"`)
+
+			_, resp = call(`{"jsonrpc":"2.0","method"`)
+			assert.Equal(t, `{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`, resp)
+
+			_, resp = call(`[]`)
+			assert.Contains(t, resp, `"code":-32600`)
+
+			code, resp = call(`{"jsonrpc":"2.0","method":"echo","params":{"str":"abcd"}}`)
+			assert.Equal(t, http.StatusNoContent, code)
+			assert.Equal(t, "", resp)
+
+			_, resp = call(`[
+				{"jsonrpc":"2.0","method":"echo","params":{"str":"abcd"},"id":1},
+				{"jsonrpc":"2.0","method":"echo","params":{"str":"abcd"}},
+				{"jsonrpc":"2.0","method":"ctx_echo","params":{"str":"efgh"},"id":2},
+				1
+			]`)
+			var batch []map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(resp), &batch))
+			assert.Equal(t, 3, len(batch))
+			assert.Equal(t, float64(2), batch[1]["id"])
+			assert.NotNil(t, batch[2]["error"])
+
+			_, resp = call(`{"jsonrpc":"2.0","method":"rpc.discover","id":5}`)
+			assert.Contains(t, resp, `"openrpc":"1.2.6"`)
+			assert.Contains(t, resp, `"name":"ctx_echo"`)
+		})
+	}
+}
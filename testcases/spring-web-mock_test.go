@@ -0,0 +1,222 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-echo"
+	"github.com/go-spring/go-spring-web/spring-gin"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+const mockSpec = `
+openapi: 3.0.3
+info:
+  title: mock
+  version: "1.0"
+servers:
+  - url: https://example.com/v1
+paths:
+  /pets/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+          minimum: 1
+    get:
+      operationId: getPet
+      responses:
+        "200":
+          description: ok
+          headers:
+            X-Rate-Limit:
+              schema:
+                type: integer
+                minimum: 10
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Pet"
+              examples:
+                cat:
+                  value: {id: 1, name: kitty, tag: cat}
+                dog:
+                  value: {id: 2, name: rex, tag: dog}
+        "404":
+          description: not found
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Error"
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: tags
+          in: query
+          schema:
+            type: array
+            items:
+              type: string
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: "#/components/schemas/Pet"
+    post:
+      operationId: addPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Pet"
+      responses:
+        "201":
+          description: created
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id, name]
+      properties:
+        id:
+          type: integer
+          format: int64
+        name:
+          type: string
+          minLength: 8
+        tag:
+          type: string
+          nullable: true
+        born:
+          type: string
+          format: date-time
+    Error:
+      type: object
+      properties:
+        message:
+          type: string
+          example: pet not found
+`
+
+func TestLoadOpenAPISpec(t *testing.T) {
+
+	s, err := SpringWeb.LoadSpec([]byte(mockSpec))
+	assert.Nil(t, err)
+	assert.Equal(t, "2.0", s.Swagger)
+	assert.Equal(t, "/v1", s.BasePath)
+	assert.Equal(t, "example.com", s.Host)
+
+	get := s.Paths.Paths["/pets/{id}"].Get
+	assert.Equal(t, "getPet", get.ID)
+	assert.Equal(t, "int64", s.Definitions["Pet"].Properties["id"].Format)
+	assert.True(t, s.Definitions["Pet"].Properties["tag"].Extensions["x-nullable"].(bool))
+	assert.Equal(t, "#/definitions/Pet", get.Responses.StatusCodeResponses[200].Schema.Ref.String())
+	assert.Equal(t, "integer", get.Responses.StatusCodeResponses[200].Headers["X-Rate-Limit"].Type)
+
+	list := s.Paths.Paths["/pets"].Get
+	assert.Equal(t, "multi", list.Parameters[0].CollectionFormat)
+
+	post := s.Paths.Paths["/pets"].Post
+	assert.Equal(t, "body", post.Parameters[0].In)
+	assert.True(t, post.Parameters[0].Required)
+	assert.Equal(t, []string{"application/json"}, post.Consumes)
+
+	// 导出的 OpenAPI 文档转换回来之后和原文档一致
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+	c.GetBinding("/pet/:id", FindDerivedPet)
+	c.PostBinding("/pet", AddDerivedPet)
+
+	b, err := SpringWeb.ExportSpec(c, "", SpringWeb.SpecSwagger, "json")
+	assert.Nil(t, err)
+	old, err := SpringWeb.LoadSpec(b)
+	assert.Nil(t, err)
+
+	b, err = SpringWeb.ExportSpec(c, "", SpringWeb.SpecOpenAPI, "yaml")
+	assert.Nil(t, err)
+	new, err := SpringWeb.LoadSpec(b)
+	assert.Nil(t, err)
+
+	assert.Empty(t, SpringWeb.DiffSwagger(old, new).Changes)
+}
+
+func TestMockServer(t *testing.T) {
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			c := SpringEcho.NewContainer(SpringWeb.ContainerConfig{})
+			assert.Nil(t, SpringWeb.BuildMock(c, []byte(mockSpec), SpringWeb.MockConfig{Validate: true}))
+			h := specValidationHandler(adapter, c)
+
+			w, body := doRequest(h, http.MethodGet, "/v1/pets/1", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "10", w.Header().Get("X-Rate-Limit"))
+			assert.JSONEq(t, `{"id":1,"name":"kitty","tag":"cat"}`, body)
+
+			w, body = doRequest(h, http.MethodGet, "/v1/pets/1", nil, map[string]string{SpringWeb.HeaderMockExample: "dog"})
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, `{"id":2,"name":"rex","tag":"dog"}`, body)
+
+			w, body = doRequest(h, http.MethodGet, "/v1/pets/1", nil, map[string]string{SpringWeb.HeaderMockStatus: "404"})
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.JSONEq(t, `{"message":"pet not found"}`, body)
+
+			w, body = doRequest(h, http.MethodGet, "/v1/pets?tags=a&tags=b", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			var pets []map[string]interface{}
+			assert.Nil(t, json.Unmarshal([]byte(body), &pets))
+			assert.Equal(t, []map[string]interface{}{{
+				"id":   float64(0),
+				"name": "stringxx",
+				"tag":  "string",
+				"born": "2020-01-01T00:00:00Z",
+			}}, pets)
+
+			w, _ = doRequest(h, http.MethodGet, "/v1/pets/0", nil, nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			w, _ = doRequest(h, http.MethodPost, "/v1/pets", strings.NewReader(`{"id":3}`),
+				map[string]string{SpringWeb.HeaderContentType: SpringWeb.MIMEApplicationJSON})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+
+			w, _ = doRequest(h, http.MethodPost, "/v1/pets", strings.NewReader(`{"id":3,"name":"longname"}`),
+				map[string]string{SpringWeb.HeaderContentType: SpringWeb.MIMEApplicationJSON})
+			assert.Equal(t, http.StatusCreated, w.Code)
+
+			failing := SpringEcho.NewContainer(SpringWeb.ContainerConfig{})
+			assert.Nil(t, SpringWeb.BuildMock(failing, []byte(mockSpec), SpringWeb.MockConfig{
+				ErrorRate: 1,
+				ErrorCode: http.StatusServiceUnavailable,
+			}))
+			w, _ = doRequest(adapter(failing), http.MethodGet, "/v1/pets", nil, nil)
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		})
+	}
+}
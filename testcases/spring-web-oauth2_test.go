@@ -0,0 +1,209 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web/spring-gin"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// introspectionStub 返回本地的令牌内省端点和调用次数
+func introspectionStub(t *testing.T, tokens map[string]map[string]interface{}) (*httptest.Server, *int) {
+	calls := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok && user == "rs" && pass == "rs-secret")
+		assert.Equal(t, "access_token", r.PostFormValue("token_type_hint"))
+		resp, ok := tokens[r.PostFormValue("token")]
+		if !ok {
+			resp = map[string]interface{}{"active": false}
+		}
+		w.Header().Set(SpringWeb.HeaderContentType, SpringWeb.MIMEApplicationJSON)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	return s, &calls
+}
+
+func TestOAuth2ResourceServer(t *testing.T) {
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	exp := time.Now().Add(time.Hour).Unix()
+
+	stub, calls := introspectionStub(t, map[string]map[string]interface{}{
+		"opaque-read":  {"active": true, "sub": "tom", "scope": "pets:read", "exp": exp},
+		"opaque-write": {"active": true, "username": "jerry", "scope": "pets:read pets:write"},
+		"opaque-old":   {"active": true, "sub": "tom", "scope": "pets:read", "exp": time.Now().Add(-time.Minute).Unix()},
+	})
+	defer stub.Close()
+
+	rs := SpringWeb.NewOAuth2ResourceServer(SpringWeb.OAuth2Config{
+		TokenUrl: "https://auth.example.com/token",
+		Scopes:   map[string]string{"pets:read": "read pets"},
+		Jwt:      &SpringWeb.JwtConfig{Secret: secret},
+		Introspection: &SpringWeb.IntrospectionConfig{
+			Endpoint:     stub.URL,
+			ClientId:     "rs",
+			ClientSecret: "rs-secret",
+			CacheTTL:     time.Minute,
+		},
+	})
+
+	m := SpringWeb.NewDefaultWebMapping()
+	r := m.Route("/pets", rs.RequireScopes())
+	r.GetMapping("/list", func(ctx SpringWeb.WebContext) {
+		p := SpringWeb.GetPrincipal(ctx)
+		ctx.String(http.StatusOK, "%s:%s", p.Name, strings.Join(p.Scopes, ","))
+	}, rs.RequireScopes("pets:read"))
+	r.PostMapping("/add", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "added")
+	}, rs.RequireScopes("pets:read", "pets:write"))
+
+	jwt := func(scope interface{}) string {
+		token, err := SpringWeb.SignJwt("HS256", "", secret, SpringWeb.JwtClaims{"sub": "tom", "exp": exp, "scp": scope})
+		assert.Nil(t, err)
+		return token
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m)
+
+			// JWT 格式的令牌在本地验证
+			before := *calls
+			w, body := doRequest(h, http.MethodGet, "/pets/list", nil, bearer(jwt([]string{"pets:read"})))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "tom:pets:read", body)
+			assert.Equal(t, before, *calls)
+
+			w, _ = doRequest(h, http.MethodPost, "/pets/add", nil, bearer(jwt([]string{"pets:read"})))
+			assert.Equal(t, http.StatusForbidden, w.Code)
+			assert.Equal(t, `Bearer error="insufficient_scope", scope="pets:read pets:write"`,
+				w.Header().Get(SpringWeb.HeaderWWWAuthenticate))
+
+			// 其他令牌调用内省端点验证
+			_, body = doRequest(h, http.MethodGet, "/pets/list", nil, bearer("opaque-write"))
+			assert.Equal(t, "jerry:pets:read,pets:write", body)
+			w, body = doRequest(h, http.MethodPost, "/pets/add", nil, bearer("opaque-write"))
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "added", body)
+			assert.True(t, *calls-before <= 1) // 内省结果被缓存
+
+			w, _ = doRequest(h, http.MethodPost, "/pets/add", nil, bearer("opaque-read"))
+			assert.Equal(t, http.StatusForbidden, w.Code)
+
+			for _, token := range []string{"opaque-unknown", "opaque-old", jwt("pets:read") + "x"} {
+				w, _ = doRequest(h, http.MethodGet, "/pets/list", nil, bearer(token))
+				assert.Equal(t, http.StatusUnauthorized, w.Code, token)
+				assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get(SpringWeb.HeaderWWWAuthenticate), token)
+			}
+
+			w, _ = doRequest(h, http.MethodGet, "/pets/list", nil, nil)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+
+	// 内省端点不可用或者超时时返回 503
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	for _, cfg := range []*SpringWeb.IntrospectionConfig{
+		{Endpoint: closed.URL},
+		{Endpoint: slow.URL, Client: &http.Client{Timeout: 20 * time.Millisecond}},
+	} {
+		down := SpringWeb.NewOAuth2ResourceServer(SpringWeb.OAuth2Config{Introspection: cfg})
+		m := SpringWeb.NewDefaultWebMapping()
+		m.GetMapping("/pets", func(ctx SpringWeb.WebContext) {}, down.RequireScopes())
+		for name, adapter := range adapters {
+			w, body := doRequest(adapter(m), http.MethodGet, "/pets", nil, bearer("opaque-read"))
+			assert.Equal(t, http.StatusServiceUnavailable, w.Code, name)
+			assert.Equal(t, SpringWeb.ErrIntrospectionUnavailable.Error(), body, name)
+		}
+	}
+
+	assert.Panics(t, func() { SpringWeb.NewOAuth2ResourceServer(SpringWeb.OAuth2Config{}) })
+}
+
+func TestIntrospectionCache(t *testing.T) {
+
+	stub, calls := introspectionStub(t, map[string]map[string]interface{}{
+		"a": {"active": true, "sub": "a"},
+		"b": {"active": true, "sub": "b"},
+	})
+	defer stub.Close()
+
+	rs := SpringWeb.NewOAuth2ResourceServer(SpringWeb.OAuth2Config{
+		Introspection: &SpringWeb.IntrospectionConfig{
+			Endpoint:     stub.URL,
+			ClientId:     "rs",
+			ClientSecret: "rs-secret",
+			CacheTTL:     time.Minute,
+			CacheSize:    1,
+		},
+	})
+
+	ctx := httptest.NewRequest(http.MethodGet, "/pets", nil).Context()
+	for _, token := range []string{"a", "a", "b", "b", "a"} {
+		p, err := rs.Authenticate(ctx, token)
+		assert.Nil(t, err)
+		assert.Equal(t, token, p.Name)
+	}
+
+	// 缓存只能保存一个结果，b 淘汰了 a
+	assert.Equal(t, 3, *calls)
+}
+
+func TestOAuth2SecurityDocs(t *testing.T) {
+
+	rs := SpringWeb.NewOAuth2ResourceServer(SpringWeb.OAuth2Config{
+		Name:             "PetAuth",
+		AuthorizationUrl: "https://auth.example.com/authorize",
+		TokenUrl:         "https://auth.example.com/token",
+		Scopes:           map[string]string{"pets:read": "read pets"},
+		Jwt:              &SpringWeb.JwtConfig{Secret: []byte("secret")},
+	})
+
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+	r := c.Route("/v1", rs.RequireScopes("pets:read"))
+	r.GetBinding("/pet/:id", FindDerivedPet)
+	r.PostBinding("/pet", AddDerivedPet, rs.RequireScopes("pets:read", "pets:write"))
+
+	b, err := SpringWeb.ExportSpec(c, "", SpringWeb.SpecSwagger, "json")
+	assert.Nil(t, err)
+	s, err := SpringWeb.LoadSpec(b)
+	assert.Nil(t, err)
+
+	scheme := s.SecurityDefinitions["PetAuth"]
+	assert.Equal(t, "oauth2", scheme.Type)
+	assert.Equal(t, "accessCode", scheme.Flow)
+	assert.Equal(t, map[string]string{"pets:read": "read pets", "pets:write": ""}, scheme.Scopes)
+
+	assert.Equal(t, []map[string][]string{{"PetAuth": {"pets:read"}}}, s.Paths.Paths["/v1/pet/{id}"].Get.Security)
+	assert.Equal(t, []map[string][]string{{"PetAuth": {"pets:read", "pets:write"}}}, s.Paths.Paths["/v1/pet"].Post.Security)
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-parent/spring-logger"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordLogger 记录日志内容的 Logger
+type recordLogger struct {
+	*SpringLogger.Console
+	lines *[]string
+}
+
+func (l *recordLogger) Warnf(format string, args ...interface{}) {
+	*l.lines = append(*l.lines, fmt.Sprintf(format, args...))
+}
+
+// policyUsers 测试使用的 API Key 和主体
+var policyUsers = map[string]*SpringWeb.Principal{
+	"admin": {Name: "alice", Roles: []string{"admin"}},
+	"user":  {Name: "bob", Roles: []string{"user"}},
+	"eve":   {Name: "eve", Roles: []string{"user"}},
+}
+
+// policyAuthFilter 根据 API Key 设置认证主体，没有 API Key 时为匿名访问
+type policyAuthFilter struct{}
+
+func (f *policyAuthFilter) Invoke(ctx SpringWeb.WebContext, chain SpringWeb.FilterChain) {
+	if p, ok := policyUsers[ctx.GetHeader("X-API-Key")]; ok {
+		SpringWeb.SetPrincipal(ctx, p)
+	}
+	chain.Next(ctx)
+}
+
+// policyHandler 使用 API Key 认证 (允许匿名访问) 并且根据 policy 授权
+func policyHandler(adapter func(SpringWeb.WebMapping, ...SpringWeb.Filter) http.Handler, policy *SpringWeb.Policy, dryRun bool) http.Handler {
+
+	m := SpringWeb.NewDefaultWebMapping()
+	ok := func(ctx SpringWeb.WebContext) { ctx.String(http.StatusOK, "ok") }
+	m.GetMapping("/home", ok)
+	m.GetMapping("/pets/:id", ok)
+	m.DELETE("/pets/:id", SpringWeb.FUNC(ok))
+	m.GetMapping("/admin/*", ok)
+	m.GetMapping("/public/*", ok)
+
+	return adapter(m, new(policyAuthFilter), SpringWeb.AuthorizationFilter(SpringWeb.AuthorizationConfig{Policy: policy, DryRun: dryRun}))
+}
+
+func TestAuthorizationFilter(t *testing.T) {
+
+	policy := SpringWeb.NewPolicy(
+		SpringWeb.PolicyRule{Subject: "anonymous", Method: SpringWeb.MethodGet, Path: "/home/"},
+		SpringWeb.PolicyRule{Subject: "*", Method: SpringWeb.MethodGet, Path: "/"},
+		SpringWeb.PolicyRule{Subject: "*", Method: SpringWeb.MethodGet, Path: "/public/*"},
+		SpringWeb.PolicyRule{Subject: "anonymous", Method: SpringWeb.MethodGet, Path: "/public/{*}"},
+		SpringWeb.PolicyRule{Subject: "role:user", Method: SpringWeb.MethodGet, Path: "/pets/{id}"},
+		SpringWeb.PolicyRule{Subject: "role:admin", Method: SpringWeb.MethodAny, Path: "/pets/:id"},
+		SpringWeb.PolicyRule{Subject: "role:admin", Method: SpringWeb.MethodGet, Path: "/admin/*path"},
+		SpringWeb.PolicyRule{Subject: "user:eve", Method: SpringWeb.MethodAny, Path: "/pets/1", Effect: "deny"},
+	)
+
+	cases := []struct {
+		method string
+		path   string
+		key    string
+		code   int
+	}{
+		{http.MethodGet, "/home", "", http.StatusOK},
+		{http.MethodGet, "/home", "user", http.StatusForbidden}, // anonymous 只匹配没有认证的请求
+		{http.MethodGet, "/public/a/b", "", http.StatusOK},
+		{http.MethodGet, "/public/a/b", "user", http.StatusOK},
+		{http.MethodGet, "/pets/1", "", http.StatusUnauthorized},
+		{http.MethodGet, "/pets/1", "user", http.StatusOK},
+		{http.MethodDelete, "/pets/1", "user", http.StatusForbidden},
+		{http.MethodDelete, "/pets/1", "admin", http.StatusOK},
+		{http.MethodGet, "/pets/1", "eve", http.StatusForbidden}, // deny 优先于 allow
+		{http.MethodGet, "/pets/2", "eve", http.StatusOK},
+		{http.MethodGet, "/admin/users", "admin", http.StatusOK},
+		{http.MethodGet, "/admin/users", "user", http.StatusForbidden},
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := policyHandler(adapter, policy, false)
+			for _, c := range cases {
+				w, _ := doRequest(h, c.method, c.path, nil, map[string]string{"X-API-Key": c.key})
+				assert.Equal(t, c.code, w.Code, "%s %s %s", c.method, c.path, c.key)
+			}
+		})
+	}
+
+	// WithCors 把跨域过滤器放在路由分组的认证和授权过滤器前面，处理所有方法的接口
+	// 收到的不带凭证的预检请求直接返回，真正的请求仍然需要授权
+	m := SpringWeb.NewDefaultWebMapping()
+	m.Route("/cors", new(policyAuthFilter), SpringWeb.AuthorizationFilter(SpringWeb.AuthorizationConfig{Policy: policy})).
+		WithCors(SpringWeb.CorsConfig{AllowOrigins: []string{"https://app.example.com"}}).
+		Request(SpringWeb.MethodAny, "/pets/:id", SpringWeb.FUNC(func(ctx SpringWeb.WebContext) { ctx.String(http.StatusOK, "ok") }))
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m)
+
+			w, _ := doRequest(h, http.MethodOptions, "/cors/pets/1", nil, map[string]string{
+				SpringWeb.HeaderOrigin:                     "https://app.example.com",
+				SpringWeb.HeaderAccessControlRequestMethod: http.MethodDelete,
+			})
+			assert.Equal(t, http.StatusNoContent, w.Code)
+			assert.Equal(t, "https://app.example.com", w.Header().Get(SpringWeb.HeaderAccessControlAllowOrigin))
+
+			w, _ = doRequest(h, http.MethodDelete, "/cors/pets/1", nil, map[string]string{
+				SpringWeb.HeaderOrigin: "https://app.example.com",
+			})
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+
+	// 根路径只匹配根路径
+	ok, _ := policy.Authorize(policyUsers["user"], http.MethodGet, "/")
+	assert.True(t, ok)
+	ok, _ = policy.Authorize(policyUsers["user"], http.MethodGet, "/other")
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		SpringWeb.NewPolicy(SpringWeb.PolicyRule{Subject: "admin", Method: SpringWeb.MethodGet, Path: "/"})
+	})
+	assert.Panics(t, func() {
+		SpringWeb.NewPolicy(SpringWeb.PolicyRule{Subject: "*", Method: SpringWeb.MethodGet, Path: "/{a"})
+	})
+	assert.Panics(t, func() { SpringWeb.NewPolicy(SpringWeb.PolicyRule{Subject: "*", Path: "/"}) })
+}
+
+func TestAuthorizationDryRun(t *testing.T) {
+
+	var lines []string
+	SpringLogger.Logger = func(ctx context.Context, tags ...string) SpringLogger.StdLogger {
+		return &recordLogger{Console: SpringLogger.NewConsole(SpringLogger.InfoLevel), lines: &lines}
+	}
+	defer func() { SpringLogger.Logger = nil }()
+
+	policy := SpringWeb.NewPolicy(SpringWeb.PolicyRule{Subject: "role:admin", Method: SpringWeb.MethodAny, Path: "/pets/:id"})
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			lines = nil
+			h := policyHandler(adapter, policy, true)
+
+			w, _ := doRequest(h, http.MethodDelete, "/pets/1", nil, map[string]string{"X-API-Key": "user"})
+			assert.Equal(t, http.StatusOK, w.Code)
+			w, _ = doRequest(h, http.MethodDelete, "/pets/1", nil, map[string]string{"X-API-Key": "admin"})
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			assert.Equal(t, []string{"authorization dry run: would deny DELETE /pets/1 for bob, no matching rule"}, lines)
+		})
+	}
+}
+
+func TestPolicyFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "policy")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "policy.yaml")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`
+rules:
+  - subject: role:user
+    methods: [GET, HEAD]
+    path: /pets/{id}
+`), 0644))
+
+	policy, err := SpringWeb.LoadPolicyFile(file)
+	assert.Nil(t, err)
+	assert.Equal(t, []SpringWeb.PolicyRule{
+		{Subject: "role:user", Method: SpringWeb.MethodGet | SpringWeb.MethodHead, Path: "/pets/{id}", Effect: "allow"},
+	}, policy.Rules())
+
+	user := policyUsers["user"]
+	ok, _ := policy.Authorize(user, http.MethodDelete, "/pets/1")
+	assert.False(t, ok)
+
+	stop := policy.Watch(10 * time.Millisecond)
+	defer stop()
+
+	// 错误的文件不会替换现有的规则，先写入临时文件再重命名，检查时不会读到写了一半的文件
+	writePolicy := func(content string, mod time.Time) {
+		tmp := file + ".tmp"
+		assert.Nil(t, ioutil.WriteFile(tmp, []byte(content), 0644))
+		assert.Nil(t, os.Chtimes(tmp, mod, mod))
+		assert.Nil(t, os.Rename(tmp, file))
+	}
+
+	writePolicy(`{"rules": [{"subject": "role:user", "methods": ["PURGE"], "path": "/pets/:id"}]}`, time.Now().Add(time.Hour))
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, policy.Rules(), 1)
+
+	writePolicy(`{"rules": [{"subject": "role:user", "methods": ["*"], "path": "/pets/:id"}]}`, time.Now().Add(2*time.Hour))
+	for i := 0; i < 100 && policy.Rules()[0].Method != SpringWeb.MethodAny; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ok, rule := policy.Authorize(user, http.MethodDelete, "/pets/1")
+	assert.True(t, ok)
+	assert.Equal(t, "/pets/:id", rule.Path)
+
+	_, err = SpringWeb.LoadPolicyFile(filepath.Join(dir, "missing.yaml"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// brokenRateLimitStore 总是返回错误的存储
+type brokenRateLimitStore struct{}
+
+func (s brokenRateLimitStore) Take(key string, quota SpringWeb.RateLimitQuota, now time.Time) (SpringWeb.RateLimitResult, error) {
+	return SpringWeb.RateLimitResult{}, errors.New("store is down")
+}
+
+func TestRateLimitFilter(t *testing.T) {
+
+	ok := func(ctx SpringWeb.WebContext) { ctx.String(http.StatusOK, "ok") }
+
+	owners := map[string]string{"alice-1": "alice", "alice-2": "alice", "bob-1": "bob"}
+	apiKey := SpringWeb.ApiKeyFilter(SpringWeb.ApiKeyConfig{
+		Key: "X-Api-Key",
+		Validator: func(key string) *SpringWeb.Principal {
+			if owner, ok := owners[key]; ok {
+				return &SpringWeb.Principal{Name: owner}
+			}
+			return nil
+		},
+	})
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			m := SpringWeb.NewDefaultWebMapping()
+			m.GetMapping("/pets", ok, apiKey, SpringWeb.RateLimitFilter(SpringWeb.RateLimitConfig{
+				Quota: SpringWeb.RateLimitQuota{Limit: 3, Period: time.Minute},
+				Key:   SpringWeb.RateLimitByApiKey(),
+			}))
+			m.GetMapping("/search", ok, SpringWeb.RateLimitFilter(SpringWeb.RateLimitConfig{
+				Quota: SpringWeb.RateLimitQuota{Limit: 1, Period: time.Hour},
+				Key:   SpringWeb.RateLimitByRoute(),
+			}))
+			m.GetMapping("/down", ok, SpringWeb.RateLimitFilter(SpringWeb.RateLimitConfig{
+				Quota: SpringWeb.RateLimitQuota{Limit: 1, Period: time.Hour},
+				Store: brokenRateLimitStore{},
+			}))
+			m.Request(SpringWeb.MethodAny, "/any", SpringWeb.FUNC(ok), SpringWeb.RateLimitFilter(SpringWeb.RateLimitConfig{
+				Quota: SpringWeb.RateLimitQuota{Limit: 1, Period: time.Hour},
+			}))
+
+			h := adapter(m, SpringWeb.RateLimitFilter(SpringWeb.RateLimitConfig{
+				Quota: SpringWeb.RateLimitQuota{Limit: 100, Period: time.Minute},
+			}))
+
+			alice := map[string]string{"X-Api-Key": "alice-1"}
+
+			for i := 2; i >= 0; i-- {
+				w, body := doRequest(h, http.MethodGet, "/pets", nil, alice)
+				assert.Equal(t, "ok", body)
+				assert.Equal(t, "3", w.Header().Get(SpringWeb.HeaderRateLimitLimit))
+				assert.Equal(t, strconv.Itoa(i), w.Header().Get(SpringWeb.HeaderRateLimitRemaining))
+			}
+
+			// 超出配额
+			w, body := doRequest(h, http.MethodGet, "/pets", nil, alice)
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "rate limit exceeded", body)
+			assert.Equal(t, "20", w.Header().Get(SpringWeb.HeaderRetryAfter))
+			assert.Equal(t, "60", w.Header().Get(SpringWeb.HeaderRateLimitReset))
+
+			// 同一个所有者的其他 API Key 共享配额，未经认证的 API Key 不能获得新的配额
+			w, _ = doRequest(h, http.MethodGet, "/pets", nil, map[string]string{"X-Api-Key": "alice-2"})
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			w, _ = doRequest(h, http.MethodGet, "/pets", nil, map[string]string{"X-Api-Key": "bob"})
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+			// 不同的所有者使用不同的配额
+			w, _ = doRequest(h, http.MethodGet, "/pets", nil, map[string]string{"X-Api-Key": "bob-1"})
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			// 处理函数的配额和全局配额同时生效，输出剩余配额最少的
+			w, _ = doRequest(h, http.MethodGet, "/search", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "1", w.Header().Get(SpringWeb.HeaderRateLimitLimit))
+			assert.Equal(t, "0", w.Header().Get(SpringWeb.HeaderRateLimitRemaining))
+			w, _ = doRequest(h, http.MethodGet, "/search", nil, nil)
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, "3600", w.Header().Get(SpringWeb.HeaderRetryAfter))
+
+			// 存储出错时放行请求
+			for i := 0; i < 3; i++ {
+				w, _ = doRequest(h, http.MethodGet, "/down", nil, nil)
+				assert.Equal(t, http.StatusOK, w.Code)
+			}
+
+			// 预检请求同样受限流控制
+			preflight := map[string]string{
+				SpringWeb.HeaderOrigin:                     "https://app.example.com",
+				SpringWeb.HeaderAccessControlRequestMethod: http.MethodGet,
+			}
+			w, _ = doRequest(h, http.MethodOptions, "/any", nil, preflight)
+			assert.Equal(t, http.StatusOK, w.Code)
+			w, _ = doRequest(h, http.MethodOptions, "/any", nil, preflight)
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		})
+	}
+
+	assert.Panics(t, func() { SpringWeb.RateLimitFilter(SpringWeb.RateLimitConfig{}) })
+}
+
+func TestRateLimitByClientIP(t *testing.T) {
+
+	ok := func(ctx SpringWeb.WebContext) { ctx.String(http.StatusOK, "ok") }
+	quota := SpringWeb.RateLimitQuota{Limit: 1, Period: time.Hour}
+
+	forwarded := func(ip string) map[string]string {
+		return map[string]string{SpringWeb.HeaderXForwardedFor: ip}
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			m := SpringWeb.NewDefaultWebMapping()
+			m.GetMapping("/direct", ok, SpringWeb.RateLimitFilter(SpringWeb.RateLimitConfig{Quota: quota}))
+			m.GetMapping("/proxied", ok, SpringWeb.RateLimitFilter(SpringWeb.RateLimitConfig{
+				Quota: quota,
+				Key:   SpringWeb.RateLimitByTrustedClientIP(SpringWeb.NewTrustedProxies("192.0.2.1")),
+			}))
+			h := adapter(m)
+
+			// 默认使用连接的对端地址，伪造 X-Forwarded-For 不能获得新的配额
+			w, _ := doRequest(h, http.MethodGet, "/direct", nil, forwarded("198.51.100.1"))
+			assert.Equal(t, http.StatusOK, w.Code)
+			w, _ = doRequest(h, http.MethodGet, "/direct", nil, forwarded("198.51.100.2"))
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+			// 来自可信代理的请求使用 X-Forwarded-For 中的客户端地址
+			w, _ = doRequest(h, http.MethodGet, "/proxied", nil, forwarded("198.51.100.1"))
+			assert.Equal(t, http.StatusOK, w.Code)
+			w, _ = doRequest(h, http.MethodGet, "/proxied", nil, forwarded("198.51.100.2"))
+			assert.Equal(t, http.StatusOK, w.Code)
+			w, _ = doRequest(h, http.MethodGet, "/proxied", nil, forwarded("198.51.100.1"))
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		})
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+
+	store := SpringWeb.NewMemoryRateLimitStore(4)
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("TokenBucket", func(t *testing.T) {
+		quota := SpringWeb.RateLimitQuota{Limit: 2, Period: 10 * time.Second, Burst: 4}
+
+		for i := 3; i >= 0; i-- {
+			r, err := store.Take("bucket", quota, now)
+			assert.NoError(t, err)
+			assert.True(t, r.Allowed)
+			assert.Equal(t, 4, r.Limit)
+			assert.Equal(t, i, r.Remaining)
+		}
+
+		r, _ := store.Take("bucket", quota, now)
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 5*time.Second, r.RetryAfter)
+		assert.Equal(t, 20*time.Second, r.Reset)
+
+		// 每 5 秒恢复一个令牌
+		r, _ = store.Take("bucket", quota, now.Add(5*time.Second))
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 0, r.Remaining)
+
+		// 空闲足够长的时间之后恢复到容量
+		r, _ = store.Take("bucket", quota, now.Add(time.Minute))
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3, r.Remaining)
+	})
+
+	t.Run("SlidingWindow", func(t *testing.T) {
+		quota := SpringWeb.RateLimitQuota{Limit: 4, Period: 10 * time.Second, Algorithm: SpringWeb.RateLimitSlidingWindow}
+
+		for i := 3; i >= 0; i-- {
+			r, _ := store.Take("window", quota, now.Add(5*time.Second))
+			assert.True(t, r.Allowed)
+			assert.Equal(t, i, r.Remaining)
+		}
+
+		r, _ := store.Take("window", quota, now.Add(6*time.Second))
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 4*time.Second, r.RetryAfter)
+
+		// 下一个窗口开始时上一个窗口的请求按照剩余时间加权，4 * 0.5 = 2
+		r, _ = store.Take("window", quota, now.Add(15*time.Second))
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 1, r.Remaining)
+		r, _ = store.Take("window", quota, now.Add(15*time.Second))
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 0, r.Remaining)
+
+		// 4 * w + 2 + 1 <= 4 需要 w <= 0.25，也就是到 17.5 秒
+		r, _ = store.Take("window", quota, now.Add(15*time.Second))
+		assert.False(t, r.Allowed)
+		assert.Equal(t, 2500*time.Millisecond, r.RetryAfter)
+
+		r, _ = store.Take("window", quota, now.Add(17500*time.Millisecond))
+		assert.True(t, r.Allowed)
+
+		// 中间间隔了完整的窗口之后重新计数
+		r, _ = store.Take("window", quota, now.Add(time.Minute))
+		assert.True(t, r.Allowed)
+		assert.Equal(t, 3, r.Remaining)
+	})
+}
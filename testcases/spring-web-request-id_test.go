@@ -0,0 +1,161 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// captureStdout 返回 fn 执行期间默认的 SpringLogger.Console 输出到标准输出的日志
+func captureStdout(t *testing.T, fn func()) []string {
+
+	r, w, err := os.Pipe()
+	if !assert.NoError(t, err) {
+		return nil
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+
+	done := make(chan []string)
+	go func() {
+		var lines []string
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		_, _ = io.Copy(ioutil.Discard, r)
+		done <- lines
+	}()
+
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	fn()
+	_ = w.Close()
+	return <-done
+}
+
+func TestRequestIdFilter(t *testing.T) {
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/log", func(ctx SpringWeb.WebContext) {
+		ctx.LogInfo("hello")
+		ctx.LogErrorf("code %d", 7)
+		ctx.Logger().Info("world")
+		ctx.String(http.StatusOK, SpringWeb.GetRequestId(ctx)+" "+SpringWeb.RequestIdFromContext(ctx.Request().Context()))
+	})
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	// 日志的调用位置是处理函数，而不是封装日志的代码
+	logLine := func(prefix, msg string) *regexp.Regexp {
+		return regexp.MustCompile(`\] testcases/spring-web-request-id_test\.go:\d+ ` + regexp.QuoteMeta(prefix+msg) + `$`)
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			h := adapter(m, SpringWeb.RequestIdFilter(SpringWeb.RequestIdConfig{}))
+
+			var (
+				w    *httptest.ResponseRecorder
+				body string
+			)
+
+			lines := captureStdout(t, func() {
+				w, body = doRequest(h, http.MethodGet, "/log", nil, map[string]string{
+					SpringWeb.HeaderXRequestId: "abc-123",
+				})
+			})
+			assert.Equal(t, "abc-123", w.Header().Get(SpringWeb.HeaderXRequestId))
+			assert.Equal(t, "abc-123 abc-123", body)
+			if assert.Len(t, lines, 3) {
+				assert.Regexp(t, logLine("[request_id:abc-123] ", "hello"), lines[0])
+				assert.Regexp(t, logLine("[request_id:abc-123] ", "code 7"), lines[1])
+				assert.Regexp(t, logLine("[request_id:abc-123] ", "world"), lines[2])
+			}
+
+			// 非法的请求 ID 会被替换
+			lines = captureStdout(t, func() {
+				w, _ = doRequest(h, http.MethodGet, "/log", nil, map[string]string{
+					SpringWeb.HeaderXRequestId: "bad id\n",
+				})
+			})
+			id := w.Header().Get(SpringWeb.HeaderXRequestId)
+			assert.Regexp(t, uuid, id)
+			if assert.Len(t, lines, 3) {
+				assert.Regexp(t, logLine("[request_id:"+id+"] ", "hello"), lines[0])
+			}
+
+			h = adapter(m, SpringWeb.RequestIdFilter(SpringWeb.RequestIdConfig{
+				Header:         "X-Trace-Id",
+				Generator:      func() string { return "fixed" },
+				IgnoreIncoming: true,
+			}))
+			w, body = doRequest(h, http.MethodGet, "/log", nil, map[string]string{"X-Trace-Id": "client"})
+			assert.Equal(t, "fixed", w.Header().Get("X-Trace-Id"))
+			assert.Equal(t, "fixed fixed", body)
+
+			// 没有使用过滤器时日志不带前缀
+			lines = captureStdout(t, func() {
+				_, body = doRequest(adapter(m), http.MethodGet, "/log", nil, nil)
+			})
+			assert.Equal(t, " ", body)
+			if assert.Len(t, lines, 3) {
+				assert.Regexp(t, logLine("", "hello"), lines[0])
+				assert.False(t, strings.Contains(lines[0], "request_id"))
+			}
+		})
+	}
+}
+
+func TestRequestIdGenerator(t *testing.T) {
+
+	ulid := SpringWeb.ULIDGenerator()
+	a, b := ulid(), ulid()
+	assert.Regexp(t, `^[0-7][0-9A-HJKMNP-TV-Z]{25}$`, a)
+	assert.NotEqual(t, a, b)
+	assert.True(t, a[:10] <= b[:10])
+
+	snowflake := SpringWeb.SnowflakeGenerator(5)
+	prev := int64(0)
+	for i := 0; i < 5000; i++ {
+		id, err := strconv.ParseInt(snowflake(), 10, 64)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5), id>>12&0x3ff)
+		if !assert.True(t, id > prev) {
+			break
+		}
+		prev = id
+	}
+
+	assert.Panics(t, func() { SpringWeb.SnowflakeGenerator(1024) })
+}
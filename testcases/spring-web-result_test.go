@@ -0,0 +1,162 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// petRequest 查询宠物的请求
+type petRequest struct {
+	Str string `query:"str" form:"str"`
+}
+
+var errPetNotFound = errors.New("pet not found")
+
+// wrapError 使用 Unwrap 约定包装的错误
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *wrapError) Unwrap() error { return e.err }
+
+// envelope 公司内部的响应格式
+type envelope struct {
+	Success bool        `json:"success"`
+	Code    int32       `json:"code,omitempty"`
+	Message string      `json:"message,omitempty"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+type envelopeWrapper struct{}
+
+func (w *envelopeWrapper) Success(ctx SpringWeb.WebContext, data interface{}) interface{} {
+	return &envelope{Success: true, Payload: data}
+}
+
+func (w *envelopeWrapper) Failure(ctx SpringWeb.WebContext, f *SpringWeb.RpcFailure) interface{} {
+	return &envelope{Code: f.Code, Message: f.Msg}
+}
+
+func TestErrorCatalog(t *testing.T) {
+
+	SpringWeb.ErrorCodes().Register(40401, http.StatusNotFound, "pet not found").
+		WithMessage("zh", "宠物不存在")
+	SpringWeb.ErrorCodes().MapError(errPetNotFound, 40401)
+
+	SpringWeb.ErrorCodes().Register(40001, http.StatusBadRequest, "pet {0} has invalid status {1}").
+		WithMessage("zh-CN", "宠物 {0} 的状态 {1} 无效")
+
+	m := SpringWeb.NewDefaultWebMapping()
+
+	m.GetBinding("/pet", func(req petRequest) string {
+		switch req.Str {
+		case "miss":
+			panic(&wrapError{"query pet", errPetNotFound})
+		case "bad!":
+			panic(SpringWeb.NewCodeError(40001, 7, "sold"))
+		case "boom":
+			panic(errors.New("boom"))
+		}
+		return req.Str
+	})
+
+	v2 := m.Route("/v2").WithResultWrapper(&envelopeWrapper{})
+	v2.GetBinding("/pet", func(req petRequest) string {
+		if req.Str == "miss" {
+			panic(errPetNotFound)
+		}
+		return req.Str
+	})
+
+	for name, adapter := range adapters {
+		h := adapter(m)
+		t.Run(name, func(t *testing.T) {
+
+			w, body := doRequest(h, http.MethodGet, "/pet?str=miss", nil, nil)
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.JSONEq(t, `{"Code":40401,"Msg":"pet not found","Err":"query pet: pet not found","Data":null}`, body)
+
+			w, body = doRequest(h, http.MethodGet, "/pet?str=miss", nil, map[string]string{
+				SpringWeb.HeaderAcceptLanguage: "fr;q=0.9, zh-TW, en;q=0.8",
+			})
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.Contains(t, body, `"Msg":"宠物不存在"`)
+
+			w, body = doRequest(h, http.MethodGet, "/pet?str=bad!", nil, map[string]string{
+				SpringWeb.HeaderAcceptLanguage: "zh-CN",
+			})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, body, `"Code":40001,"Msg":"宠物 7 的状态 sold 无效"`)
+
+			w, body = doRequest(h, http.MethodGet, "/pet?str=boom", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, `{"Code":-1,"Msg":"ERROR","Err":"boom","Data":null}`, body)
+
+			_, body = doRequest(h, http.MethodGet, "/v2/pet?str=okay", nil, nil)
+			assert.JSONEq(t, `{"success":true,"payload":"okay"}`, body)
+
+			w, body = doRequest(h, http.MethodGet, "/v2/pet?str=miss", nil, nil)
+			assert.Equal(t, http.StatusNotFound, w.Code)
+			assert.JSONEq(t, `{"success":false,"code":40401,"message":"pet not found"}`, body)
+		})
+	}
+
+	t.Run("container", func(t *testing.T) {
+		h := ginHandler(m, SpringWeb.ResultWrapperFilter(&envelopeWrapper{}))
+		_, body := doRequest(h, http.MethodGet, "/pet?str=okay", nil, nil)
+		assert.JSONEq(t, `{"success":true,"payload":"okay"}`, body)
+	})
+}
+
+func TestCodeError(t *testing.T) {
+	SpringWeb.ErrorCodes().Register(50001, 0, "order {0} is locked")
+	err := SpringWeb.NewCodeError(50001, 42).WithCause(errPetNotFound)
+	assert.Equal(t, "order 42 is locked: pet not found", err.Error())
+	assert.Equal(t, errPetNotFound, err.Unwrap())
+	assert.Equal(t, "error code 50002", SpringWeb.NewCodeError(50002).Error())
+}
+
+func TestAcceptLanguages(t *testing.T) {
+	assert.Equal(t, []string{"zh-CN", "zh", "en"}, SpringWeb.AcceptLanguages("en;q=0.5, zh-CN, zh;q=0.8, *;q=0.1"))
+	assert.Empty(t, SpringWeb.AcceptLanguages(""))
+}
+
+func TestContextLookup(t *testing.T) {
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/lookup", func(ctx SpringWeb.WebContext) {
+		_, ok := ctx.Lookup("missing")
+		ctx.Set("present", "yes")
+		v, _ := ctx.Lookup("present")
+		ctx.String(http.StatusOK, "%v %v %v", ok, v, SpringWeb.GetResultWrapper(ctx) != nil)
+	})
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			_, body := doRequest(adapter(m), http.MethodGet, "/lookup", nil, nil)
+			assert.Equal(t, "false yes true", body)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecureFilter(t *testing.T) {
+
+	page := func(ctx SpringWeb.WebContext) {
+		ctx.HTML(http.StatusOK, `<script nonce="`+SpringWeb.CspNonce(ctx)+`"></script>`)
+	}
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/page", page)
+
+	// 可以被其他站点嵌入的页面
+	embed := SpringWeb.DefaultSecureConfig
+	embed.XFrameOptions = ""
+	embed.ContentSecurityPolicy = "frame-ancestors *"
+	embed.CSPReportOnly = true
+	embed.HSTSTrustForwardedProto = false
+	m.Route("/embed").WithSecureHeaders(embed).GetMapping("/page", page)
+
+	https := map[string]string{SpringWeb.HeaderXForwardedProto: "https"}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m, SpringWeb.SecureFilter(SpringWeb.DefaultSecureConfig))
+
+			w, body := doRequest(h, http.MethodGet, "/page", nil, nil)
+			header := w.Header()
+			assert.Equal(t, "nosniff", header.Get(SpringWeb.HeaderXContentTypeOptions))
+			assert.Equal(t, "SAMEORIGIN", header.Get(SpringWeb.HeaderXFrameOptions))
+			assert.Equal(t, "strict-origin-when-cross-origin", header.Get(SpringWeb.HeaderReferrerPolicy))
+			assert.Equal(t, "camera=(), microphone=(), geolocation=()", header.Get(SpringWeb.HeaderPermissionsPolicy))
+			assert.Empty(t, header.Get(SpringWeb.HeaderStrictTransportSecurity)) // HTTP 请求不输出 HSTS
+
+			// 每个请求使用不同的 nonce
+			csp := header.Get(SpringWeb.HeaderContentSecurityPolicy)
+			nonce := body[len(`<script nonce="`) : len(body)-len(`"></script>`)]
+			assert.Len(t, nonce, 24)
+			assert.Contains(t, csp, "script-src 'self' 'nonce-"+nonce+"';")
+
+			w, body2 := doRequest(h, http.MethodGet, "/page", nil, https)
+			assert.NotEqual(t, body, body2)
+			assert.Equal(t, "max-age=31536000; includeSubDomains", w.Header().Get(SpringWeb.HeaderStrictTransportSecurity))
+
+			// 路由分组覆盖全局配置
+			w, body = doRequest(h, http.MethodGet, "/embed/page", nil, https)
+			header = w.Header()
+			assert.Empty(t, header.Get(SpringWeb.HeaderXFrameOptions))
+			assert.Empty(t, header.Get(SpringWeb.HeaderContentSecurityPolicy))
+			assert.Empty(t, header.Get(SpringWeb.HeaderStrictTransportSecurity))
+			assert.Equal(t, "frame-ancestors *", header.Get(SpringWeb.HeaderContentSecurityPolicyReportOnly))
+			assert.Equal(t, "nosniff", header.Get(SpringWeb.HeaderXContentTypeOptions))
+			assert.Equal(t, `<script nonce=""></script>`, body)
+		})
+	}
+
+	// HSTS 的可选项
+	h := adapters["SpringGin"](m, SpringWeb.SecureFilter(SpringWeb.SecureConfig{
+		HSTSMaxAge:              600,
+		HSTSExcludeSubdomains:   true,
+		HSTSPreload:             true,
+		HSTSTrustForwardedProto: true,
+	}))
+	w, _ := doRequest(h, http.MethodGet, "/page", nil, map[string]string{SpringWeb.HeaderXForwardedProto: "https, http"})
+	assert.Equal(t, "max-age=600; preload", w.Header().Get(SpringWeb.HeaderStrictTransportSecurity))
+	assert.Empty(t, w.Header().Get(SpringWeb.HeaderXFrameOptions))
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/go-spring/go-spring-web/testcases"
+	"github.com/stretchr/testify/assert"
+)
+
+// routedEchoRequest 同时支持查询参数和 JSON 请求体
+type routedEchoRequest struct {
+	Str string `query:"str" form:"str" json:"str"`
+}
+
+type RoutedService struct{}
+
+func (s *RoutedService) Echo(request routedEchoRequest) *testcases.EchoResponse {
+	return &testcases.EchoResponse{Echo: "echo " + request.Str}
+}
+
+func (s *RoutedService) PtrEcho(request *routedEchoRequest) *testcases.EchoResponse {
+	return &testcases.EchoResponse{Echo: "echo " + request.Str}
+}
+
+func (s *RoutedService) CtxEcho(ctx SpringWeb.WebContext, request *routedEchoRequest) *testcases.EchoResponse {
+	return &testcases.EchoResponse{Echo: "echo " + request.Str}
+}
+
+func (s *RoutedService) EchoCtx(request routedEchoRequest, ctx SpringWeb.WebContext) *testcases.EchoResponse {
+	return &testcases.EchoResponse{Echo: "echo " + request.Str}
+}
+
+func (s *RoutedService) Routes() map[string]string {
+	return map[string]string{
+		"Echo":    "GET /echo",
+		"EchoCtx": "PUT|PATCH echo",
+		"CtxEcho": "-",
+	}
+}
+
+func TestService(t *testing.T) {
+
+	m := SpringWeb.NewDefaultWebMapping()
+
+	r := m.Service("/rpc/", new(testcases.RpcService))
+	assert.Len(t, r.Mappers, 4)
+	assert.Equal(t, []SpringWeb.SkippedMethod{
+		{Name: "Err", Reason: "signature func(SpringWeb.WebContext) interface {} isn't BIND-compatible"},
+		{Name: "OK", Reason: "signature func(SpringWeb.WebContext) interface {} isn't BIND-compatible"},
+		{Name: "Panic", Reason: "signature func(SpringWeb.WebContext) interface {} isn't BIND-compatible"},
+	}, r.Skipped)
+
+	_, _, fnName := r.Mappers[0].Handler().FileLine()
+	assert.Equal(t, "(*RpcService).CtxEcho", fnName)
+
+	r = m.Route("/v2").Service("/routed", new(RoutedService))
+	assert.Len(t, r.Mappers, 3)
+	assert.Equal(t, "CtxEcho", r.Skipped[0].Name)
+	assert.Equal(t, "excluded by Routes()", r.Skipped[0].Reason)
+
+	keys := make(map[string]bool)
+	for key := range m.Mappers() {
+		keys[key] = true
+	}
+	assert.True(t, keys[SpringWeb.NewMapper(SpringWeb.MethodGet, "/v2/routed/echo", nil, nil).Key()])
+	assert.True(t, keys[SpringWeb.NewMapper(SpringWeb.MethodPost, "/v2/routed/PtrEcho", nil, nil).Key()])
+	assert.True(t, keys[SpringWeb.NewMapper(SpringWeb.MethodPut|SpringWeb.MethodPatch, "/v2/routed/echo", nil, nil).Key()])
+
+	for name, adapter := range adapters {
+		h := adapter(m)
+		t.Run(name, func(t *testing.T) {
+
+			header := map[string]string{SpringWeb.HeaderContentType: SpringWeb.MIMEApplicationJSON}
+
+			_, body := doRequest(h, http.MethodPost, "/rpc/Echo", strings.NewReader(`{"str":"1234"}`), header)
+			assert.JSONEq(t, `{"Code":0,"Msg":"SUCCESS","Err":"","Data":{"echo":"echo 1234"}}`, body)
+
+			_, body = doRequest(h, http.MethodGet, "/v2/routed/echo?str=5678", nil, nil)
+			assert.JSONEq(t, `{"Code":0,"Msg":"SUCCESS","Err":"","Data":{"echo":"echo 5678"}}`, body)
+
+			_, body = doRequest(h, http.MethodPatch, "/v2/routed/echo", strings.NewReader(`{"str":"abcd"}`), header)
+			assert.JSONEq(t, `{"Code":0,"Msg":"SUCCESS","Err":"","Data":{"echo":"echo abcd"}}`, body)
+		})
+	}
+
+	assert.Panics(t, func() {
+		m.Service("/bad", &badRoutedService{})
+	})
+}
+
+type badRoutedService struct{}
+
+func (s *badRoutedService) Routes() map[string]string {
+	return map[string]string{"Missing": "GET /missing"}
+}
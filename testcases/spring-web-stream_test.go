@@ -0,0 +1,156 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+// streamRequest 流式接口的请求
+type streamRequest struct {
+	Str string `query:"str" form:"str"`
+}
+
+func TestStream(t *testing.T) {
+
+	m := SpringWeb.NewDefaultWebMapping()
+
+	m.HandleGet("/chan", SpringWeb.RPC(func(ctx SpringWeb.WebContext) interface{} {
+		ch := make(chan int)
+		go func() {
+			defer close(ch)
+			for i := 1; i <= 3; i++ {
+				select {
+				case ch <- i:
+				case <-ctx.Request().Context().Done():
+					return
+				}
+			}
+		}()
+		return (<-chan int)(ch)
+	}))
+
+	m.GetBinding("/iter", func(req streamRequest) SpringWeb.StreamIterator {
+		n := 0
+		return SpringWeb.StreamIteratorFunc(func(ctx context.Context) (interface{}, error) {
+			if n++; n > 2 {
+				return nil, errors.New("broken")
+			}
+			return req.Str, nil
+		})
+	})
+
+	m.HandleGet("/reader", SpringWeb.RPC(func(ctx SpringWeb.WebContext) interface{} {
+		return SpringWeb.NewStreamReader(strings.NewReader("raw bytes"), SpringWeb.MIMETextPlain)
+	}))
+
+	// 任何 io.Reader 都原样写出
+	m.HandleGet("/buffer", SpringWeb.RPC(func(ctx SpringWeb.WebContext) interface{} {
+		return bytes.NewBufferString("raw bytes")
+	}))
+
+	f, err := ioutil.TempFile("", "stream")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("file bytes")
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	m.HandleGet("/file", SpringWeb.RPC(func(ctx SpringWeb.WebContext) interface{} {
+		file, err := os.Open(f.Name())
+		if err != nil {
+			panic(err)
+		}
+		return file
+	}))
+
+	m.HandleGet("/block", SpringWeb.RPC(func(ctx SpringWeb.WebContext) interface{} {
+		return make(chan string)
+	}))
+
+	for name, adapter := range adapters {
+		h := adapter(m)
+		t.Run(name, func(t *testing.T) {
+
+			w, body := doRequest(h, http.MethodGet, "/chan", nil, nil)
+			assert.Equal(t, SpringWeb.MIMEJsonStream, w.Header().Get(SpringWeb.HeaderContentType))
+			assert.Equal(t, `{"Code":0,"Msg":"SUCCESS","Err":"","Data":1}
+{"Code":0,"Msg":"SUCCESS","Err":"","Data":2}
+{"Code":0,"Msg":"SUCCESS","Err":"","Data":3}
+`, body)
+
+			w, body = doRequest(h, http.MethodGet, "/iter?str=abcd", nil, map[string]string{
+				"Accept": SpringWeb.MIMETextEventStream,
+			})
+			assert.Equal(t, SpringWeb.MIMETextEventStream, w.Header().Get(SpringWeb.HeaderContentType))
+			assert.True(t, strings.HasPrefix(body, `event: message
+data: {"Code":0,"Msg":"SUCCESS","Err":"","Data":"abcd"}
+
+event: message
+data: {"Code":0,"Msg":"SUCCESS","Err":"","Data":"abcd"}
+
+event: error
+data: {"Code":-1,"Msg":"ERROR","Err":`))
+
+			w, body = doRequest(h, http.MethodGet, "/reader", nil, nil)
+			assert.Equal(t, SpringWeb.MIMETextPlain, w.Header().Get(SpringWeb.HeaderContentType))
+			assert.Equal(t, "raw bytes", body)
+
+			w, body = doRequest(h, http.MethodGet, "/buffer", nil, nil)
+			assert.Equal(t, SpringWeb.MIMEOctetStream, w.Header().Get(SpringWeb.HeaderContentType))
+			assert.Equal(t, "raw bytes", body)
+
+			w, body = doRequest(h, http.MethodGet, "/file", nil, nil)
+			assert.Equal(t, SpringWeb.MIMEOctetStream, w.Header().Get(SpringWeb.HeaderContentType))
+			assert.Equal(t, "file bytes", body)
+
+			// 客户端断开之后流式响应立即结束
+			ctx, cancel := context.WithCancel(context.Background())
+			r := httptest.NewRequest(http.MethodGet, "/block", nil).WithContext(ctx)
+			done := make(chan struct{})
+			go func() {
+				h.ServeHTTP(httptest.NewRecorder(), r)
+				close(done)
+			}()
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("stream not stopped after client disconnected")
+			}
+		})
+	}
+}
+
+func TestWriteSSEvent(t *testing.T) {
+	var sb strings.Builder
+	assert.NoError(t, SpringWeb.WriteSSEvent(&sb, "ping", "a\nb"))
+	assert.NoError(t, SpringWeb.WriteSSEvent(&sb, "", map[string]int{"a": 1}))
+	assert.Equal(t, "event: ping\ndata: a\ndata: b\n\ndata: {\"a\":1}\n\n", sb.String())
+}
@@ -0,0 +1,564 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-spring/go-spring-web/spring-echo"
+	"github.com/go-spring/go-spring-web/spring-gin"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/go-spring/go-spring-web/testcases"
+	"github.com/stretchr/testify/assert"
+)
+
+type DerivedPet struct {
+	Id   int64    `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+type FindPetRequest struct {
+	Id     int64  `param:"id" json:"id"`
+	Status string `query:"status" json:"status"`
+}
+
+func FindDerivedPet(req *FindPetRequest) *DerivedPet {
+	return &DerivedPet{Id: req.Id}
+}
+
+func AddDerivedPet(pet DerivedPet) []DerivedPet {
+	return []DerivedPet{pet}
+}
+
+// jsonPath 按照 . 分隔的路径读取 JSON 中的值
+func jsonPath(v interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
+
+func TestDeriveSwagger(t *testing.T) {
+
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+
+	r := c.Route("/derived")
+	r.GetBinding("/pet/:id", FindDerivedPet)
+	r.PostBinding("/pet", AddDerivedPet).
+		Swagger("createPet").
+		WithTags("pets")
+
+	c.PreStart()
+
+	var d map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(c.Swagger().ReadDoc()), &d))
+
+	get := jsonPath(d, "paths./derived/pet/{id}.get")
+	assert.Equal(t, "findDerivedPet", jsonPath(get, "operationId"))
+	assert.Equal(t, []interface{}{"derived"}, jsonPath(get, "tags"))
+
+	params := jsonPath(get, "parameters").([]interface{})
+	assert.Len(t, params, 2)
+	assert.Equal(t, "path", jsonPath(params[0], "in"))
+	assert.Equal(t, "integer", jsonPath(params[0], "type"))
+	assert.Equal(t, "status", jsonPath(params[1], "name"))
+	assert.Equal(t, "query", jsonPath(params[1], "in"))
+
+	assert.Equal(t, "#/definitions/DerivedPet", jsonPath(get, "responses.200.schema.properties.Data.$ref"))
+	assert.Equal(t, "integer", jsonPath(get, "responses.200.schema.properties.Code.type"))
+
+	post := jsonPath(d, "paths./derived/pet.post")
+	assert.Equal(t, "createPet", jsonPath(post, "operationId"))
+	assert.Equal(t, []interface{}{"pets"}, jsonPath(post, "tags"))
+	assert.Equal(t, "body", jsonPath(post.(map[string]interface{})["parameters"].([]interface{})[0], "in"))
+	assert.Equal(t, "array", jsonPath(post, "responses.200.schema.properties.Data.type"))
+
+	assert.Equal(t, []interface{}{"id", "name"}, jsonPath(d, "definitions.DerivedPet.required"))
+}
+
+type Money int64
+
+func (Money) JSONSchema() spec.Schema {
+	return *spec.StringProperty().WithPattern(`^\d+\.\d{2}$`)
+}
+
+type Audit struct {
+	Creator string    `json:"creator"`
+	Created time.Time `json:"created"`
+	Id      string    `json:"id"`
+}
+
+type TreeNode struct {
+	Audit
+	Id       int64             `json:"id"`
+	Size     uint16            `json:"size"`
+	Weight   float32           `json:"weight,omitempty"`
+	Data     []byte            `json:"data"`
+	Raw      json.RawMessage   `json:"raw"`
+	Timeout  time.Duration     `json:"timeout"`
+	Labels   map[string]string `json:"labels"`
+	Any      interface{}       `json:"any"`
+	Price    Money             `json:"price"`
+	Count    int               `json:"count,string"`
+	Point    [2]float64        `json:"point"`
+	Secret   string            `json:"-"`
+	Parent   *TreeNode         `json:"parent,omitempty"`
+	Children []*TreeNode       `json:"children"`
+	Echo     *testcases.EchoRequest
+	Local    *EchoRequest
+	private  string
+}
+
+type EchoRequest struct {
+	Text string `json:"text"`
+}
+
+func TestSchemaGeneration(t *testing.T) {
+
+	s := SpringWeb.NewSwagger().BindDefinitions(new(TreeNode))
+
+	var d map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(s.ReadDoc()), &d))
+
+	node := jsonPath(d, "definitions.TreeNode.properties").(map[string]interface{})
+
+	assert.Equal(t, "string", jsonPath(node, "creator.type"))
+	assert.Equal(t, "date-time", jsonPath(node, "created.format"))
+	assert.Equal(t, "integer", jsonPath(node, "id.type")) // 外层的同名字段优先
+	assert.Nil(t, node["Secret"])
+	assert.Nil(t, node["private"])
+
+	assert.Equal(t, "uint16", jsonPath(node, "size.format"))
+	assert.Equal(t, float64(0), jsonPath(node, "size.minimum"))
+	assert.Equal(t, "float", jsonPath(node, "weight.format"))
+	assert.Equal(t, "byte", jsonPath(node, "data.format"))
+	assert.Equal(t, map[string]interface{}{}, node["raw"])
+	assert.Equal(t, "int64", jsonPath(node, "timeout.format"))
+	assert.Equal(t, "string", jsonPath(node, "labels.additionalProperties.type"))
+	assert.Equal(t, map[string]interface{}{}, node["any"])
+	assert.Equal(t, "string", jsonPath(node, "count.type"))
+	assert.Equal(t, float64(2), jsonPath(node, "point.maxItems"))
+
+	assert.Equal(t, "#/definitions/Money", jsonPath(node, "price.$ref"))
+	assert.Equal(t, "string", jsonPath(d, "definitions.Money.type"))
+
+	assert.Equal(t, "#/definitions/TreeNode", jsonPath(node, "parent.$ref"))
+	assert.Equal(t, "#/definitions/TreeNode", jsonPath(node, "children.items.$ref"))
+
+	assert.Equal(t, "#/definitions/EchoRequest", jsonPath(node, "Echo.$ref"))
+	assert.Equal(t, "#/definitions/TestcasesTestEchoRequest", jsonPath(node, "Local.$ref"))
+	assert.Equal(t, "string", jsonPath(d, "definitions.EchoRequest.properties.Str.type"))
+	assert.Equal(t, "string", jsonPath(d, "definitions.TestcasesTestEchoRequest.properties.text.type"))
+
+	required := jsonPath(d, "definitions.TreeNode.required").([]interface{})
+	assert.Contains(t, required, "creator")
+	assert.NotContains(t, required, "weight")
+	assert.NotContains(t, required, "parent")
+}
+
+type TaggedPet struct {
+	Name     string   `json:"name,omitempty" validate:"required,min=1,max=64" description:"pet name" example:"doggie"`
+	Age      int32    `json:"age" validate:"omitempty,gte=0,lt=30"`
+	Status   string   `json:"status" binding:"oneof=available pending 'sold out'"`
+	Level    int      `json:"level" validate:"oneof=1 2 3"`
+	Email    string   `json:"email" validate:"omitempty,email"`
+	Id       string   `json:"id" validate:"uuid4"`
+	Homepage string   `json:"homepage" validate:"url|email"`
+	Tags     []string `json:"tags" validate:"max=5,dive,alphanum,len=8"`
+	Kind     string   `json:"kind" enum:"cat,dog"`
+	Weight   float64  `json:"weight" example:"1.5"`
+}
+
+type FindTaggedPetRequest struct {
+	Status []string `query:"status" validate:"required,dive,oneof=available pending"`
+	Limit  int      `query:"limit" validate:"min=1,max=100" description:"page size"`
+}
+
+func FindTaggedPet(req *FindTaggedPetRequest) []TaggedPet {
+	return nil
+}
+
+func TestSchemaValidateTags(t *testing.T) {
+
+	s := SpringWeb.NewSwagger().BindDefinitions(new(TaggedPet))
+
+	var d map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(s.ReadDoc()), &d))
+
+	pet := jsonPath(d, "definitions.TaggedPet").(map[string]interface{})
+	assert.Equal(t, []interface{}{"name", "status", "level", "id", "homepage", "tags", "kind", "weight"}, pet["required"])
+
+	props := pet["properties"].(map[string]interface{})
+	assert.Equal(t, float64(1), jsonPath(props, "name.minLength"))
+	assert.Equal(t, float64(64), jsonPath(props, "name.maxLength"))
+	assert.Equal(t, "pet name", jsonPath(props, "name.description"))
+	assert.Equal(t, "doggie", jsonPath(props, "name.example"))
+	assert.Equal(t, float64(0), jsonPath(props, "age.minimum"))
+	assert.Equal(t, float64(30), jsonPath(props, "age.maximum"))
+	assert.Equal(t, true, jsonPath(props, "age.exclusiveMaximum"))
+	assert.Equal(t, []interface{}{"available", "pending", "sold out"}, jsonPath(props, "status.enum"))
+	assert.Equal(t, []interface{}{float64(1), float64(2), float64(3)}, jsonPath(props, "level.enum"))
+	assert.Equal(t, "email", jsonPath(props, "email.format"))
+	assert.Equal(t, "uuid", jsonPath(props, "id.format"))
+	assert.Nil(t, jsonPath(props, "homepage.format"))
+	assert.Equal(t, float64(5), jsonPath(props, "tags.maxItems"))
+	assert.Equal(t, "^[a-zA-Z0-9]+$", jsonPath(props, "tags.items.pattern"))
+	assert.Equal(t, float64(8), jsonPath(props, "tags.items.minLength"))
+	assert.Equal(t, []interface{}{"cat", "dog"}, jsonPath(props, "kind.enum"))
+	assert.Equal(t, 1.5, jsonPath(props, "weight.example"))
+
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+	c.GetBinding("/tagged/pet", FindTaggedPet)
+	c.PreStart()
+
+	d = nil
+	assert.Nil(t, json.Unmarshal([]byte(c.Swagger().ReadDoc()), &d))
+
+	params := jsonPath(d, "paths./tagged/pet.get.parameters").([]interface{})
+	assert.Equal(t, true, jsonPath(params[0], "required"))
+	assert.Equal(t, []interface{}{"available", "pending"}, jsonPath(params[0], "items.enum"))
+	assert.Equal(t, float64(100), jsonPath(params[1], "maximum"))
+	assert.Equal(t, "page size", jsonPath(params[1], "description"))
+	assert.Nil(t, jsonPath(params[1], "required"))
+}
+
+func TestSwaggerGroups(t *testing.T) {
+
+	SpringWeb.Swagger().WithTitle("global")
+
+	public := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+	public.Swagger().WithTitle("public").WithBasePath("/api")
+	public.SwaggerGroup("admin").WithTitle("admin")
+
+	api := public.Route("/api")
+	api.GetBinding("/pet/:id", FindDerivedPet)
+
+	admin := public.Route("/api/admin").WithDocGroup("admin")
+	admin.PostBinding("/pet", AddDerivedPet)
+
+	internal := public.Route("/internal").ExcludeFromDoc()
+	internal.GetBinding("/pet/:id", FindDerivedPet)
+
+	other := SpringEcho.NewContainer(SpringWeb.ContainerConfig{})
+	other.GetBinding("/other", FindDerivedPet)
+
+	public.PreStart()
+	other.PreStart()
+
+	var d map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(public.Swagger().ReadDoc()), &d))
+	assert.Equal(t, "public", jsonPath(d, "info.title"))
+	assert.NotNil(t, jsonPath(d, "paths./pet/{id}.get"))
+	assert.Nil(t, jsonPath(d, "paths./admin/pet"))
+	assert.Nil(t, jsonPath(d, "paths./internal/pet/{id}"))
+	assert.Nil(t, jsonPath(d, "paths./other"))
+
+	d = nil
+	assert.Nil(t, json.Unmarshal([]byte(public.SwaggerGroup("admin").ReadDoc()), &d))
+	assert.Equal(t, "admin", jsonPath(d, "info.title"))
+	assert.Equal(t, "/api", jsonPath(d, "basePath"))
+	assert.NotNil(t, jsonPath(d, "paths./admin/pet.post"))
+	assert.Nil(t, jsonPath(d, "paths./pet/{id}"))
+
+	d = nil
+	assert.Nil(t, json.Unmarshal([]byte(other.Swagger().ReadDoc()), &d))
+	assert.Equal(t, "global", jsonPath(d, "info.title"))
+	assert.NotNil(t, jsonPath(d, "paths./other.get"))
+	assert.Nil(t, jsonPath(d, "paths./pet/{id}"))
+
+	assert.Empty(t, SpringWeb.Swagger().Paths.Paths)
+	SpringWeb.Swagger().WithTitle("")
+
+	h := ginHandler(public)
+
+	_, body := doRequest(h, http.MethodGet, "/swagger/admin/doc.json", nil, nil)
+	assert.Contains(t, body, `"title":"admin"`)
+
+	_, body = doRequest(h, http.MethodGet, "/swagger/doc.json", nil, nil)
+	assert.Contains(t, body, `"title":"public"`)
+
+	resp, _ := doRequest(h, http.MethodGet, "/openapi/admin.yaml", nil, nil)
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	_, body = doRequest(h, http.MethodGet, "/redoc/admin", nil, nil)
+	assert.Contains(t, body, "/swagger/admin/doc.json")
+}
+
+func TestDocConfig(t *testing.T) {
+
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+	c.Swagger().WithTitle("docs")
+	c.SetDocConfig(SpringWeb.DocConfig{
+		SwaggerPath: "/docs/swagger/",
+		ReDocPath:   "/docs/redoc",
+		OpenAPIPath: SpringWeb.DisabledDocPath,
+		Filters: []SpringWeb.Filter{
+			SpringWeb.TrustedRemoteIPFilter(SpringWeb.NewTrustedProxies("192.0.2.1"), "10.0.0.0/8", "::1"),
+		},
+		ReDocBundle: []byte("redoc bundle"),
+	})
+	c.GetBinding("/pet/:id", FindDerivedPet)
+	c.PreStart()
+
+	// httptest 的请求来自 192.0.2.1，也就是可信的反向代理
+	internal := map[string]string{SpringWeb.HeaderXForwardedFor: "10.1.2.3"}
+	external := map[string]string{SpringWeb.HeaderXForwardedFor: "10.1.2.3, 203.0.113.9"}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(c)
+
+			resp, body := doRequest(h, http.MethodGet, "/docs/swagger/doc.json", nil, nil)
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+			assert.NotContains(t, body, "docs")
+
+			resp, body = doRequest(h, http.MethodGet, "/docs/swagger/doc.json", nil, internal)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Contains(t, body, `"title":"docs"`)
+
+			// 客户端伪造的地址在可信代理添加的地址左边，不会被使用
+			resp, _ = doRequest(h, http.MethodGet, "/docs/swagger/doc.json", nil, external)
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+
+			resp, body = doRequest(h, http.MethodGet, "/docs/swagger/index.html", nil, internal)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Contains(t, body, `\/docs\/swagger\/doc.json`)
+
+			_, body = doRequest(h, http.MethodGet, "/docs/redoc", nil, internal)
+			assert.Contains(t, body, `spec-url='/docs/swagger/doc.json'`)
+			assert.Contains(t, body, `src="/docs/redoc/redoc.standalone.js"`)
+			assert.NotContains(t, body, "googleapis")
+
+			_, body = doRequest(h, http.MethodGet, "/docs/redoc/redoc.standalone.js", nil, internal)
+			assert.Equal(t, "redoc bundle", body)
+
+			resp, _ = doRequest(h, http.MethodGet, "/openapi.json", nil, internal)
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		})
+	}
+}
+
+func TestReDocScript(t *testing.T) {
+
+	bundle := []byte("/* redoc.standalone.js */")
+
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+	c.SetDocConfig(SpringWeb.DocConfig{ReDocBundle: bundle})
+	c.GetBinding("/pet/:id", FindDerivedPet)
+	c.PreStart()
+
+	cdn := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+	cdn.SetDocConfig(SpringWeb.DocConfig{ReDocScript: SpringWeb.ReDocScriptURL})
+	cdn.GetBinding("/pet/:id", FindDerivedPet)
+	cdn.PreStart()
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			// 内嵌的脚本从本地加载
+			h := adapter(c)
+			_, body := doRequest(h, http.MethodGet, "/redoc", nil, nil)
+			assert.Contains(t, body, `src="/redoc/redoc.standalone.js"`)
+			assert.NotContains(t, body, "cdn.jsdelivr.net")
+
+			resp, script := doRequest(h, http.MethodGet, "/redoc/redoc.standalone.js", nil, nil)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, SpringWeb.MIMEApplicationJavaScriptCharsetUTF8, resp.Header().Get(SpringWeb.HeaderContentType))
+			assert.Equal(t, string(bundle), script)
+
+			h = adapter(cdn)
+			_, body = doRequest(h, http.MethodGet, "/redoc", nil, nil)
+			assert.Contains(t, body, `src="`+SpringWeb.ReDocScriptURL+`"`)
+			resp, _ = doRequest(h, http.MethodGet, "/redoc/redoc.standalone.js", nil, nil)
+			assert.Equal(t, http.StatusNotFound, resp.Code)
+		})
+	}
+}
+
+func TestDocsWithSecureFilter(t *testing.T) {
+
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+	c.GetBinding("/pet/:id", FindDerivedPet)
+	c.PreStart()
+
+	nonceRe := regexp.MustCompile(`'nonce-([^']+)'`)
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			h := adapter(c, SpringWeb.SecureFilter(SpringWeb.DefaultSecureConfig))
+
+			// 普通的接口仍然使用全局的 CSP
+			w, _ := doRequest(h, http.MethodGet, "/pet/1", nil, nil)
+			assert.NotContains(t, w.Header().Get(SpringWeb.HeaderContentSecurityPolicy), "unsafe-inline")
+
+			// Swagger UI 的内联脚本带有 CSP 中的 nonce，内联样式可以使用
+			w, body := doRequest(h, http.MethodGet, "/swagger/index.html", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			csp := w.Header().Get(SpringWeb.HeaderContentSecurityPolicy)
+			assert.Contains(t, csp, "style-src 'self' 'unsafe-inline';")
+			assert.NotContains(t, csp, "fonts.g")
+			match := nonceRe.FindStringSubmatch(csp)
+			if assert.Len(t, match, 2) {
+				assert.Contains(t, body, `<script nonce="`+match[1]+`">`)
+				assert.NotContains(t, body, "<script>")
+			}
+
+			// Swagger UI 的静态资源来自同一个源
+			w, _ = doRequest(h, http.MethodGet, "/swagger/swagger-ui-bundle.js", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			// ReDoc 的脚本带有 nonce，不论是内嵌的脚本还是 CDN
+			w, body = doRequest(h, http.MethodGet, "/redoc", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			match = nonceRe.FindStringSubmatch(w.Header().Get(SpringWeb.HeaderContentSecurityPolicy))
+			if assert.Len(t, match, 2) {
+				// html/template 会转义 nonce 中的 +，浏览器解析属性时还原
+				assert.Regexp(t, `<script src="[^"]+" nonce="`+regexp.QuoteMeta(match[1])+`">`, html.UnescapeString(body))
+			}
+
+			// 没有使用 SecureFilter 时不输出 CSP
+			w, body = doRequest(adapter(c), http.MethodGet, "/swagger/index.html", nil, nil)
+			assert.Empty(t, w.Header().Get(SpringWeb.HeaderContentSecurityPolicy))
+			assert.Contains(t, body, "<script>")
+		})
+	}
+}
+
+func TestRemoteIPFilter(t *testing.T) {
+
+	m := SpringWeb.NewDefaultWebMapping()
+	m.GetMapping("/internal", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "ok")
+	}, SpringWeb.InternalNetworkFilter())
+	m.GetMapping("/proxy", func(ctx SpringWeb.WebContext) {
+		ctx.String(http.StatusOK, "ok")
+	}, SpringWeb.RemoteIPFilter("192.0.2.0/24"))
+
+	spoofed := map[string]string{
+		SpringWeb.HeaderXRealIP:       "10.1.2.3",
+		SpringWeb.HeaderXForwardedFor: "127.0.0.1",
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+			h := adapter(m)
+
+			// 不信任客户端提供的请求头
+			resp, _ := doRequest(h, http.MethodGet, "/internal", nil, spoofed)
+			assert.Equal(t, http.StatusForbidden, resp.Code)
+
+			resp, _ = doRequest(h, http.MethodGet, "/proxy", nil, spoofed)
+			assert.Equal(t, http.StatusOK, resp.Code)
+		})
+	}
+
+	proxies := SpringWeb.NewTrustedProxies("192.0.2.1", "10.0.0.0/8")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, "192.0.2.1", (*SpringWeb.TrustedProxies)(nil).ClientIP(r))
+	assert.Equal(t, "192.0.2.1", proxies.ClientIP(r))
+
+	r.Header.Set(SpringWeb.HeaderXRealIP, "203.0.113.7")
+	assert.Equal(t, "203.0.113.7", proxies.ClientIP(r))
+
+	r.Header.Set(SpringWeb.HeaderXForwardedFor, "127.0.0.1, 203.0.113.9, 10.0.0.2")
+	assert.Equal(t, "203.0.113.9", proxies.ClientIP(r))
+
+	r.RemoteAddr = "203.0.113.1:4321"
+	assert.Equal(t, "203.0.113.1", proxies.ClientIP(r))
+}
+
+type PetNotFound struct {
+	Message string `json:"message"`
+}
+
+func TestOperationResponds(t *testing.T) {
+
+	c := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+
+	c.GetBinding("/pet/:id", FindDerivedPet).
+		Swagger("").
+		Responds(http.StatusNotFound, PetNotFound{})
+
+	c.GetMapping("/pets", func(ctx SpringWeb.WebContext) {}).
+		Swagger("listPets").
+		Responds(http.StatusOK, SpringWeb.RpcResultOf([]DerivedPet{})).
+		Responds(http.StatusPartialContent, DerivedPet{}).
+		Responds(http.StatusPartialContent, []DerivedPet{}).
+		WithResponseHeader(http.StatusOK, "X-Total-Count", "integer", "total number of pets").
+		WithResponseExample(http.StatusOK, "empty", map[string]interface{}{"Code": 0, "Msg": "SUCCESS"}).
+		WithResponseExample(http.StatusOK, "one", map[string]interface{}{"Code": 0, "Msg": "SUCCESS", "Data": []interface{}{}})
+
+	c.GetMapping("/pet/:id/photo", func(ctx SpringWeb.WebContext) {}).
+		Swagger("getPetPhoto").
+		RespondsFile(http.StatusOK, SpringWeb.MIMEImagePng)
+
+	// 返回 io.Reader 的 BIND 接口以文件的形式写出
+	c.GetBinding("/pets/export", func(req streamRequest) *bytes.Buffer {
+		return bytes.NewBufferString(req.Str)
+	})
+
+	c.BuildDocs()
+
+	var d map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(c.Swagger().ReadDoc()), &d))
+
+	get := jsonPath(d, "paths./pet/{id}.get")
+	assert.Equal(t, "#/definitions/PetNotFound", jsonPath(get, "responses.404.schema.$ref"))
+	assert.Equal(t, "#/definitions/DerivedPet", jsonPath(get, "responses.200.schema.properties.Data.$ref"))
+	assert.Equal(t, "string", jsonPath(d, "definitions.PetNotFound.properties.message.type"))
+
+	list := jsonPath(d, "paths./pets.get")
+	assert.Equal(t, "#/definitions/RpcResult-List-DerivedPet", jsonPath(list, "responses.200.schema.$ref"))
+	assert.Equal(t, "array", jsonPath(d, "definitions.RpcResult-List-DerivedPet.properties.Data.type"))
+	assert.Equal(t, "integer", jsonPath(list, "responses.200.headers.X-Total-Count.type"))
+	assert.Equal(t, "SUCCESS", jsonPath(list, "responses.200.examples.application/json.Msg"))
+	assert.NotNil(t, jsonPath(list, "responses.200.x-examples.one"))
+	assert.Len(t, jsonPath(list, "responses.206.schema.oneOf"), 2)
+
+	photo := jsonPath(d, "paths./pet/{id}/photo.get")
+	assert.Equal(t, "file", jsonPath(photo, "responses.200.schema.type"))
+	assert.Equal(t, []interface{}{SpringWeb.MIMEImagePng}, jsonPath(photo, "produces"))
+
+	export := jsonPath(d, "paths./pets/export.get")
+	assert.Equal(t, "file", jsonPath(export, "responses.200.schema.type"))
+	assert.Equal(t, []interface{}{SpringWeb.MIMEOctetStream}, jsonPath(export, "produces"))
+
+	b, err := c.Swagger().ReadOpenAPI("json")
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(b, &d))
+
+	content := jsonPath(d, "paths./pets.get.responses.200.content").(map[string]interface{})
+	for _, mt := range content {
+		assert.Nil(t, jsonPath(mt, "example"))
+		assert.Equal(t, "SUCCESS", jsonPath(mt, "examples.empty.value.Msg"))
+	}
+}
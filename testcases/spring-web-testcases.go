@@ -194,3 +194,27 @@ func (s *RpcService) Panic(ctx SpringWeb.WebContext) interface{} {
 
 	return "ok"
 }
+
+///////////////////// client service ////////////////////////
+
+// ClientService 生成客户端使用的服务，请求参数同时支持查询参数和 JSON 请求体
+type ClientService struct{}
+
+type ClientEchoRequest struct {
+	Str string `query:"str" form:"str" json:"str" validate:"required,len=4"`
+}
+
+// Echo BIND 的结构体参数形式
+func (s *ClientService) Echo(request ClientEchoRequest) *EchoResponse {
+	return &EchoResponse{"echo " + request.Str}
+}
+
+// PtrEcho BIND 的结构体指针参数形式
+func (s *ClientService) PtrEcho(request *ClientEchoRequest) *EchoResponse {
+	return &EchoResponse{"echo " + request.Str}
+}
+
+// CtxEcho BIND 的 WebContext 形式
+func (s *ClientService) CtxEcho(ctx SpringWeb.WebContext, request *ClientEchoRequest) *EchoResponse {
+	return &EchoResponse{"echo " + request.Str}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2012-2019 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testcases_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"github.com/go-spring/go-spring-web/spring-gin"
+	"github.com/go-spring/go-spring-web/spring-web"
+	"github.com/stretchr/testify/assert"
+)
+
+type ValidatedFindRequest struct {
+	Id     int64  `param:"id" json:"id"`
+	Status string `query:"status" json:"status" enum:"available,sold"`
+}
+
+type ValidatedPet struct {
+	Name string   `json:"name" binding:"required" validate:"max=8"`
+	Tags []string `json:"tags,omitempty" validate:"max=2"`
+}
+
+func FindValidatedPet(req *ValidatedFindRequest) *DerivedPet {
+	return &DerivedPet{Id: req.Id, Name: req.Status}
+}
+
+func AddValidatedPet(pet ValidatedPet) *DerivedPet {
+	return &DerivedPet{Name: pet.Name, Tags: pet.Tags}
+}
+
+// specValidationHandler 为路由表中的每个处理函数加上它的校验过滤器，然后创建 http.Handler
+func specValidationHandler(adapter func(m SpringWeb.WebMapping, filters ...SpringWeb.Filter) http.Handler,
+	c SpringWeb.WebContainer) http.Handler {
+
+	m := SpringWeb.NewDefaultWebMapping()
+	for _, mapper := range c.Mappers() {
+		filters := mapper.Filters()
+		if f := c.SpecValidationFilter(mapper); f != nil {
+			filters = append(filters, f)
+		}
+		m.AddMapper(SpringWeb.NewMapper(mapper.Method(), mapper.Path(), mapper.Handler(), filters))
+	}
+	return adapter(m)
+}
+
+func TestSpecValidation(t *testing.T) {
+
+	type violation struct {
+		In   string
+		Name string
+	}
+
+	violations := func(body string) []violation {
+		var e SpringWeb.SpecValidationError
+		assert.Nil(t, json.Unmarshal([]byte(body), &e))
+		var r []violation
+		for _, v := range e.Violations {
+			r = append(r, violation{v.In, v.Name})
+		}
+		return r
+	}
+
+	for name, adapter := range adapters {
+		t.Run(name, func(t *testing.T) {
+
+			find := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+			find.SetSpecValidation(SpringWeb.SpecValidation{Request: true})
+			find.GetBinding("/valid/pet/:id", FindValidatedPet)
+			h := specValidationHandler(adapter, find)
+
+			w, body := doRequest(h, http.MethodGet, "/valid/pet/3?status=sold", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, body, `"Msg":"SUCCESS"`)
+
+			w, body = doRequest(h, http.MethodGet, "/valid/pet/abc", nil, nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, []violation{{"path", "id"}}, violations(body))
+
+			w, body = doRequest(h, http.MethodGet, "/valid/pet/3?status=lost", nil, nil)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, []violation{{"query", "status"}}, violations(body))
+
+			add := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+			add.SetSpecValidation(SpringWeb.SpecValidation{Request: true})
+			add.PostBinding("/valid/pet", AddValidatedPet).Swagger("").WithConsumes(SpringWeb.MIMEApplicationJSON)
+			h = specValidationHandler(adapter, add)
+
+			jsonHeader := map[string]string{SpringWeb.HeaderContentType: SpringWeb.MIMEApplicationJSON}
+
+			w, body = doRequest(h, http.MethodPost, "/valid/pet", strings.NewReader(`{"name":"tom","tags":["a"]}`), jsonHeader)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, body, `"name":"tom"`)
+
+			w, body = doRequest(h, http.MethodPost, "/valid/pet", strings.NewReader(`{"name":"a long name","tags":["a","b","c"]}`), jsonHeader)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, []violation{{"body", "name"}, {"body", "tags"}}, violations(body))
+
+			w, body = doRequest(h, http.MethodPost, "/valid/pet", strings.NewReader(`{"tags":[1]}`), jsonHeader)
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, []violation{{"body", "name"}, {"body", "tags[0]"}}, violations(body))
+
+			w, body = doRequest(h, http.MethodPost, "/valid/pet", strings.NewReader(`name=tom`),
+				map[string]string{SpringWeb.HeaderContentType: SpringWeb.MIMEApplicationForm})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Equal(t, []violation{{"header", SpringWeb.HeaderContentType}}, violations(body))
+
+			// 校验时读取的请求体大小有上限
+			limited := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+			limited.SetSpecValidation(SpringWeb.SpecValidation{Request: true, MaxBodySize: 16})
+			limited.PostBinding("/valid/pet", AddValidatedPet).Swagger("").WithConsumes(SpringWeb.MIMEApplicationJSON)
+			h = specValidationHandler(adapter, limited)
+
+			w, _ = doRequest(h, http.MethodPost, "/valid/pet", strings.NewReader(`{"name":"tom"}`), jsonHeader)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			w, _ = doRequest(h, http.MethodPost, "/valid/pet", strings.NewReader(`{"name":"tom","tags":["a"]}`), jsonHeader)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+			w, _ = doRequest(h, http.MethodPost, "/valid/pet", ioutil.NopCloser(strings.NewReader(`{"name":"tom","tags":["a"]}`)), jsonHeader)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+			var drift *SpringWeb.SpecValidationError
+			resp := SpringGin.NewContainer(SpringWeb.ContainerConfig{})
+			resp.SetSpecValidation(SpringWeb.SpecValidation{
+				Response: true,
+				OnResponseDrift: func(ctx SpringWeb.WebContext, err *SpringWeb.SpecValidationError) {
+					drift = err
+				},
+			})
+			resp.GetMapping("/valid/drift", func(ctx SpringWeb.WebContext) {
+				ctx.JSON(http.StatusOK, map[string]interface{}{"id": "x"})
+			}).Swagger("drift").RespondsWith(http.StatusOK, spec.NewResponse().WithSchema(
+				new(spec.Schema).Typed("object", "").WithRequired("id", "name").
+					SetProperty("id", *spec.Int64Property()).
+					SetProperty("name", *spec.StringProperty())))
+			h = specValidationHandler(adapter, resp)
+
+			w, body = doRequest(h, http.MethodGet, "/valid/drift", nil, nil)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, `{"id":"x"}`, strings.TrimSpace(body))
+			if assert.NotNil(t, drift) {
+				assert.Len(t, drift.Violations, 2)
+				assert.Equal(t, "response name: is required", drift.Violations[0].String())
+				assert.Equal(t, "response id: must be integer", drift.Violations[1].String())
+			}
+		})
+	}
+}